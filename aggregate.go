@@ -0,0 +1,259 @@
+package fali
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// AggFunc is the function of an Aggregation: COUNT, SUM, MIN, MAX, AVG
+type AggFunc uint8
+
+const (
+	AggCount AggFunc = iota
+	AggSum
+	AggMin
+	AggMax
+	AggAvg
+)
+
+func (f AggFunc) String() string {
+	switch f {
+	case AggCount:
+		return "COUNT"
+	case AggSum:
+		return "SUM"
+	case AggMin:
+		return "MIN"
+	case AggMax:
+		return "MAX"
+	case AggAvg:
+		return "AVG"
+	default:
+		return fmt.Sprintf("UNKNOWN: %d", f)
+	}
+}
+
+// aggFuncByName returns the AggFunc for the name (case insensitive)
+func aggFuncByName(name string) (AggFunc, bool) {
+	for _, f := range []AggFunc{AggCount, AggSum, AggMin, AggMax, AggAvg} {
+		if strings.EqualFold(f.String(), name) {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// Aggregation is a function over the values of a field: COUNT(*), SUM(price), MIN(age), MAX(age), AVG(age)
+type Aggregation struct {
+	Func  AggFunc
+	Field string // empty for: COUNT(*)
+}
+
+func (a Aggregation) String() string {
+	if a.Field == "" {
+		return fmt.Sprintf("%s(*)", a.Func)
+	}
+	return fmt.Sprintf("%s(%s)", a.Func, a.Field)
+}
+
+// AggregateValue is the value of the Aggregation.
+// The types of the values are:
+// COUNT: int, SUM: int64, uint64 or float64, MIN and MAX: the value type of the field, AVG: float64.
+// MIN, MAX and AVG are nil for an empty result.
+type AggregateValue struct {
+	Aggregation
+	Value any
+}
+
+// Number are the types, which can be summed up
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Aggregate calculates the Aggregations over the Items of the result, the fields are the names of the Indices.
+// MIN and MAX with a SortedIndex are the first and the last value of the SortedIndex, which is in the result.
+func (q *QueryResult[T, ID]) Aggregate(aggs ...Aggregation) ([]AggregateValue, error) {
+	var values []AggregateValue
+	var err error
+	q.readItems(func(items *FreeList[T]) {
+		values, err = aggregate(q.bitSet, aggs, q.filterByName(), items)
+	})
+	return values, err
+}
+
+// Aggregates returns the values of the SELECT Aggregations of the query string
+func (q *QueryResult[T, ID]) Aggregates() []AggregateValue { return q.aggregates }
+
+// Sum returns the sum of the field values of the result Items
+func Sum[T any, ID comparable, N Number](q *QueryResult[T, ID], field FromField[T, N]) N {
+	var sum N
+	q.eachItem(func(item *T) { sum += field(item) })
+	return sum
+}
+
+// Avg returns the average of the field values of the result Items, false for an empty result
+func Avg[T any, ID comparable, N Number](q *QueryResult[T, ID], field FromField[T, N]) (float64, bool) {
+	var sum float64
+	count := 0
+	q.eachItem(func(item *T) {
+		sum += float64(field(item))
+		count++
+	})
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
+
+// Min returns the min field value of the result Items, false for an empty result
+func Min[T any, ID comparable, V cmp.Ordered](q *QueryResult[T, ID], field FromField[T, V]) (V, bool) {
+	return minMax(q, field, -1)
+}
+
+// Max returns the max field value of the result Items, false for an empty result
+func Max[T any, ID comparable, V cmp.Ordered](q *QueryResult[T, ID], field FromField[T, V]) (V, bool) {
+	return minMax(q, field, 1)
+}
+
+// minMax returns the min value (sign -1) or the max value (sign 1)
+func minMax[T any, ID comparable, V cmp.Ordered](q *QueryResult[T, ID], field FromField[T, V], sign int) (V, bool) {
+	var result V
+	found := false
+	q.eachItem(func(item *T) {
+		if v := field(item); !found || cmp.Compare(v, result) == sign {
+			result, found = v, true
+		}
+	})
+	return result, found
+}
+
+// eachItem calls fn for all Items of the result
+func (q *QueryResult[T, ID]) eachItem(fn func(*T)) {
+	q.readItems(func(items *FreeList[T]) {
+		q.bitSet.Values(func(lidx uint32) bool {
+			item, _ := items.Get(int(lidx))
+			fn(&item)
+			return true
+		})
+	})
+}
+
+// aggregate calculates the Aggregations with the values of the Indices
+func aggregate[T any](result *BitSet[uint32], aggs []Aggregation, l FilterByName32, items *FreeList[T]) ([]AggregateValue, error) {
+	values := make([]AggregateValue, len(aggs))
+	for i, agg := range aggs {
+		value, err := aggregateOne(result, agg, l, items)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = AggregateValue{Aggregation: agg, Value: value}
+	}
+	return values, nil
+}
+
+func aggregateOne[T any](result *BitSet[uint32], agg Aggregation, l FilterByName32, items *FreeList[T]) (any, error) {
+	if agg.Func == AggCount {
+		if agg.Field != "" {
+			if _, err := l(agg.Field); err != nil {
+				return nil, err
+			}
+		}
+		return result.Count(), nil
+	}
+
+	filter, err := l(agg.Field)
+	if err != nil {
+		return nil, err
+	}
+	valuer, okValuer := filter.(itemValuer[T])
+	vt, okType := filter.(ValueTyper)
+	if !okValuer || !okType {
+		return nil, ErrInvalidAggregation{agg.String()}
+	}
+	kind := vt.ValueType().Kind()
+
+	switch agg.Func {
+	case AggMin, AggMax:
+		if !isOrdered(kind) {
+			return nil, ErrInvalidAggregation{agg.String()}
+		}
+		if walker, ok := filter.(sortedWalker[uint32]); ok {
+			return firstSorted(result, walker, agg.Func == AggMax, valuer, items), nil
+		}
+
+		var value any
+		result.Values(func(lidx uint32) bool {
+			item, _ := items.Get(int(lidx))
+			v := valuer.itemValue(&item)
+			if value == nil {
+				value = v
+			} else if c := compareValues(v, value); (agg.Func == AggMin && c < 0) || (agg.Func == AggMax && c > 0) {
+				value = v
+			}
+			return true
+		})
+		return value, nil
+
+	case AggSum, AggAvg:
+		if !isNumber(kind) {
+			return nil, ErrInvalidAggregation{agg.String()}
+		}
+
+		var sumInt int64
+		var sumUint uint64
+		var sumFloat float64
+		count := 0
+		result.Values(func(lidx uint32) bool {
+			item, _ := items.Get(int(lidx))
+			v := reflect.ValueOf(valuer.itemValue(&item))
+			switch {
+			case v.CanInt():
+				sumInt += v.Int()
+				sumFloat += float64(v.Int())
+			case v.CanUint():
+				sumUint += v.Uint()
+				sumFloat += float64(v.Uint())
+			default:
+				sumFloat += v.Float()
+			}
+			count++
+			return true
+		})
+
+		switch {
+		case agg.Func == AggAvg && count == 0:
+			return nil, nil
+		case agg.Func == AggAvg:
+			return sumFloat / float64(count), nil
+		case kind >= reflect.Int && kind <= reflect.Int64:
+			return sumInt, nil
+		case kind >= reflect.Uint && kind <= reflect.Uintptr:
+			return sumUint, nil
+		default:
+			return sumFloat, nil
+		}
+	}
+
+	return nil, ErrInvalidAggregation{agg.String()}
+}
+
+// firstSorted returns the value of the first Item of the SortedIndex (desc: the last Item), which is in the result
+func firstSorted[T any](result *BitSet[uint32], walker sortedWalker[uint32], desc bool, valuer itemValuer[T], items *FreeList[T]) any {
+	var value any
+	walker.walkSorted(desc, nil, func(p PostingList[uint32]) bool {
+		p.Values(func(lidx uint32) bool {
+			if result.Contains(lidx) {
+				item, _ := items.Get(int(lidx))
+				value = valuer.itemValue(&item)
+				return false
+			}
+			return true
+		})
+		return value == nil
+	})
+	return value
+}
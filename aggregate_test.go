@@ -0,0 +1,146 @@
+package fali
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregate_FromField(t *testing.T) {
+	il := newPlannerList()
+
+	qr, err := il.QueryStr(`age between [uint8(2), uint8(5)]`)
+	assert.NoError(t, err)
+
+	assert.Equal(t, uint8(14), Sum(&qr, (*car).Age))
+	avg, ok := Avg(&qr, (*car).Age)
+	assert.True(t, ok)
+	assert.Equal(t, 3.5, avg)
+	minName, ok := Min(&qr, (*car).Name)
+	assert.True(t, ok)
+	assert.Equal(t, "C", minName)
+	maxAge, ok := Max(&qr, (*car).Age)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(5), maxAge)
+
+	qr, err = il.QueryStr(`age > uint8(99)`)
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(0), Sum(&qr, (*car).Age))
+	_, ok = Avg(&qr, (*car).Age)
+	assert.False(t, ok)
+	_, ok = Max(&qr, (*car).Age)
+	assert.False(t, ok)
+}
+
+func TestAggregate_Index(t *testing.T) {
+	il := newPlannerList()
+
+	qr, err := il.QueryStr(`isnew = true and age < uint8(6)`)
+	assert.NoError(t, err)
+
+	values, err := qr.Aggregate(
+		Aggregation{Func: AggCount},
+		Aggregation{Func: AggSum, Field: "age"},
+		Aggregation{Func: AggAvg, Field: "age"},
+		Aggregation{Func: AggMin, Field: "age"},
+		Aggregation{Func: AggMax, Field: "age"},
+		// without SortedIndex
+		Aggregation{Func: AggMax, Field: "name"},
+		Aggregation{Func: AggMin, Field: "id"},
+		Aggregation{Func: AggMax, Field: "isnew"},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{5, uint64(12), 2.4, uint8(0), uint8(5), "F", "A", true}, aggValues(values))
+
+	// an empty result
+	qr, err = il.QueryStr(`age > uint8(99)`)
+	assert.NoError(t, err)
+	values, err = qr.Aggregate(
+		Aggregation{Func: AggCount, Field: "age"},
+		Aggregation{Func: AggSum, Field: "age"},
+		Aggregation{Func: AggAvg, Field: "age"},
+		Aggregation{Func: AggMin, Field: "age"},
+		Aggregation{Func: AggMax, Field: "name"},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{0, uint64(0), nil, nil, nil}, aggValues(values))
+
+	_, err = qr.Aggregate(Aggregation{Func: AggSum, Field: "name"})
+	assert.ErrorIs(t, err, ErrInvalidAggregation{"SUM(name)"})
+	_, err = qr.Aggregate(Aggregation{Func: AggMin, Field: "unknown"})
+	assert.ErrorIs(t, err, ErrInvalidIndexdName{"unknown"})
+	_, err = qr.Aggregate(Aggregation{Func: AggCount, Field: "unknown"})
+	assert.ErrorIs(t, err, ErrInvalidIndexdName{"unknown"})
+}
+
+func aggValues(values []AggregateValue) []any {
+	result := make([]any, len(values))
+	for i, v := range values {
+		result[i] = v.Value
+	}
+	return result
+}
+
+func TestAggregate_Select(t *testing.T) {
+	il := newPlannerList()
+
+	qr, err := il.QueryStr(`SELECT COUNT(*), MAX(age), avg(age) WHERE isnew = true and age > uint8(15) LIMIT 2`)
+	assert.NoError(t, err)
+	assert.Equal(t, []AggregateValue{
+		{Aggregation: Aggregation{Func: AggCount}, Value: 4},
+		{Aggregation: Aggregation{Func: AggMax, Field: "age"}, Value: uint8(19)},
+		{Aggregation: Aggregation{Func: AggAvg, Field: "age"}, Value: 17.5},
+	}, qr.Aggregates())
+	// the Aggregations are calculated before LIMIT
+	assert.Equal(t, 2, qr.Count())
+	assert.Equal(t, "MAX(age)", qr.Aggregates()[1].String())
+
+	// without a condition
+	qr, err = il.QueryStr(`select count(*), min(name)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{20, "A"}, aggValues(qr.Aggregates()))
+
+	// without SELECT
+	qr, err = il.QueryStr(`age > uint8(15)`)
+	assert.NoError(t, err)
+	assert.Nil(t, qr.Aggregates())
+
+	snap, err := il.Snapshot()
+	assert.NoError(t, err)
+	qr, err = snap.QueryStr(`select sum(age) where age < uint8(3)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{uint64(3)}, aggValues(qr.Aggregates()))
+}
+
+func TestAggregate_SelectParse(t *testing.T) {
+	stmt, err := ParseStatement(`select count(*), sum(price) where age > 1 order by age limit 3`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Aggregation{{Func: AggCount}, {Func: AggSum, Field: "price"}}, stmt.Select)
+	assert.Equal(t, []OrderBy{{Field: "age"}}, stmt.OrderBy)
+	assert.Equal(t, 3, stmt.Limit)
+
+	// select is a field name
+	stmt, err = ParseStatement(`select = 1`)
+	assert.NoError(t, err)
+	assert.Nil(t, stmt.Select)
+
+	_, err = Parse(`select count(*) where age > 1`)
+	assert.ErrorIs(t, err, ErrStatementClause{"SELECT"})
+
+	tests := []struct {
+		query string
+		err   error
+	}{
+		{`select foo(age)`, ErrInvalidAggregation{"foo"}},
+		{`select sum(*)`, ErrUnexpectedToken{token: token{Op: OpAsterisk, Start: 11, End: 12}, expected: OpIdent}},
+		{`select count age`, ErrUnexpectedToken{token: token{Op: OpIdent, Start: 13, End: 16}}},
+		{`select count(age`, ErrUnexpectedToken{token: token{Op: OpEOF, Start: 16, End: 16}, expected: OpRParen}},
+		{`select count(*) age > 1`, ErrUnexpectedToken{token: token{Op: OpIdent, Start: 16, End: 19}}},
+		{`select count(*) where`, ErrUnexpectedToken{token: token{Op: OpEOF, Start: 21, End: 21}, expected: OpIdent}},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseStatement(tt.query)
+		assert.ErrorIs(t, err, tt.err, tt.query)
+	}
+}
@@ -0,0 +1,407 @@
+package fali
+
+import (
+	"encoding"
+	"io"
+	"math"
+	"reflect"
+)
+
+// The binary format of BitSet, SliceSet, Posting and SkipList (all numbers are little-endian or varints):
+//
+//	BitSet:   version byte, number of words, blocks: [number of zero words, number of words, words (uint64)]
+//	SliceSet: version byte, number of values, values (as delta to the previous value)
+//	Posting:  version byte, 0 and the SliceSet or 1 and the BitSet (without the version byte)
+//	SkipList: version byte, number of entries, entries: [key, value]
+//
+// Keys and values of the SkipList: bool, numbers and strings or a type, which implements
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler (e.g. *BitSet, *Posting).
+const binaryVersion = byte(1)
+
+// maxWordsPerByte bounds the words of a decoded BitSet by the size of the input (512 KB per byte),
+// so a small (untrusted) input can not allocate a big BitSet. Big sparse values are better saved in a Posting.
+const maxWordsPerByte = 1 << 16
+
+// MarshalBinary encodes the BitSet, words with zeros are run-length encoded
+func (b *BitSet[V]) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.buf.WriteByte(binaryVersion)
+	b.marshal(e)
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the BitSet, which is encoded with MarshalBinary
+func (b *BitSet[V]) UnmarshalBinary(data []byte) error {
+	d := &decoder{data: data}
+	readBinaryVersion(d)
+
+	var decoded BitSet[V]
+	decoded.unmarshal(d)
+	if err := readBinaryEnd(d); err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// WriteTo writes the BitSet in the binary format (see: MarshalBinary)
+func (b *BitSet[V]) WriteTo(w io.Writer) (int64, error) { return writeBinary(w, b) }
+
+// ReadFrom reads the BitSet in the binary format until EOF (see: UnmarshalBinary)
+func (b *BitSet[V]) ReadFrom(r io.Reader) (int64, error) { return readBinary(r, b) }
+
+func (b *BitSet[V]) marshal(e *encoder) {
+	words := b.data[:b.MaxSetIndex()+1]
+	e.uvarint(uint64(len(words)))
+	for i := 0; i < len(words); {
+		start := i
+		for i < len(words) && words[i] == 0 {
+			i++
+		}
+		zeros := i - start
+
+		start = i
+		for i < len(words) && words[i] != 0 {
+			i++
+		}
+
+		e.uvarint(uint64(zeros))
+		e.uvarint(uint64(i - start))
+		for _, w := range words[start:i] {
+			e.uint64(w)
+		}
+	}
+}
+
+func (b *BitSet[V]) unmarshal(d *decoder) {
+	n := d.uvarint()
+	if n > uint64(^V(0))>>6+1 || n > uint64(len(d.data))*maxWordsPerByte {
+		d.fail("bitset is too big")
+	}
+
+	words := make([]uint64, 0, min(n, uint64(len(d.data)/8)))
+	for d.err == nil && uint64(len(words)) < n {
+		rest := n - uint64(len(words))
+		zeros, literals := d.uvarint(), d.uvarint()
+		if zeros > rest || literals == 0 || literals > rest-zeros {
+			d.fail("invalid bitset block")
+			break
+		}
+
+		words = append(words, make([]uint64, zeros)...)
+		for range literals {
+			words = append(words, d.uint64())
+		}
+	}
+	b.data = words
+}
+
+// MarshalBinary encodes the SliceSet, the values are saved as delta to the previous value
+func (s *SliceSet[V]) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.buf.WriteByte(binaryVersion)
+	s.marshal(e)
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the SliceSet, which is encoded with MarshalBinary
+func (s *SliceSet[V]) UnmarshalBinary(data []byte) error {
+	d := &decoder{data: data}
+	readBinaryVersion(d)
+
+	var decoded SliceSet[V]
+	decoded.unmarshal(d)
+	if err := readBinaryEnd(d); err != nil {
+		return err
+	}
+	*s = decoded
+	return nil
+}
+
+// WriteTo writes the SliceSet in the binary format (see: MarshalBinary)
+func (s *SliceSet[V]) WriteTo(w io.Writer) (int64, error) { return writeBinary(w, s) }
+
+// ReadFrom reads the SliceSet in the binary format until EOF (see: UnmarshalBinary)
+func (s *SliceSet[V]) ReadFrom(r io.Reader) (int64, error) { return readBinary(r, s) }
+
+func (s *SliceSet[V]) marshal(e *encoder) {
+	e.uvarint(uint64(len(s.data)))
+	prev := uint64(0)
+	for _, v := range s.data {
+		e.uvarint(uint64(v) - prev)
+		prev = uint64(v)
+	}
+}
+
+func (s *SliceSet[V]) unmarshal(d *decoder) {
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail("sliceset is too big")
+	}
+
+	values := make([]V, 0, min(n, uint64(len(d.data))))
+	value := uint64(0)
+	for i := uint64(0); d.err == nil && i < n; i++ {
+		delta := d.uvarint()
+		if i > 0 && delta == 0 {
+			d.fail("values are not sorted")
+			break
+		}
+		if value+delta < value || value+delta > uint64(^V(0)) {
+			d.fail("value is too big")
+			break
+		}
+		value += delta
+		values = append(values, V(value))
+	}
+	s.data = values
+}
+
+// MarshalBinary encodes the Posting as SliceSet or as BitSet, if the Posting is dense
+func (p *Posting[LI]) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.buf.WriteByte(binaryVersion)
+	if p.bitSet == nil {
+		e.buf.WriteByte(0)
+		p.slice.marshal(e)
+	} else {
+		e.buf.WriteByte(1)
+		p.bitSet.marshal(e)
+	}
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the Posting, which is encoded with MarshalBinary
+func (p *Posting[LI]) UnmarshalBinary(data []byte) error {
+	d := &decoder{data: data}
+	readBinaryVersion(d)
+
+	var decoded Posting[LI]
+	switch kind := d.byte(); {
+	case d.err != nil:
+	case kind == 0:
+		decoded.slice = &SliceSet[LI]{}
+		decoded.slice.unmarshal(d)
+	case kind == 1:
+		decoded.bitSet = &BitSet[LI]{}
+		decoded.bitSet.unmarshal(d)
+		decoded.count = decoded.bitSet.Count()
+	default:
+		d.fail("invalid posting kind")
+	}
+
+	if err := readBinaryEnd(d); err != nil {
+		return err
+	}
+	decoded.adapt()
+	*p = decoded
+	return nil
+}
+
+// WriteTo writes the Posting in the binary format (see: MarshalBinary)
+func (p *Posting[LI]) WriteTo(w io.Writer) (int64, error) { return writeBinary(w, p) }
+
+// ReadFrom reads the Posting in the binary format until EOF (see: UnmarshalBinary)
+func (p *Posting[LI]) ReadFrom(r io.Reader) (int64, error) { return readBinary(r, p) }
+
+// MarshalBinary encodes the keys and values of the SkipList, in the order of the keys
+func (sl *SkipList[K, V]) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.buf.WriteByte(binaryVersion)
+
+	count := 0
+	sl.Traverse(func(K, V) bool {
+		count++
+		return true
+	})
+	e.uvarint(uint64(count))
+
+	var err error
+	sl.Traverse(func(key K, value V) bool {
+		if err = writeBinaryValue(e, key); err == nil {
+			err = writeBinaryValue(e, value)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the SkipList, which is encoded with MarshalBinary.
+// The SkipList contains only the decoded keys and values.
+func (sl *SkipList[K, V]) UnmarshalBinary(data []byte) error {
+	d := &decoder{data: data}
+	readBinaryVersion(d)
+
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail("skiplist is too big")
+	}
+
+	decoded := NewSkipList[K, V]()
+	var prev K
+	for i := uint64(0); d.err == nil && i < n; i++ {
+		key, err := readBinaryValue[K](d)
+		if err != nil {
+			return err
+		}
+		value, err := readBinaryValue[V](d)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 && !(prev < key) {
+			d.fail("keys are not sorted")
+			break
+		}
+		decoded.Put(key, value)
+		prev = key
+	}
+
+	if err := readBinaryEnd(d); err != nil {
+		return err
+	}
+	*sl = decoded
+	return nil
+}
+
+// WriteTo writes the SkipList in the binary format (see: MarshalBinary)
+func (sl *SkipList[K, V]) WriteTo(w io.Writer) (int64, error) { return writeBinary(w, sl) }
+
+// ReadFrom reads the SkipList in the binary format until EOF (see: UnmarshalBinary)
+func (sl *SkipList[K, V]) ReadFrom(r io.Reader) (int64, error) { return readBinary(r, sl) }
+
+func writeBinary(w io.Writer, m encoding.BinaryMarshaler) (int64, error) {
+	data, err := m.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+func readBinary(r io.Reader, u encoding.BinaryUnmarshaler) (int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return int64(len(data)), err
+	}
+	return int64(len(data)), u.UnmarshalBinary(data)
+}
+
+func readBinaryVersion(d *decoder) {
+	if version := d.byte(); d.err == nil && version != binaryVersion {
+		d.err = ErrUnsupportedVersion{uint16(version)}
+		d.data = nil
+	}
+}
+
+// readBinaryEnd returns the error of the decoder or an error, if not all data are read
+func readBinaryEnd(d *decoder) error {
+	if d.err == nil && len(d.data) > 0 {
+		d.fail("unexpected data at the end")
+	}
+	return d.err
+}
+
+// writeBinaryValue writes a bool, number, string or a value, which implements encoding.BinaryMarshaler
+func writeBinaryValue[T any](e *encoder, value T) error {
+	m, ok := any(value).(encoding.BinaryMarshaler)
+	if !ok {
+		m, ok = any(&value).(encoding.BinaryMarshaler)
+	}
+	if ok {
+		data, err := m.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		e.bytes(data)
+		return nil
+	}
+
+	v := reflect.ValueOf(&value).Elem()
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf.WriteByte(1)
+		} else {
+			e.buf.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.varint(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.uvarint(v.Uint())
+	case reflect.Float32:
+		e.uint32(math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.uint64(math.Float64bits(v.Float()))
+	case reflect.String:
+		e.string(v.String())
+	default:
+		return ErrNotSerializable{reflect.TypeFor[T]()}
+	}
+	return nil
+}
+
+// readBinaryValue reads a value, which is written with writeBinaryValue
+func readBinaryValue[T any](d *decoder) (T, error) {
+	var value T
+
+	// a pointer to a type, which implements encoding.BinaryUnmarshaler, e.g. *BitSet
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Pointer {
+		ptr := reflect.New(t.Elem())
+		if u, ok := ptr.Interface().(encoding.BinaryUnmarshaler); ok {
+			data := d.bytes()
+			if d.err != nil {
+				return value, d.err
+			}
+			if err := u.UnmarshalBinary(data); err != nil {
+				return value, err
+			}
+			return ptr.Interface().(T), nil
+		}
+	}
+
+	if u, ok := any(&value).(encoding.BinaryUnmarshaler); ok {
+		data := d.bytes()
+		if d.err != nil {
+			return value, d.err
+		}
+		return value, u.UnmarshalBinary(data)
+	}
+
+	v := reflect.ValueOf(&value).Elem()
+	switch v.Kind() {
+	case reflect.Bool:
+		switch d.byte() {
+		case 0:
+		case 1:
+			v.SetBool(true)
+		default:
+			d.fail("invalid bool")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := d.varint(); v.OverflowInt(i) {
+			d.fail("int overflow")
+		} else {
+			v.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := d.uvarint(); v.OverflowUint(u) {
+			d.fail("uint overflow")
+		} else {
+			v.SetUint(u)
+		}
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(d.uint32())))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(d.uint64()))
+	case reflect.String:
+		v.SetString(d.string())
+	default:
+		return value, ErrNotSerializable{reflect.TypeFor[T]()}
+	}
+	return value, d.err
+}
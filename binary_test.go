@@ -0,0 +1,274 @@
+package fali
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinary_BitSet(t *testing.T) {
+	bs := NewBitSetFrom[uint32](1, 64, 65, 4_000_000)
+	data, err := bs.MarshalBinary()
+	assert.NoError(t, err)
+	// the ~62_500 zero words are run-length encoded
+	assert.Less(t, len(data), 48)
+
+	restored := NewBitSet[uint32]()
+	assert.NoError(t, restored.UnmarshalBinary(data))
+	assert.Equal(t, bs.ToSlice(), restored.ToSlice())
+	assert.Equal(t, bs.Count(), restored.Count())
+
+	var buf bytes.Buffer
+	n, err := bs.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+
+	restored = NewBitSet[uint32]()
+	n, err = restored.ReadFrom(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+	assert.Equal(t, bs.ToSlice(), restored.ToSlice())
+
+	// empty BitSet
+	data, err = NewBitSet[uint32]().MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{binaryVersion, 0}, data)
+	assert.NoError(t, restored.UnmarshalBinary(data))
+	assert.True(t, restored.IsEmpty())
+}
+
+func TestBinary_SliceSet(t *testing.T) {
+	s := NewSliceSetFrom[uint16](3, 1, 300, 65_535)
+	data, err := s.MarshalBinary()
+	assert.NoError(t, err)
+
+	restored := NewSliceSet[uint16]()
+	assert.NoError(t, restored.UnmarshalBinary(data))
+	assert.Equal(t, []uint16{1, 3, 300, 65_535}, restored.ToSlice())
+
+	var buf bytes.Buffer
+	_, err = s.WriteTo(&buf)
+	assert.NoError(t, err)
+	restored = NewSliceSet[uint16]()
+	_, err = restored.ReadFrom(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, s.ToSlice(), restored.ToSlice())
+}
+
+func TestBinary_Posting(t *testing.T) {
+	// sparse and dense Posting
+	for _, p := range []*Posting[uint32]{NewPostingFrom[uint32](3, 4_000_000), NewPostingFrom[uint32](1, 2, 3, 5, 8, 13)} {
+		data, err := p.MarshalBinary()
+		assert.NoError(t, err)
+
+		restored := NewPosting[uint32]()
+		assert.NoError(t, restored.UnmarshalBinary(data))
+		assert.Equal(t, p.IsDense(), restored.IsDense())
+		assert.Equal(t, p.Count(), restored.Count())
+		assert.Equal(t, p.ToSlice(), restored.ToSlice())
+
+		var buf bytes.Buffer
+		_, err = p.WriteTo(&buf)
+		assert.NoError(t, err)
+		restored = NewPosting[uint32]()
+		_, err = restored.ReadFrom(&buf)
+		assert.NoError(t, err)
+		assert.Equal(t, p.ToSlice(), restored.ToSlice())
+	}
+
+	// the SkipList of the SortedIndex
+	sl := NewSkipList[string, *Posting[uint32]]()
+	sl.Put("Opel", NewPostingFrom[uint32](3, 4_000_000))
+	sl.Put("Mercedes", NewPostingFrom[uint32](0, 1, 2, 3))
+	data, err := sl.MarshalBinary()
+	assert.NoError(t, err)
+
+	var restored SkipList[string, *Posting[uint32]]
+	assert.NoError(t, restored.UnmarshalBinary(data))
+	opel, found := restored.Get("Opel")
+	assert.True(t, found)
+	assert.Equal(t, []uint32{3, 4_000_000}, opel.ToSlice())
+	mercedes, found := restored.Get("Mercedes")
+	assert.True(t, found)
+	assert.True(t, mercedes.IsDense())
+	assert.Equal(t, 4, mercedes.Count())
+}
+
+func TestBinary_SkipList(t *testing.T) {
+	sl := NewSkipList[string, *BitSet[uint32]]()
+	sl.Put("b", NewBitSetFrom[uint32](2, 3))
+	sl.Put("a", NewBitSetFrom[uint32](1))
+	sl.Put("c", NewBitSet[uint32]())
+
+	data, err := sl.MarshalBinary()
+	assert.NoError(t, err)
+
+	var restored SkipList[string, *BitSet[uint32]]
+	assert.NoError(t, restored.UnmarshalBinary(data))
+
+	var keys []string
+	restored.Traverse(func(key string, bs *BitSet[uint32]) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+	bs, found := restored.Get("b")
+	assert.True(t, found)
+	assert.Equal(t, []uint32{2, 3}, bs.ToSlice())
+
+	// numbers and bools
+	nums := NewSkipList[float32, bool]()
+	nums.Put(-1.5, true)
+	nums.Put(42, false)
+	var buf bytes.Buffer
+	_, err = nums.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	var restoredNums SkipList[float32, bool]
+	_, err = restoredNums.ReadFrom(&buf)
+	assert.NoError(t, err)
+	v, found := restoredNums.Get(-1.5)
+	assert.True(t, found)
+	assert.True(t, v)
+	maxKey, _ := restoredNums.MaxKey()
+	assert.Equal(t, float32(42), maxKey)
+
+	// not serializable values
+	lists := NewSkipList[int, []int]()
+	lists.Put(1, []int{1})
+	_, err = lists.MarshalBinary()
+	assert.ErrorIs(t, err, ErrNotSerializable{typ: reflect.TypeFor[[]int]()})
+}
+
+func TestBinary_Errors(t *testing.T) {
+	bs := NewBitSet[uint32]()
+	assert.ErrorIs(t, bs.UnmarshalBinary(nil), ErrInvalidFormat{"unexpected end of data"})
+	assert.ErrorIs(t, bs.UnmarshalBinary([]byte{9}), ErrUnsupportedVersion{9})
+	assert.ErrorIs(t, bs.UnmarshalBinary([]byte{binaryVersion, 1, 0, 1, 1}), ErrInvalidFormat{"unexpected end of data"})
+	assert.ErrorIs(t, bs.UnmarshalBinary([]byte{binaryVersion, 1, 0, 0}), ErrInvalidFormat{"invalid bitset block"})
+	assert.ErrorIs(t, bs.UnmarshalBinary([]byte{binaryVersion, 0, 1}), ErrInvalidFormat{"unexpected data at the end"})
+	// 18 bytes for 2^26 words (512 MB)
+	assert.Len(t, bigBitSetInput(), 18)
+	assert.ErrorIs(t, bs.UnmarshalBinary(bigBitSetInput()), ErrInvalidFormat{"bitset is too big"})
+
+	s := NewSliceSet[uint8]()
+	assert.ErrorIs(t, s.UnmarshalBinary([]byte{binaryVersion, 2, 1, 0}), ErrInvalidFormat{"values are not sorted"})
+	assert.ErrorIs(t, s.UnmarshalBinary([]byte{binaryVersion, 2, 1, 0xff, 0x01}), ErrInvalidFormat{"value is too big"})
+	// the number of values is not allocated
+	assert.ErrorIs(t, s.UnmarshalBinary([]byte{binaryVersion, 0xc4, 0xc4, 0xc4, 0xc4, 0xc4, 0x30}), ErrInvalidFormat{"sliceset is too big"})
+
+	p := NewPosting[uint32]()
+	assert.ErrorIs(t, p.UnmarshalBinary([]byte{binaryVersion, 2}), ErrInvalidFormat{"invalid posting kind"})
+	assert.ErrorIs(t, p.UnmarshalBinary([]byte{binaryVersion}), ErrInvalidFormat{"unexpected end of data"})
+
+	var sl SkipList[int, int]
+	assert.ErrorIs(t, sl.UnmarshalBinary([]byte{binaryVersion, 2, 2, 0, 2, 0}), ErrInvalidFormat{"keys are not sorted"})
+	var sl8 SkipList[int8, int]
+	assert.ErrorIs(t, sl8.UnmarshalBinary(binary.AppendVarint([]byte{binaryVersion, 1}, 300)), ErrInvalidFormat{"int overflow"})
+}
+
+// fuzzValues creates values from the bytes: 3 bytes are one value, so there are sparse and dense values
+func fuzzValues(data []byte) []uint32 {
+	values := make([]uint32, 0, len(data)/3)
+	for i := 0; i+3 <= len(data); i += 3 {
+		values = append(values, uint32(data[i])|uint32(data[i+1])<<8|uint32(data[i+2]&0x3f)<<16)
+	}
+	return values
+}
+
+// bigBitSetInput is a BitSet with one value, but with 2^26 - 1 zero words before
+func bigBitSetInput() []byte {
+	data := binary.AppendUvarint([]byte{binaryVersion}, 1<<26)
+	data = binary.AppendUvarint(data, 1<<26-1)
+	data = binary.AppendUvarint(data, 1)
+	return binary.LittleEndian.AppendUint64(data, 1)
+}
+
+func addFuzzSeeds(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{1, 0, 0})
+	f.Add([]byte{0, 0, 0, 1, 0, 0, 255, 255, 63})
+	f.Add([]byte{binaryVersion, 1, 0, 1, 1, 2, 3, 4, 5, 6, 7, 8})
+	f.Add(bytes.Repeat([]byte{7, 1, 0}, 100))
+	f.Add(bigBitSetInput())
+}
+
+func FuzzBitSet_RoundTrip(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		bs := NewBitSetFrom(fuzzValues(data)...)
+		encoded, err := bs.MarshalBinary()
+		assert.NoError(t, err)
+
+		restored := NewBitSet[uint32]()
+		assert.NoError(t, restored.UnmarshalBinary(encoded))
+		assert.Equal(t, bs.Count(), restored.Count())
+		assert.Equal(t, bs.ToSlice(), restored.ToSlice())
+
+		// invalid data returns an error and not a panic
+		_ = NewBitSet[uint16]().UnmarshalBinary(data)
+	})
+}
+
+func FuzzSliceSet_RoundTrip(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := NewSliceSetFrom(fuzzValues(data)...)
+		encoded, err := s.MarshalBinary()
+		assert.NoError(t, err)
+
+		restored := NewSliceSet[uint32]()
+		assert.NoError(t, restored.UnmarshalBinary(encoded))
+		assert.Equal(t, s.Count(), restored.Count())
+		assert.Equal(t, s.ToSlice(), restored.ToSlice())
+
+		_ = NewSliceSet[uint16]().UnmarshalBinary(data)
+	})
+}
+
+func FuzzPosting_RoundTrip(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p := NewPostingFrom(fuzzValues(data)...)
+		encoded, err := p.MarshalBinary()
+		assert.NoError(t, err)
+
+		restored := NewPosting[uint32]()
+		assert.NoError(t, restored.UnmarshalBinary(encoded))
+		assert.Equal(t, p.IsDense(), restored.IsDense())
+		assert.Equal(t, p.ToSlice(), restored.ToSlice())
+
+		_ = NewPosting[uint32]().UnmarshalBinary(data)
+	})
+}
+
+func FuzzSkipList_RoundTrip(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sl := NewSkipList[string, uint32]()
+		for i, v := range fuzzValues(data) {
+			sl.Put(string(data[i*3:i*3+2]), v)
+		}
+		encoded, err := sl.MarshalBinary()
+		assert.NoError(t, err)
+
+		var restored SkipList[string, uint32]
+		assert.NoError(t, restored.UnmarshalBinary(encoded))
+
+		var expected, got []uint32
+		sl.Traverse(func(_ string, v uint32) bool {
+			expected = append(expected, v)
+			return true
+		})
+		restored.Traverse(func(_ string, v uint32) bool {
+			got = append(got, v)
+			return true
+		})
+		assert.Equal(t, expected, got)
+
+		_ = new(SkipList[string, uint32]).UnmarshalBinary(data)
+	})
+}
@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"math/bits"
@@ -192,6 +192,55 @@ func (b *BitSet[V]) Count() int {
 	return count
 }
 
+// CountAnd counts the values, which are in both BitSets, without creating a new BitSet.
+func (b *BitSet[V]) CountAnd(other *BitSet[V]) int {
+	count := 0
+	for i := range min(len(b.data), len(other.data)) {
+		count += bits.OnesCount64(b.data[i] & other.data[i])
+	}
+	return count
+}
+
+// Rank counts the values, which are less than the given value.
+// [1, 3, 100] => Rank(3) = 1, Rank(4) = 2
+func (b *BitSet[V]) Rank(value V) int {
+	index := int(value >> 6)
+	if index >= len(b.data) {
+		return b.Count()
+	}
+
+	count := 0
+	for _, w := range b.data[:index] {
+		count += bits.OnesCount64(w)
+	}
+	mask := (uint64(1) << (value & 63)) - 1
+	return count + bits.OnesCount64(b.data[index]&mask)
+}
+
+// Select returns the value on the position n (starting with 0) of the sorted values.
+// [1, 3, 100] => Select(1) = (3, true), Select(3) = (0, false)
+func (b *BitSet[V]) Select(n int) (V, bool) {
+	if n < 0 {
+		return 0, false
+	}
+
+	for i, w := range b.data {
+		count := bits.OnesCount64(w)
+		if n >= count {
+			n -= count
+			continue
+		}
+
+		// remove the n lowest bits
+		for ; n > 0; n-- {
+			w &= w - 1
+		}
+		return V(i<<6 + bits.TrailingZeros64(w)), true
+	}
+
+	return 0, false
+}
+
 // IsEmpty there are no bits set, means Count() == 0
 func (b *BitSet[V]) IsEmpty() bool { return b.Count() == 0 }
 
@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"testing"
@@ -321,3 +321,30 @@ func TestBitSet_Clear(t *testing.T) {
 	assert.Equal(t, 0, len(b.data))
 	assert.Equal(t, 2, cap(b.data))
 }
+
+func TestBitSet_RankSelect(t *testing.T) {
+	b := NewBitSetFrom[uint32](1, 3, 63, 64, 100, 200)
+
+	assert.Equal(t, 0, b.Rank(0))
+	assert.Equal(t, 0, b.Rank(1))
+	assert.Equal(t, 1, b.Rank(2))
+	assert.Equal(t, 2, b.Rank(63))
+	assert.Equal(t, 3, b.Rank(64))
+	assert.Equal(t, 5, b.Rank(200))
+	assert.Equal(t, 6, b.Rank(201))
+	assert.Equal(t, 6, b.Rank(1_000))
+
+	for n, expected := range []uint32{1, 3, 63, 64, 100, 200} {
+		v, found := b.Select(n)
+		assert.True(t, found)
+		assert.Equal(t, expected, v)
+		assert.Equal(t, n, b.Rank(v))
+	}
+
+	_, found := b.Select(6)
+	assert.False(t, found)
+	_, found = b.Select(-1)
+	assert.False(t, found)
+	_, found = NewBitSet[uint32]().Select(0)
+	assert.False(t, found)
+}
@@ -0,0 +1,32 @@
+// Command fali is an interactive shell, which loads a JSON array into an IndexList
+// and evaluates query strings against it.
+//
+// Usage:
+//
+//	fali -file testdata/testdata.json -path data.namesLists.results
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	file := flag.String("file", "", "JSON file, which contains an array of objects")
+	path := flag.String("path", "", "dot separated path to the array in the JSON file, e.g.: data.results")
+	limit := flag.Int("limit", 10, "max number of printed rows per query")
+	flag.Parse()
+
+	sh := newShell(os.Stdout)
+	sh.limit = *limit
+
+	if *file != "" {
+		if err := sh.load(*file, *path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	sh.run(os.Stdin)
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lima1909/fali"
+)
+
+const prompt = "fali> "
+
+const help = `commands:
+  \load <file> [path]     load a JSON array (path: dot separated path to the array)
+  \index <field> <kind>   create an index for a field, kind: map, sorted, trigram, multi
+  \indexes                list the created indexes
+  \limit <n>              max number of printed rows per query
+  \count                  count of the loaded items
+  \explain [analyze] <q>  show the plan of the query, analyze executes the query
+  \help                   show this help
+  \quit                   exit the shell
+every other line is a query, e.g.: Genre = "male" and Name contains "ara"
+`
+
+// record is one object of the loaded JSON array
+type record = map[string]any
+
+type shell struct {
+	list    *fali.IndexList[record, struct{}]
+	indexes map[string]string
+	limit   int
+	out     io.Writer
+}
+
+func newShell(out io.Writer) *shell {
+	return &shell{
+		list:    fali.NewIndexList[record](),
+		indexes: make(map[string]string),
+		limit:   10,
+		out:     out,
+	}
+}
+
+// run reads line by line from the input and executes the commands or queries, until EOF or \quit
+func (s *shell) run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprint(s.out, prompt)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == `\quit` || line == `\q` {
+			return
+		}
+
+		if err := s.exec(line); err != nil {
+			fmt.Fprintf(s.out, "error: %v\n", err)
+		}
+		fmt.Fprint(s.out, prompt)
+	}
+}
+
+func (s *shell) exec(line string) error {
+	if line == "" {
+		return nil
+	}
+
+	if !strings.HasPrefix(line, `\`) {
+		return s.query(line)
+	}
+
+	args := strings.Fields(line)
+	switch args[0] {
+	case `\help`, `\h`:
+		fmt.Fprint(s.out, help)
+	case `\load`:
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf(`usage: \load <file> [path]`)
+		}
+		path := ""
+		if len(args) == 3 {
+			path = args[2]
+		}
+		if err := s.load(args[1], path); err != nil {
+			return err
+		}
+	case `\index`:
+		if len(args) != 3 {
+			return fmt.Errorf(`usage: \index <field> <map|sorted|trigram|multi>`)
+		}
+		if err := s.createIndex(args[1], args[2]); err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "created %s index for: %s\n", args[2], args[1])
+	case `\indexes`:
+		fields := make([]string, 0, len(s.indexes))
+		for f := range s.indexes {
+			fields = append(fields, f)
+		}
+		slices.Sort(fields)
+		for _, f := range fields {
+			fmt.Fprintf(s.out, "%s: %s\n", f, s.indexes[f])
+		}
+	case `\limit`:
+		if len(args) != 2 {
+			return fmt.Errorf(`usage: \limit <n>`)
+		}
+		limit, err := strconv.Atoi(args[1])
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid limit: %s", args[1])
+		}
+		s.limit = limit
+	case `\count`:
+		fmt.Fprintf(s.out, "%d items\n", s.list.Count())
+	case `\explain`:
+		queryStr := strings.TrimSpace(strings.TrimPrefix(line, args[0]))
+		explain := s.list.Explain
+		if len(args) > 1 && strings.EqualFold(args[1], "analyze") {
+			queryStr = strings.TrimSpace(queryStr[len(args[1]):])
+			explain = s.list.ExplainAnalyze
+		}
+		if queryStr == "" {
+			return fmt.Errorf(`usage: \explain [analyze] <query>`)
+		}
+		e, err := explain(queryStr)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(s.out, e)
+	default:
+		return fmt.Errorf("unknown command: %s (try \\help)", args[0])
+	}
+
+	return nil
+}
+
+// load reads the JSON array from the file and inserts all objects in the list.
+func (s *shell) load(file, path string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	start := time.Now()
+	records, err := readRecords(f, path)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if _, err := s.list.Insert(r); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(s.out, "loaded %d items in %s\n", len(records), time.Since(start))
+	return nil
+}
+
+// readRecords decodes the JSON and returns the objects of the array, found by the path.
+// Numbers without a fraction are decoded as int64, all other numbers as float64.
+func readRecords(r io.Reader, path string) ([]record, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var data any
+	if err := dec.Decode(&data); err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		for key := range strings.SplitSeq(path, ".") {
+			obj, ok := data.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("path: %q, %q is not an object", path, key)
+			}
+			if data, ok = obj[key]; !ok {
+				return nil, fmt.Errorf("path: %q, key %q not found", path, key)
+			}
+		}
+	}
+
+	arr, ok := data.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a JSON array, got: %T", data)
+	}
+
+	records := make([]record, 0, len(arr))
+	for i, el := range arr {
+		obj, ok := el.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("array element %d is not an object: %T", i, el)
+		}
+		for k, v := range obj {
+			switch v := v.(type) {
+			case json.Number:
+				obj[k] = toNumber(v)
+			case []any:
+				for j, el := range v {
+					if n, ok := el.(json.Number); ok {
+						v[j] = toNumber(n)
+					}
+				}
+			}
+		}
+		records = append(records, obj)
+	}
+
+	return records, nil
+}
+
+func toNumber(n json.Number) any {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n.String()
+}
+
+func (s *shell) createIndex(field, kind string) error {
+	var index fali.Index32[record]
+
+	switch kind {
+	case "map":
+		index = fali.NewMapIndex(func(r *record) any { return mapKey((*r)[field]) })
+	case "sorted":
+		switch s.sampleValue(field).(type) {
+		case int64:
+			index = newSortedField[int64](field)
+		case float64:
+			index = newSortedField[float64](field)
+		case string, nil:
+			index = newSortedField[string](field)
+		default:
+			return fmt.Errorf("field: %s has no ordered values, use a map index", field)
+		}
+	case "trigram":
+		index = fali.NewTrigramIndex(fieldValue[string](field))
+	case "multi":
+		index = fali.NewMultiValueIndex(func(r *record) []any {
+			values, _ := (*r)[field].([]any)
+			keys := make([]any, 0, len(values))
+			for _, v := range values {
+				if v = mapKey(v); v != nil {
+					keys = append(keys, v)
+				}
+			}
+			return keys
+		})
+	default:
+		return fmt.Errorf("unknown index kind: %s (map, sorted, trigram, multi)", kind)
+	}
+
+	if err := s.list.CreateIndex(field, index); err != nil {
+		return err
+	}
+	s.indexes[field] = kind
+	return nil
+}
+
+// mapKey returns the value, if it can be a map key, otherwise nil
+func mapKey(v any) any {
+	switch v.(type) {
+	case string, int64, float64, bool:
+		return v
+	default:
+		return nil
+	}
+}
+
+// sampleValue returns the first not nil value of the field, to determine the type for a sorted index.
+func (s *shell) sampleValue(field string) any {
+	qr, err := s.list.Query(fali.All())
+	if err != nil {
+		return nil
+	}
+
+	for _, r := range qr.Values() {
+		if v := r[field]; v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// fieldValue returns the value of the field, or the zero value, if the field has an other type.
+func fieldValue[V any](field string) fali.FromField[record, V] {
+	return func(r *record) V {
+		v, _ := (*r)[field].(V)
+		return v
+	}
+}
+
+// sortedField is a SortedIndex, which skips the records without the field (or with an other type),
+// so they are not found as the zero value.
+type sortedField[V cmp.Ordered] struct {
+	*fali.SortedIndex[record, V, uint32]
+	field string
+}
+
+func newSortedField[V cmp.Ordered](field string) sortedField[V] {
+	si := fali.NewSortedIndex(fieldValue[V](field)).(*fali.SortedIndex[record, V, uint32])
+	return sortedField[V]{SortedIndex: si, field: field}
+}
+
+func (s sortedField[V]) Set(r *record, lidx uint32) {
+	if _, ok := (*r)[s.field].(V); ok {
+		s.SortedIndex.Set(r, lidx)
+	}
+}
+
+func (s sortedField[V]) UnSet(r *record, lidx uint32) {
+	if _, ok := (*r)[s.field].(V); ok {
+		s.SortedIndex.UnSet(r, lidx)
+	}
+}
+
+func (s *shell) query(queryStr string) error {
+	start := time.Now()
+	qr, err := s.list.QueryStr(queryStr)
+	if err != nil {
+		return err
+	}
+	took := time.Since(start)
+
+	if groups := qr.Groups(); groups != nil {
+		for _, g := range groups {
+			values := make([]string, 0, len(g.Aggregates))
+			for _, agg := range g.Aggregates {
+				values = append(values, fmt.Sprintf("%s: %v", agg.Aggregation, agg.Value))
+			}
+			fmt.Fprintf(s.out, "%v (%d items) %s\n", g.Value, g.Count, strings.Join(values, ", "))
+		}
+		fmt.Fprintf(s.out, "(%d groups, took %s)\n", len(groups), took)
+		return nil
+	}
+
+	if aggs := qr.Aggregates(); len(aggs) > 0 {
+		for _, agg := range aggs {
+			fmt.Fprintf(s.out, "%s: %v\n", agg.Aggregation, agg.Value)
+		}
+		fmt.Fprintf(s.out, "(took %s)\n", took)
+		return nil
+	}
+
+	rows := qr.Values()
+	rows = rows[:min(len(rows), s.limit)]
+	for _, r := range rows {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(s.out, string(b))
+	}
+
+	count := qr.Count()
+	if count > len(rows) {
+		fmt.Fprintf(s.out, "... %d more\n", count-len(rows))
+	}
+	fmt.Fprintf(s.out, "(%d rows, took %s)\n", count, took)
+
+	return nil
+}
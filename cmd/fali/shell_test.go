@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell_Query(t *testing.T) {
+	var out bytes.Buffer
+	sh := newShell(&out)
+
+	err := sh.load("../../testdata/testdata.json", "data.namesLists.results")
+	assert.NoError(t, err)
+	assert.True(t, sh.list.Count() > 0)
+
+	sh.run(strings.NewReader(`\index Genre map
+\index Name sorted
+\index Letter trigram
+\limit 1
+Name = "Abram"
+Genre = "male" and Letter contains "A"
+select count(*), min(Name) where Genre = "male"
+select Genre, count(*) where Letter contains "A" group by Genre
+\quit
+Name = "not executed"
+`))
+
+	output := out.String()
+	assert.Contains(t, output, "created map index for: Genre")
+	assert.Contains(t, output, "created sorted index for: Name")
+	assert.Contains(t, output, `"Name":"Abram"`)
+	assert.Contains(t, output, "(1 rows, took")
+	assert.Contains(t, output, "more\n")
+	assert.Contains(t, output, "COUNT(*): ")
+	assert.Contains(t, output, "MIN(Name): ")
+	assert.Contains(t, output, "male (")
+	assert.Contains(t, output, "(2 groups, took")
+	assert.NotContains(t, output, "error")
+	assert.NotContains(t, output, "not executed")
+}
+
+func TestShell_MultiIndex(t *testing.T) {
+	var out bytes.Buffer
+	sh := newShell(&out)
+
+	records, err := readRecords(strings.NewReader(`[
+		{"Name": "a", "Tags": ["go", 1, {"x": 1}]},
+		{"Name": "b", "Tags": ["rust", "go"]},
+		{"Name": "c"}
+	]`), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"go", int64(1), map[string]any{"x": json.Number("1")}}, records[0]["Tags"])
+	for _, r := range records {
+		_, _ = sh.list.Insert(r)
+	}
+
+	assert.NoError(t, sh.exec(`\index Tags multi`))
+	assert.NoError(t, sh.exec(`Tags has all ("go", 1)`))
+	assert.Contains(t, out.String(), "(1 rows, took")
+
+	out.Reset()
+	assert.NoError(t, sh.exec(`Tags has any ("go")`))
+	assert.Contains(t, out.String(), "(2 rows, took")
+
+	out.Reset()
+	assert.NoError(t, sh.exec(`\explain Tags has any ("go")`))
+	assert.Contains(t, out.String(), `Tags HAS ANY ("go") [MultiValueIndex] (estimate: 2)`)
+
+	out.Reset()
+	assert.NoError(t, sh.exec(`\explain ANALYZE Tags has all ("go", 1)`))
+	assert.Contains(t, out.String(), `Tags HAS ALL ("go", 1) [MultiValueIndex] (estimate: 1, count: 1, time: `)
+}
+
+func TestShell_SortedMissingField(t *testing.T) {
+	var out bytes.Buffer
+	sh := newShell(&out)
+
+	records, err := readRecords(strings.NewReader(`[
+		{"Name": "a", "Age": 3},
+		{"Name": "b"},
+		{"Name": "c", "Age": "x"},
+		{"Name": "d", "Age": 0}
+	]`), "")
+	assert.NoError(t, err)
+	for _, r := range records {
+		_, _ = sh.list.Insert(r)
+	}
+	assert.NoError(t, sh.exec(`\index Age sorted`))
+	assert.NoError(t, sh.exec(`\index Name map`))
+
+	// the records without the field are not found as the zero value
+	assert.NoError(t, sh.exec(`Age = 0`))
+	assert.Contains(t, out.String(), `"Name":"d"`)
+	assert.Contains(t, out.String(), "(1 rows, took")
+
+	out.Reset()
+	assert.NoError(t, sh.exec(`Age < 5`))
+	assert.Contains(t, out.String(), "(2 rows, took")
+
+	// the records without the field are at the end
+	out.Reset()
+	assert.NoError(t, sh.exec(`Name != "x" ORDER BY Age`))
+	output := out.String()
+	assert.Contains(t, output, "(4 rows, took")
+	assert.Less(t, strings.Index(output, `"Name":"d"`), strings.Index(output, `"Name":"a"`))
+	assert.Less(t, strings.Index(output, `"Name":"a"`), strings.Index(output, `"Name":"b"`))
+	assert.Less(t, strings.Index(output, `"Name":"b"`), strings.Index(output, `"Name":"c"`))
+}
+
+func TestShell_Errors(t *testing.T) {
+	var out bytes.Buffer
+	sh := newShell(&out)
+
+	assert.Error(t, sh.exec(`\index Name`))
+	assert.Error(t, sh.exec(`\index Name btree`))
+	assert.Error(t, sh.exec(`\limit x`))
+	assert.Error(t, sh.exec(`\unknown`))
+	assert.Error(t, sh.exec(`\explain analyze`))
+	assert.Error(t, sh.exec(`Name = "Abram"`))
+	assert.Error(t, sh.exec(`\load not_found.json`))
+}
+
+func TestReadRecords(t *testing.T) {
+	records, err := readRecords(strings.NewReader(`[{"a": 1, "b": 1.5, "c": "x"}]`), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []record{{"a": int64(1), "b": 1.5, "c": "x"}}, records)
+
+	records, err = readRecords(strings.NewReader(`{"data": {"list": [{"a": true}]}}`), "data.list")
+	assert.NoError(t, err)
+	assert.Equal(t, []record{{"a": true}}, records)
+
+	_, err = readRecords(strings.NewReader(`{"data": []}`), "")
+	assert.Error(t, err)
+	_, err = readRecords(strings.NewReader(`{"data": []}`), "nix")
+	assert.Error(t, err)
+	_, err = readRecords(strings.NewReader(`[1, 2]`), "")
+	assert.Error(t, err)
+}
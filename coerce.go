@@ -0,0 +1,158 @@
+package fali
+
+import (
+	"maps"
+	"math"
+	"reflect"
+)
+
+// ValueTyper is implemented by Filters, which know the type of the indexed values.
+// The values of a query string (e.g. the int64 22) are converted to this type (e.g. uint8), if it is lossless.
+type ValueTyper interface {
+	ValueType() reflect.Type
+}
+
+// coerced converts the values to the value type of the Index for the field, before the Query is executed
+//
+//go:inline
+func coerced[LI Value](fieldName string, values []any, query func(values ...any) Query[LI]) Query[LI] {
+	return func(l FilterByName[LI], allIDs *BitSet[LI]) (PostingList[LI], bool, error) {
+		filter, err := l(fieldName)
+		if err != nil {
+			return nil, false, err
+		}
+
+		converted, err := coerceValues(filter, values)
+		if err != nil {
+			return nil, false, err
+		}
+		return query(converted...)(l, allIDs)
+	}
+}
+
+// coerceValues converts the values to the value type of the Filter, if the Filter is a ValueTyper
+func coerceValues(filter any, values []any) ([]any, error) {
+	vt, ok := filter.(ValueTyper)
+	if !ok {
+		return values, nil
+	}
+
+	target := vt.ValueType()
+	converted := make([]any, len(values))
+	for i, v := range values {
+		c, err := coerce(v, target)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = c
+	}
+	return converted, nil
+}
+
+// coerceComposite converts the values of the CompositeKey to the value types of the fields
+func coerceComposite[LI Value](l FilterByName[LI], name string, values CompositeKey) (CompositeKey, error) {
+	composite, _ := l(name)
+	ft, isComposite := composite.(interface{ FieldType(string) reflect.Type })
+
+	converted := make(CompositeKey, len(values))
+	for field, v := range values {
+		var target reflect.Type
+		if isComposite {
+			target = ft.FieldType(field)
+		} else if filter, err := l(field); err == nil {
+			if vt, ok := filter.(ValueTyper); ok {
+				target = vt.ValueType()
+			}
+		}
+
+		c, err := coerce(v, target)
+		if err != nil {
+			return nil, err
+		}
+		converted[field] = c
+	}
+	return converted, nil
+}
+
+// coerceCompositeParts converts the values to the value types of the Filters, which are used for the parts (see: compositeParts)
+func coerceCompositeParts[LI Value](l FilterByName[LI], values CompositeKey) (CompositeKey, error) {
+	converted := make(CompositeKey, len(values))
+	for _, part := range compositeParts(l, values) {
+		c, err := coerceComposite(l, part.name, part.values)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(converted, c)
+	}
+	return converted, nil
+}
+
+// coerce converts the value lossless to the target type:
+// numbers to other number types (e.g. int64 to uint8), if the value fits into the target type
+// and values to defined types with the same kind (e.g. string to: type Color string).
+// Floats are converted to the nearest float32 value, other numbers must not lose precision (e.g. 2.5 to int).
+// Values with other types are not changed.
+func coerce(value any, target reflect.Type) (any, error) {
+	if value == nil || target == nil || target.Kind() == reflect.Interface {
+		return value, nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type() == target {
+		return value, nil
+	}
+
+	switch {
+	case isNumber(v.Kind()) && isNumber(target.Kind()):
+		if overflows(v, target) {
+			return nil, ErrValueOverflow{value: value, typ: target}
+		}
+
+		c := v.Convert(target)
+		if v.CanFloat() && c.CanFloat() {
+			return c.Interface(), nil
+		}
+		// lossless: the converted value must be converted back to the same value
+		if !c.Convert(v.Type()).Equal(v) {
+			return nil, ErrValuePrecision{value: value, typ: target}
+		}
+		return c.Interface(), nil
+	case v.Kind() == target.Kind() && v.Type().ConvertibleTo(target):
+		return v.Convert(target).Interface(), nil
+	}
+
+	return value, nil
+}
+
+//go:inline
+func isNumber(k reflect.Kind) bool {
+	return (k >= reflect.Int && k <= reflect.Uintptr) || k == reflect.Float32 || k == reflect.Float64
+}
+
+// overflows checks, is the number outside the range of the target type
+func overflows(v reflect.Value, target reflect.Type) bool {
+	t := reflect.Zero(target)
+	switch {
+	case t.CanInt():
+		switch {
+		case v.CanUint():
+			return v.Uint() > math.MaxInt64 || t.OverflowInt(int64(v.Uint()))
+		case v.CanFloat():
+			f := math.Trunc(v.Float())
+			return !(f >= math.MinInt64 && f < math.MaxInt64) || t.OverflowInt(int64(f))
+		}
+		return t.OverflowInt(v.Int())
+	case t.CanUint():
+		switch {
+		case v.CanInt():
+			return v.Int() < 0 || t.OverflowUint(uint64(v.Int()))
+		case v.CanFloat():
+			f := math.Trunc(v.Float())
+			return !(f >= 0 && f < math.MaxUint64) || t.OverflowUint(uint64(f))
+		}
+		return t.OverflowUint(v.Uint())
+	case t.CanFloat() && v.CanFloat():
+		return t.OverflowFloat(v.Float())
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package fali
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type colorName string
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		value    any
+		target   reflect.Type
+		expected any
+	}{
+		{int64(22), reflect.TypeFor[uint8](), uint8(22)},
+		{int64(-5), reflect.TypeFor[int8](), int8(-5)},
+		{int64(7), reflect.TypeFor[float32](), float32(7)},
+		{int64(7), reflect.TypeFor[float64](), float64(7)},
+		{2.0, reflect.TypeFor[int](), 2},
+		{1.5, reflect.TypeFor[float32](), float32(1.5)},
+		// the nearest float32 value
+		{1.2, reflect.TypeFor[float32](), float32(1.2)},
+		{0.1, reflect.TypeFor[float32](), float32(0.1)},
+		{"red", reflect.TypeFor[colorName](), colorName("red")},
+		{"red", reflect.TypeFor[string](), "red"},
+		{"red", reflect.TypeFor[any](), "red"},
+		// not changed, the Index returns the error
+		{"red", reflect.TypeFor[int](), "red"},
+		{true, reflect.TypeFor[string](), true},
+		{nil, reflect.TypeFor[uint8](), nil},
+		{int64(1), nil, int64(1)},
+	}
+
+	for _, tt := range tests {
+		v, err := coerce(tt.value, tt.target)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, v)
+	}
+
+	errors := []struct {
+		value  any
+		target reflect.Type
+	}{
+		{int64(300), reflect.TypeFor[uint8]()},
+		{int64(-1), reflect.TypeFor[uint]()},
+		{uint64(1 << 63), reflect.TypeFor[int64]()},
+		{int64(1 << 40), reflect.TypeFor[int32]()},
+		{1e40, reflect.TypeFor[float32]()},
+		{-1e40, reflect.TypeFor[float32]()},
+		{1e20, reflect.TypeFor[int64]()},
+		{-1.5, reflect.TypeFor[uint8]()},
+	}
+
+	for _, tt := range errors {
+		_, err := coerce(tt.value, tt.target)
+		assert.ErrorIs(t, err, ErrValueOverflow{value: tt.value, typ: tt.target})
+	}
+
+	// the value fits, but the precision is lost
+	precision := []struct {
+		value  any
+		target reflect.Type
+	}{
+		{int64(1<<53 + 1), reflect.TypeFor[float64]()},
+		{2.5, reflect.TypeFor[int]()},
+		{-0.5, reflect.TypeFor[uint8]()},
+	}
+
+	for _, tt := range precision {
+		_, err := coerce(tt.value, tt.target)
+		assert.ErrorIs(t, err, ErrValuePrecision{value: tt.value, typ: tt.target})
+	}
+
+	_, err := coerce(int64(300), reflect.TypeFor[uint8]())
+	assert.Equal(t, "value overflow: 300 (int64) doesn't fit into the type: uint8", err.Error())
+}
+
+func TestCoerce_Float32(t *testing.T) {
+	type product struct{ price float32 }
+	il := NewIndexList[product]()
+	_ = il.CreateIndex("price", NewSortedIndex(func(p *product) float32 { return p.price }))
+	_, _ = il.Insert(product{price: 1.2})
+	_, _ = il.Insert(product{price: 2.5})
+
+	// the float64 literal 1.2 is the nearest float32 value
+	qr, err := il.QueryStr(`price = 1.2`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, qr.Count())
+
+	qr, err = il.QueryStr(`price < 2`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, qr.Count())
+
+	_, err = il.QueryStr(`price = 1000000000000000000000000000000000000000.0`)
+	assert.ErrorIs(t, err, ErrValueOverflow{value: 1e39, typ: reflect.TypeFor[float32]()})
+}
+
+func TestCoerce_QueryStr(t *testing.T) {
+	il := newPlannerList()
+	_ = il.CreateIndex("age+name", NewCompositeIndex(Field("name", (*car).Name), Field("age", (*car).Age)))
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{`age = 5`, []string{"F"}},
+		{`age = 5.0`, []string{"F"}},
+		{`age > 1 and age < 4`, []string{"C", "D"}},
+		{`age between [1, 2]`, []string{"B", "C"}},
+		{`age in (1, 3)`, []string{"B", "D"}},
+		{`not age >= 1`, []string{"A"}},
+		{`age != 0 and age <= 1`, []string{"B"}},
+		// CompositeIndex
+		{`name = "C" and age = 2`, []string{"C"}},
+		// without CompositeIndex
+		{`isnew = false and age = 3`, []string{"D"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			qr, err := il.QueryStr(tt.query)
+			assert.NoError(t, err)
+
+			names := make([]string, 0)
+			for _, c := range qr.Values() {
+				names = append(names, c.name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+
+	_, err := il.QueryStr(`age = 256`)
+	assert.ErrorIs(t, err, ErrValueOverflow{value: int64(256), typ: reflect.TypeFor[uint8]()})
+	_, err = il.QueryStr(`age = -1 or age = 2`)
+	assert.ErrorIs(t, err, ErrValueOverflow{value: int64(-1), typ: reflect.TypeFor[uint8]()})
+	_, err = il.QueryStr(`age = 2.5`)
+	assert.ErrorIs(t, err, ErrValuePrecision{value: 2.5, typ: reflect.TypeFor[uint8]()})
+	_, err = il.QueryStr(`name = "C" and age = 300`)
+	assert.ErrorIs(t, err, ErrValueOverflow{value: int64(300), typ: reflect.TypeFor[uint8]()})
+
+	// the cast is still supported
+	qr, err := il.QueryStr(`age = uint8(5)`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, qr.Count())
+
+	// Bind with an int
+	p, err := Prepare(`age = ?`)
+	assert.NoError(t, err)
+	query, err := p.Bind(5)
+	assert.NoError(t, err)
+	qr, err = il.Query(query)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, qr.Count())
+
+	// the Query builders are not changed, the value type must match
+	_, err = il.Query(Eq("age", 5))
+	assert.ErrorIs(t, err, ErrInvalidIndexValue[uint8]{5})
+}
+
+func TestCoerce_ValueType(t *testing.T) {
+	il := NewIndexListWithID(func(c *car) int { return int(c.age) })
+	_ = il.CreateIndex("color", NewMapIndex(func(c *car) colorName { return colorName(c.color) }))
+	_, _ = il.Insert(car{name: "Opel", color: "red", age: 3})
+	_, _ = il.Insert(car{name: "Audi", color: "blue", age: 7})
+
+	qr, err := il.QueryStr(`color = "red"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Opel", color: "red", age: 3}}, qr.Values())
+
+	qr, err = il.QueryStr(`id = 7`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Audi", color: "blue", age: 7}}, qr.Values())
+}
@@ -0,0 +1,286 @@
+package fali
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const CompositeIndexName = "CompositeIndex"
+
+// CompositeKey maps the field-names of a CompositeIndex to the values
+type CompositeKey = map[string]any
+
+// CompositeField is one field of a CompositeIndex, created with: Field
+type CompositeField[OBJ any] struct {
+	name      string
+	fromField FromField[OBJ, any]
+	check     func(any) error
+	valueType reflect.Type
+}
+
+// Field creates a field for a CompositeIndex, the name is the field-name in a Query.
+func Field[OBJ any, V comparable](name string, fromField FromField[OBJ, V]) CompositeField[OBJ] {
+	return CompositeField[OBJ]{
+		name:      name,
+		fromField: func(obj *OBJ) any { return fromField(obj) },
+		check: func(value any) error {
+			if _, ok := value.(V); !ok {
+				return ErrInvalidIndexValue[V]{value}
+			}
+			return nil
+		},
+		valueType: reflect.TypeFor[V](),
+	}
+}
+
+// CompositeIndex indexes a tuple of fields, it is well suited for Queries with equality on all these fields:
+// genre = "male" AND letter = "A" is one lookup, instead of two lookups and an And.
+// This index only supported Queries with the Equal Ralation, the value is a CompositeKey!
+type CompositeIndex[OBJ any, LI Value] struct {
+	fields  []CompositeField[OBJ]
+	keyType reflect.Type
+	data    map[any]*BitSet[LI]
+	cow     cow[any]
+}
+
+// NewCompositeIndex creates an Index for the given fields (at least 2).
+// If an AND of equality terms on exactly these fields is queried, the CompositeIndex is used.
+func NewCompositeIndex[OBJ any](fields ...CompositeField[OBJ]) Index32[OBJ] {
+	return newCompositeIndex[OBJ, uint32](fields...)
+}
+
+func newCompositeIndex[OBJ any, LI Value](fields ...CompositeField[OBJ]) *CompositeIndex[OBJ, LI] {
+	if len(fields) < 2 {
+		panic("a CompositeIndex needs at least 2 fields")
+	}
+
+	return &CompositeIndex[OBJ, LI]{
+		fields:  fields,
+		keyType: reflect.ArrayOf(len(fields), reflect.TypeFor[any]()),
+		data:    make(map[any]*BitSet[LI]),
+	}
+}
+
+func (ci *CompositeIndex[OBJ, LI]) Set(obj *OBJ, lidx LI) {
+	key := ci.itemKey(obj)
+	bs, found := ci.bitSet(key)
+	if !found {
+		bs = NewBitSet[LI]()
+	}
+	bs.Set(lidx)
+	ci.data[key] = bs
+}
+
+func (ci *CompositeIndex[OBJ, LI]) UnSet(obj *OBJ, lidx LI) {
+	key := ci.itemKey(obj)
+	if bs, found := ci.bitSet(key); found {
+		bs.UnSet(lidx)
+		if bs.Count() == 0 {
+			delete(ci.data, key)
+		}
+	}
+}
+
+func (ci *CompositeIndex[OBJ, LI]) Match(op Op, value any) (*BitSet[LI], error) {
+	if op != OpEq {
+		return nil, ErrInvalidOperation{CompositeIndexName, op}
+	}
+
+	key, err := ci.valueKey(value)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, found := ci.data[key]
+	if !found {
+		return NewBitSet[LI](), nil
+	}
+
+	return bs, nil
+}
+
+// Estimate returns the number of Items with the given CompositeKey
+func (ci *CompositeIndex[OBJ, LI]) Estimate(op Op, values ...any) (int, error) {
+	if len(values) != 1 {
+		return 0, ErrInvalidArgsLen{defined: "1", got: len(values)}
+	}
+
+	bs, err := ci.Match(op, values[0])
+	if err != nil {
+		return 0, err
+	}
+	return bs.Count(), nil
+}
+
+// MatchMany is not supported by CompositeIndex, so that always returns an error
+func (ci *CompositeIndex[OBJ, LI]) MatchMany(op Op, values ...any) (*BitSet[LI], error) {
+	return nil, ErrInvalidOperation{CompositeIndexName, op}
+}
+
+// Snapshot returns an immutable CompositeIndex, which shares the data until this CompositeIndex is changed.
+func (ci *CompositeIndex[OBJ, LI]) Snapshot() Filter[LI] {
+	ci.cow.snapshot()
+	return &CompositeIndex[OBJ, LI]{fields: ci.fields, keyType: ci.keyType, data: ci.data}
+}
+
+// Fields returns the field-names of the CompositeIndex
+func (ci *CompositeIndex[OBJ, LI]) Fields() []string {
+	names := make([]string, len(ci.fields))
+	for i, f := range ci.fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// FieldType returns the value type of the field or nil, if the field doesn't exist
+func (ci *CompositeIndex[OBJ, LI]) FieldType(name string) reflect.Type {
+	for _, f := range ci.fields {
+		if f.name == name {
+			return f.valueType
+		}
+	}
+	return nil
+}
+
+func (ci *CompositeIndex[OBJ, LI]) matchItem(obj *OBJ, op Op, values ...any) (bool, error) {
+	if op != OpEq || len(values) != 1 {
+		return false, ErrInvalidOperation{CompositeIndexName, op}
+	}
+
+	key, err := ci.valueKey(values[0])
+	if err != nil {
+		return false, err
+	}
+
+	return ci.itemKey(obj) == key, nil
+}
+
+// itemKey creates the map key from the fields of the Item
+func (ci *CompositeIndex[OBJ, LI]) itemKey(obj *OBJ) any {
+	key := reflect.New(ci.keyType).Elem()
+	for i, f := range ci.fields {
+		if v := f.fromField(obj); v != nil {
+			key.Index(i).Set(reflect.ValueOf(v))
+		}
+	}
+	return key.Interface()
+}
+
+// valueKey creates the map key from a CompositeKey, which contains values for all fields
+func (ci *CompositeIndex[OBJ, LI]) valueKey(value any) (any, error) {
+	values, ok := value.(CompositeKey)
+	if !ok {
+		return nil, ErrInvalidIndexValue[CompositeKey]{value}
+	}
+	if len(values) != len(ci.fields) {
+		return nil, ErrInvalidCompositeKey{strings.Join(ci.Fields(), ", ")}
+	}
+
+	key := reflect.New(ci.keyType).Elem()
+	for i, f := range ci.fields {
+		v, found := values[f.name]
+		if !found {
+			return nil, ErrInvalidCompositeKey{strings.Join(ci.Fields(), ", ")}
+		}
+		if err := f.check(v); err != nil {
+			return nil, err
+		}
+		if v != nil {
+			key.Index(i).Set(reflect.ValueOf(v))
+		}
+	}
+	return key.Interface(), nil
+}
+
+// bitSet returns the BitSet for changing, copy the BitSet if it is shared with a snapshot
+func (ci *CompositeIndex[OBJ, LI]) bitSet(key any) (*BitSet[LI], bool) {
+	if ci.cow.ownRoot() {
+		ci.data = maps.Clone(ci.data)
+	}
+
+	bs, found := ci.data[key]
+	if found && ci.cow.own(key) {
+		bs = bs.Copy()
+		ci.data[key] = bs
+	}
+	return bs, found
+}
+
+// compositeName is the name, to find a CompositeIndex for the given fields (the order is not relevant)
+func compositeName(fields []string) string {
+	sorted := slices.Clone(fields)
+	slices.Sort(sorted)
+	return "(" + strings.Join(sorted, ",") + ")"
+}
+
+// compositesName is the reserved field-name (the compositeName without fields),
+// for which FilterByName returns the registered CompositeIndices (see: registeredComposites)
+const compositesName = "()"
+
+// composites are the registered CompositeIndices of an IndexList
+type composites struct {
+	// maps the compositeName of the fields to the field-name of the CompositeIndex
+	names map[string]string
+	// the sorted fields of the CompositeIndices, the most fields first.
+	// The slice is replaced by add and remove, so it can be shared with a Snapshot.
+	fields compositeFields
+}
+
+func newComposites() composites { return composites{names: make(map[string]string)} }
+
+func (c *composites) add(fields []string, indexName string) {
+	name := compositeName(fields)
+	if _, found := c.names[name]; !found {
+		c.fields = append(slices.Clone(c.fields), slices.Sorted(slices.Values(fields)))
+		slices.SortStableFunc(c.fields, func(a, b []string) int { return len(b) - len(a) })
+	}
+	c.names[name] = indexName
+}
+
+func (c *composites) remove(indexName string) {
+	for name, n := range c.names {
+		if n == indexName {
+			delete(c.names, name)
+			c.fields = slices.DeleteFunc(slices.Clone(c.fields), func(f []string) bool { return compositeName(f) == name })
+		}
+	}
+}
+
+// clone for a Snapshot
+func (c composites) clone() composites {
+	return composites{names: maps.Clone(c.names), fields: c.fields}
+}
+
+// compositeFields is the Filter for the compositesName, it matches nothing,
+// it only carries the fields of the registered CompositeIndices
+type compositeFields [][]string
+
+func (compositeFields) Match(op Op, _ any) (*BitSet[uint32], error) {
+	return nil, ErrInvalidOperation{compositesName, op}
+}
+
+func (compositeFields) MatchMany(op Op, _ ...any) (*BitSet[uint32], error) {
+	return nil, ErrInvalidOperation{compositesName, op}
+}
+
+// registeredComposites returns the sorted fields of the registered CompositeIndices, the most fields first
+func registeredComposites[LI Value](l FilterByName[LI]) [][]string {
+	filter, err := l(compositesName)
+	if err != nil {
+		return nil
+	}
+	fields, _ := any(filter).(compositeFields)
+	return fields
+}
+
+// largestComposite returns the fields of the largest registered CompositeIndex, whose fields are all in the given fields
+func largestComposite(composites [][]string, contains func(field string) bool) []string {
+	for _, fields := range composites {
+		if !slices.ContainsFunc(fields, func(f string) bool { return !contains(f) }) {
+			return fields
+		}
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package fali
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCarCompositeIndex() *CompositeIndex[car, uint32] {
+	return newCompositeIndex[car, uint32](Field("name", (*car).Name), Field("age", (*car).Age))
+}
+
+func TestCompositeIndex_Base(t *testing.T) {
+	ci := newCarCompositeIndex()
+	ci.Set(&car{name: "Opel", age: 22}, 1)
+	ci.Set(&car{name: "Opel", age: 5}, 3)
+	ci.Set(&car{name: "Audi", age: 22}, 5)
+	ci.Set(&car{name: "Opel", age: 22}, 7)
+
+	assert.Equal(t, []string{"name", "age"}, ci.Fields())
+
+	bs, err := ci.Match(OpEq, CompositeKey{"age": uint8(22), "name": "Opel"})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1, 7}, bs.ToSlice())
+
+	bs, err = ci.Match(OpEq, CompositeKey{"name": "Opel", "age": uint8(99)})
+	assert.NoError(t, err)
+	assert.True(t, bs.IsEmpty())
+
+	ci.UnSet(&car{name: "Opel", age: 22}, 1)
+	ci.UnSet(&car{name: "Opel", age: 22}, 7)
+	bs, err = ci.Match(OpEq, CompositeKey{"age": uint8(22), "name": "Opel"})
+	assert.NoError(t, err)
+	assert.True(t, bs.IsEmpty())
+	assert.Equal(t, 2, len(ci.data))
+}
+
+func TestCompositeIndex_Error(t *testing.T) {
+	ci := newCarCompositeIndex()
+
+	_, err := ci.Match(OpLt, CompositeKey{"name": "Opel", "age": uint8(22)})
+	assert.ErrorIs(t, err, ErrInvalidOperation{CompositeIndexName, OpLt})
+
+	_, err = ci.Match(OpEq, "Opel")
+	assert.ErrorIs(t, err, ErrInvalidIndexValue[CompositeKey]{"Opel"})
+
+	_, err = ci.Match(OpEq, CompositeKey{"name": "Opel", "color": "red"})
+	assert.ErrorIs(t, err, ErrInvalidCompositeKey{"name, age"})
+
+	_, err = ci.Match(OpEq, CompositeKey{"name": "Opel"})
+	assert.ErrorIs(t, err, ErrInvalidCompositeKey{"name, age"})
+
+	_, err = ci.Match(OpEq, CompositeKey{"name": "Opel", "age": 22})
+	assert.ErrorIs(t, err, ErrInvalidIndexValue[uint8]{22})
+
+	_, err = ci.MatchMany(OpIn, "Opel")
+	assert.ErrorIs(t, err, ErrInvalidOperation{CompositeIndexName, OpIn})
+
+	assert.Panics(t, func() { NewCompositeIndex(Field("name", (*car).Name)) })
+}
+
+func TestCompositeIndex_Snapshot(t *testing.T) {
+	ci := newCarCompositeIndex()
+	ci.Set(&car{name: "Opel", age: 22}, 1)
+
+	snap := ci.Snapshot()
+	ci.Set(&car{name: "Opel", age: 22}, 2)
+
+	bs, err := snap.Match(OpEq, CompositeKey{"name": "Opel", "age": uint8(22)})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1}, bs.ToSlice())
+
+	bs, err = ci.Match(OpEq, CompositeKey{"name": "Opel", "age": uint8(22)})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1, 2}, bs.ToSlice())
+}
+
+func TestCompositeIndex_IndexList(t *testing.T) {
+	// only the CompositeIndex exist, no Index for name or age
+	il := NewIndexList[car]()
+	assert.NoError(t, il.CreateIndex("name+age", NewCompositeIndex(Field("name", (*car).Name), Field("age", (*car).Age))))
+	assert.NoError(t, il.CreateIndex("isnew", NewMapIndex((*car).IsNew)))
+
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Opel", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Audi", age: 22, isNew: true})
+
+	qr, err := il.QueryStr(`age = uint8(22) and name = "Opel"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Opel", age: 22}}, qr.Values())
+
+	qr, err = il.QueryStr(`name = "Audi" and isnew != false and age = uint8(22)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Audi", age: 22, isNew: true}}, qr.Values())
+
+	// the CompositeIndex is used for a subset of the fields
+	qr, err = il.QueryStr(`name = "Audi" and isnew = true and age = uint8(22)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Audi", age: 22, isNew: true}}, qr.Values())
+
+	qr, err = il.Query(EqAll(CompositeKey{"name": "Opel", "age": uint8(5)}))
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Opel", age: 5, isNew: true}}, qr.Values())
+
+	// fields without Index
+	_, err = il.QueryStr(`name = "Opel"`)
+	assert.ErrorIs(t, err, ErrInvalidIndexdName{"name"})
+
+	// without the CompositeIndex, the equality terms are combined with And
+	il.RemoveIndex("name+age")
+	assert.NoError(t, il.CreateIndex("name", NewMapIndex((*car).Name)))
+	assert.NoError(t, il.CreateIndex("age", NewMapIndex((*car).Age)))
+
+	qr, err = il.QueryStr(`name = "Opel" and age = uint8(22)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Opel", age: 22}}, qr.Values())
+}
+
+func TestCompositeIndex_Subset(t *testing.T) {
+	type abc struct{ a, b, c int }
+	il := NewIndexList[abc]()
+	assert.NoError(t, il.CreateIndex("a+b", NewCompositeIndex(
+		Field("a", func(v *abc) int { return v.a }),
+		Field("b", func(v *abc) int { return v.b }),
+	)))
+	assert.NoError(t, il.CreateIndex("c", NewMapIndex(func(v *abc) int { return v.c })))
+
+	_, _ = il.Insert(abc{1, 2, 3})
+	_, _ = il.Insert(abc{1, 2, 4})
+	_, _ = il.Insert(abc{1, 5, 3})
+
+	qr, err := il.QueryStr(`a = 1 and b = 2 and c = 3`)
+	assert.NoError(t, err)
+	assert.Equal(t, []abc{{1, 2, 3}}, qr.Values())
+
+	qr, err = il.Query(EqAll(CompositeKey{"a": 1, "b": 5, "c": 3}))
+	assert.NoError(t, err)
+	assert.Equal(t, []abc{{1, 5, 3}}, qr.Values())
+
+	e, err := il.Explain(`c = 3 and b = 2 and a = 1`)
+	assert.NoError(t, err)
+	assert.Equal(t, "(a, b) = (1, 2)", e.Plan.Children[0].Expr.(TermCompositeExpr).String())
+	assert.Equal(t, CompositeIndexName, e.Plan.Children[0].Index)
+	assert.Equal(t, "MapIndex", e.Plan.Children[1].Index)
+
+	// the largest CompositeIndex is used: (a, b, c) and not (a, b)
+	assert.NoError(t, il.CreateIndex("a+b+c", NewCompositeIndex(
+		Field("a", func(v *abc) int { return v.a }),
+		Field("b", func(v *abc) int { return v.b }),
+		Field("c", func(v *abc) int { return v.c }),
+	)))
+	e, err = il.Explain(`c = 3 and b = 2 and a = 1`)
+	assert.NoError(t, err)
+	assert.Equal(t, "CompositeIndex", e.Plan.Index)
+}
+
+func TestCompositeIndex_MergeComposites(t *testing.T) {
+	eq := func(field string, value any) TermExpr { return TermExpr{Field: field, Op: OpEq, Value: value} }
+	gt := TermExpr{Field: "c", Op: OpGt, Value: 1}
+	ab := [][]string{{"a", "b"}}
+
+	tests := []struct {
+		name       string
+		expr       Expr
+		composites [][]string
+		expected   Expr
+	}{
+		{
+			name:       "two equals",
+			expr:       BinaryExpr{Op: ExprAnd, Left: eq("a", 1), Right: eq("b", 2)},
+			composites: ab,
+			expected:   TermCompositeExpr{Fields: []string{"a", "b"}, Values: []any{1, 2}},
+		},
+		{
+			name:     "without CompositeIndex",
+			expr:     BinaryExpr{Op: ExprAnd, Left: eq("a", 1), Right: eq("b", 2)},
+			expected: BinaryExpr{Op: ExprAnd, Left: eq("a", 1), Right: eq("b", 2)},
+		},
+		{
+			name:       "other CompositeIndex",
+			expr:       BinaryExpr{Op: ExprAnd, Left: eq("a", 1), Right: eq("b", 2)},
+			composites: [][]string{{"a", "c"}},
+			expected:   BinaryExpr{Op: ExprAnd, Left: eq("a", 1), Right: eq("b", 2)},
+		},
+		{
+			name: "three equals",
+			expr: BinaryExpr{Op: ExprAnd,
+				Left:  BinaryExpr{Op: ExprAnd, Left: eq("c", 3), Right: eq("b", 2)},
+				Right: eq("a", 1),
+			},
+			composites: [][]string{{"a", "b", "c"}, {"a", "b"}},
+			expected:   TermCompositeExpr{Fields: []string{"a", "b", "c"}, Values: []any{1, 2, 3}},
+		},
+		{
+			name: "subset",
+			expr: BinaryExpr{Op: ExprAnd,
+				Left:  BinaryExpr{Op: ExprAnd, Left: eq("a", 1), Right: eq("b", 2)},
+				Right: eq("c", 3),
+			},
+			composites: ab,
+			expected:   BinaryExpr{Op: ExprAnd, Left: TermCompositeExpr{Fields: []string{"a", "b"}, Values: []any{1, 2}}, Right: eq("c", 3)},
+		},
+		{
+			name: "pull equal out of and",
+			expr: BinaryExpr{Op: ExprAnd,
+				Left:  BinaryExpr{Op: ExprAnd, Left: eq("a", 1), Right: gt},
+				Right: eq("b", 2),
+			},
+			composites: ab,
+			expected:   BinaryExpr{Op: ExprAnd, Left: TermCompositeExpr{Fields: []string{"a", "b"}, Values: []any{1, 2}}, Right: gt},
+		},
+		{
+			name: "pull equal out of and not",
+			expr: BinaryExpr{Op: ExprAnd,
+				Left:  BinaryExpr{Op: ExprAnd, Left: eq("a", 1), Right: NotExpr{Child: eq("c", 3)}},
+				Right: eq("b", 2),
+			},
+			composites: ab,
+			expected:   BinaryExpr{Op: ExprAndNot, Left: TermCompositeExpr{Fields: []string{"a", "b"}, Values: []any{1, 2}}, Right: eq("c", 3)},
+		},
+		{
+			name: "in or",
+			expr: BinaryExpr{Op: ExprOr,
+				Left:  BinaryExpr{Op: ExprAnd, Left: eq("a", 1), Right: eq("b", 2)},
+				Right: gt,
+			},
+			composites: ab,
+			expected:   BinaryExpr{Op: ExprOr, Left: TermCompositeExpr{Fields: []string{"a", "b"}, Values: []any{1, 2}}, Right: gt},
+		},
+		{
+			name:       "same field",
+			expr:       BinaryExpr{Op: ExprAnd, Left: eq("a", 1), Right: eq("a", 2)},
+			composites: ab,
+			expected:   BinaryExpr{Op: ExprAnd, Left: eq("a", 1), Right: eq("a", 2)},
+		},
+		{
+			name:       "or",
+			expr:       BinaryExpr{Op: ExprOr, Left: eq("a", 1), Right: eq("b", 2)},
+			composites: ab,
+			expected:   BinaryExpr{Op: ExprOr, Left: eq("a", 1), Right: eq("b", 2)},
+		},
+		{
+			name:       "id",
+			expr:       BinaryExpr{Op: ExprAnd, Left: eq("id", 1), Right: eq("b", 2)},
+			composites: [][]string{{"b", "id"}},
+			expected:   BinaryExpr{Op: ExprAnd, Left: eq("id", 1), Right: eq("b", 2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, mergeComposites(optimize(tt.expr), tt.composites))
+		})
+	}
+}
+
+func TestCompositeIndex_Registered(t *testing.T) {
+	type abc struct{ a, b, c int }
+	a := Field("a", func(v *abc) int { return v.a })
+	b := Field("b", func(v *abc) int { return v.b })
+	c := Field("c", func(v *abc) int { return v.c })
+
+	il := NewIndexList[abc]()
+	assert.Nil(t, registeredComposites(il.indexMap.FilterByName))
+
+	assert.NoError(t, il.CreateIndex("b+a", NewCompositeIndex(b, a)))
+	assert.NoError(t, il.CreateIndex("a+b+c", NewCompositeIndex(a, b, c)))
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"a", "b"}}, registeredComposites(il.indexMap.FilterByName))
+
+	snap, err := il.Snapshot()
+	assert.NoError(t, err)
+
+	il.RemoveIndex("a+b+c")
+	assert.Equal(t, [][]string{{"a", "b"}}, registeredComposites(il.indexMap.FilterByName))
+	// the Snapshot is not changed
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"a", "b"}}, registeredComposites(snap.FilterByName))
+
+	// the reserved name is not a Filter for a query
+	_, err = il.QueryStr(`() = 1`)
+	assert.Error(t, err)
+}
@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"fmt"
@@ -46,3 +46,137 @@ type ErrInvalidArgsLen struct {
 func (e ErrInvalidArgsLen) Error() string {
 	return fmt.Sprintf("expected: %s values, got: %d", e.defined, e.got)
 }
+
+type ErrInvalidFormat struct{ msg string }
+
+func (e ErrInvalidFormat) Error() string {
+	return fmt.Sprintf("invalid format: %s", e.msg)
+}
+
+type ErrChecksumMismatch struct {
+	expected uint32
+	got      uint32
+}
+
+func (e ErrChecksumMismatch) Error() string {
+	return fmt.Sprintf("checksum mismatch, expected: %x, got: %x", e.expected, e.got)
+}
+
+type ErrUnsupportedVersion struct{ version uint16 }
+
+func (e ErrUnsupportedVersion) Error() string {
+	return fmt.Sprintf("unsupported version: %d", e.version)
+}
+
+type ErrTornRecord struct {
+	file   string
+	offset int
+}
+
+func (e ErrTornRecord) Error() string {
+	return fmt.Sprintf("torn record in: %s on offset: %d", e.file, e.offset)
+}
+
+type ErrWALNotOpened struct{}
+
+func (e ErrWALNotOpened) Error() string { return "write-ahead log is not opened" }
+
+type ErrWALAlreadyOpened struct{}
+
+func (e ErrWALAlreadyOpened) Error() string { return "write-ahead log is already opened" }
+
+type ErrSnapshotNotSupported struct{ fieldName string }
+
+func (e ErrSnapshotNotSupported) Error() string {
+	return fmt.Sprintf("index for field name: %s doesn't support snapshots", e.fieldName)
+}
+
+type ErrReadOnly struct{}
+
+func (e ErrReadOnly) Error() string { return "the query result of a snapshot is read-only" }
+
+type ErrInvalidCompositeKey struct{ fields string }
+
+func (e ErrInvalidCompositeKey) Error() string {
+	return fmt.Sprintf("the composite key must contain values for exactly the fields: %s", e.fields)
+}
+
+type ErrUniqueViolation struct {
+	field string
+	value any
+}
+
+func (e ErrUniqueViolation) Error() string {
+	return fmt.Sprintf("unique violation, field: %q, value: %v already exists", e.field, e.value)
+}
+
+type ErrMissingNamedArg struct{ name string }
+
+func (e ErrMissingNamedArg) Error() string {
+	return fmt.Sprintf("missing value for the placeholder: :%s", e.name)
+}
+
+type ErrUnknownNamedArg struct{ name string }
+
+func (e ErrUnknownNamedArg) Error() string {
+	return fmt.Sprintf("the placeholder: :%s doesn't exist", e.name)
+}
+
+type ErrValueOverflow struct {
+	value any
+	typ   reflect.Type
+}
+
+func (e ErrValueOverflow) Error() string {
+	return fmt.Sprintf("value overflow: %v (%T) doesn't fit into the type: %s", e.value, e.value, e.typ)
+}
+
+type ErrValuePrecision struct {
+	value any
+	typ   reflect.Type
+}
+
+func (e ErrValuePrecision) Error() string {
+	return fmt.Sprintf("value precision: %v (%T) can not be converted to the type: %s without losing precision", e.value, e.value, e.typ)
+}
+
+type ErrInvalidClauseValue struct {
+	clause string
+	value  any
+}
+
+func (e ErrInvalidClauseValue) Error() string {
+	return fmt.Sprintf("invalid value for %s: %v", e.clause, e.value)
+}
+
+type ErrStatementClause struct{ clause string }
+
+func (e ErrStatementClause) Error() string {
+	return fmt.Sprintf("%s is not supported for a Query, use a Statement (see: ParseStatement)", e.clause)
+}
+
+type ErrNotSortable struct{ field string }
+
+func (e ErrNotSortable) Error() string {
+	return fmt.Sprintf("the field: %s can not be sorted, an Index with ordered values is required", e.field)
+}
+
+type ErrInvalidCursor struct{ cursor string }
+
+func (e ErrInvalidCursor) Error() string { return fmt.Sprintf("invalid cursor: %q", e.cursor) }
+
+type ErrInvalidAggregation struct{ agg string }
+
+func (e ErrInvalidAggregation) Error() string { return fmt.Sprintf("invalid aggregation: %s", e.agg) }
+
+type ErrGroupOrderBy struct{ field, groupBy string }
+
+func (e ErrGroupOrderBy) Error() string {
+	return fmt.Sprintf("ORDER BY %s is not supported with GROUP BY %s, only the groups can be ordered by: %s", e.field, e.groupBy, e.groupBy)
+}
+
+type ErrNotSerializable struct{ typ reflect.Type }
+
+func (e ErrNotSerializable) Error() string {
+	return fmt.Sprintf("the type: %v is not serializable, a bool, number, string or an encoding.BinaryMarshaler is required", e.typ)
+}
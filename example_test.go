@@ -0,0 +1,29 @@
+package fali_test
+
+import (
+	"fmt"
+
+	"github.com/lima1909/fali"
+)
+
+type car struct {
+	Name string
+	Age  uint8
+}
+
+func Example() {
+	il := fali.NewIndexListWithID(func(c *car) string { return c.Name })
+	_ = il.CreateIndex("age", fali.NewSortedIndex(fali.FromName[car, uint8]("Age")))
+
+	_, _ = il.Insert(car{Name: "Opel", Age: 22})
+	_, _ = il.Insert(car{Name: "Mercedes", Age: 5})
+	_, _ = il.Insert(car{Name: "Dacia", Age: 22})
+
+	qr, err := il.QueryStr(`age = uint8(22) and not(id = "Opel")`)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(qr.Values())
+	// Output: [{Dacia 22}]
+}
@@ -0,0 +1,194 @@
+package fali
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Explain describes, how a query string is executed
+type Explain struct {
+	Query     string
+	Parsed    Expr // the AST from the parser
+	Optimized Expr // the AST after the rewrites, like: AndNot, BETWEEN folding
+	Plan      *ExplainNode
+	Analyzed  bool
+	Err       error // the error of the execution (only with analyze)
+}
+
+// ExplainNode is a node of the optimized AST with the Index, the estimate and with analyze the actual result.
+type ExplainNode struct {
+	Expr     Expr
+	Index    string // the Index type of the field(s), only for terms
+	Estimate int    // the estimated number of Items, -1 if unknown
+	Children []*ExplainNode
+
+	// only with analyze
+	Executed bool // false, if the node was not executed (early exit)
+	Count    int
+	Duration time.Duration
+	Err      error
+
+	estimate estimateFn[uint32]
+}
+
+// Explain returns the parsed and the optimized AST and the Index for every field of the query string.
+func (l *IndexList[T, ID]) Explain(queryStr string) (Explain, error) {
+	return l.explain(queryStr, false)
+}
+
+// ExplainAnalyze executes the query string and returns the Explain with the count and duration for every node.
+func (l *IndexList[T, ID]) ExplainAnalyze(queryStr string) (Explain, error) {
+	return l.explain(queryStr, true)
+}
+
+func (l *IndexList[T, ID]) explain(queryStr string, analyze bool) (Explain, error) {
+	parsed, err := parse(queryStr)
+	if err != nil {
+		return Explain{}, err
+	}
+
+	ast := parsed.ast
+	if ast == nil {
+		// SELECT without a condition
+		return Explain{Query: queryStr}, nil
+	}
+	optAst := optimize(ast)
+
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	// the equality terms are merged with the registered CompositeIndices, like at execution (see: planComposites)
+	p := planExpr(mergeComposites(optAst, registeredComposites(l.indexMap.FilterByName)), analyze)
+	p.node.describe(l.indexMap.FilterByName)
+	e := Explain{Query: queryStr, Parsed: ast, Optimized: optAst, Plan: p.node, Analyzed: analyze}
+	if analyze {
+		_, _, e.Err = p.query(l.indexMap.FilterByName, l.indexMap.allIDs)
+	}
+
+	return e, nil
+}
+
+// analyze wraps the Query and saves the count and the duration
+func (n *ExplainNode) analyze(query Query32) Query32 {
+	return func(l FilterByName32, allIDs *BitSet[uint32]) (PostingList[uint32], bool, error) {
+		start := time.Now()
+		bs, canMutate, err := query(l, allIDs)
+		n.Duration += time.Since(start)
+		n.Executed = true
+		n.Err = err
+		if err == nil {
+			n.Count = bs.Count()
+		}
+		return bs, canMutate, err
+	}
+}
+
+// describe sets the Index and the estimate for the node and the children
+func (n *ExplainNode) describe(l FilterByName32) {
+	n.Estimate = -1
+	if n.estimate != nil {
+		if cost, err := n.estimate(l); err == nil && cost != unknownCost {
+			n.Estimate = cost
+		}
+	}
+
+	switch e := n.Expr.(type) {
+	case TermExpr:
+		n.Index = filterIndexName(l, e.Field)
+	case TermManyExpr:
+		n.Index = filterIndexName(l, e.Field)
+	case TermCompositeExpr:
+		values := make(CompositeKey, len(e.Fields))
+		for i, field := range e.Fields {
+			values[field] = e.Values[i]
+		}
+		parts := compositeParts(l, values)
+		names := make([]string, len(parts))
+		for i, part := range parts {
+			if len(part.values) > 1 {
+				names[i] = CompositeIndexName
+			} else {
+				names[i] = filterIndexName(l, part.name)
+			}
+		}
+		n.Index = strings.Join(names, ", ")
+	}
+
+	for _, c := range n.Children {
+		c.describe(l)
+	}
+}
+
+// String returns the Explain as readable text
+func (e Explain) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Query:     %s\n", e.Query)
+	fmt.Fprintf(&sb, "Parsed:    %s\n", e.Parsed)
+	fmt.Fprintf(&sb, "Optimized: %s\n", e.Optimized)
+	sb.WriteString("Plan:\n")
+	if e.Plan != nil {
+		e.Plan.write(&sb, 1, e.Analyzed)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&sb, "Error:     %s\n", e.Err)
+	}
+	return sb.String()
+}
+
+func (n *ExplainNode) write(sb *strings.Builder, depth int, analyzed bool) {
+	sb.WriteString(strings.Repeat("  ", depth))
+
+	switch e := n.Expr.(type) {
+	case BinaryExpr:
+		sb.WriteString(e.Op.String())
+	case NotExpr:
+		sb.WriteString(ExprNot.String())
+	default:
+		fmt.Fprint(sb, n.Expr)
+	}
+	if n.Index != "" {
+		fmt.Fprintf(sb, " [%s]", n.Index)
+	}
+
+	details := make([]string, 0, 3)
+	if n.Estimate >= 0 {
+		details = append(details, fmt.Sprintf("estimate: %d", n.Estimate))
+	}
+	switch {
+	case !analyzed:
+	case n.Err != nil:
+		details = append(details, fmt.Sprintf("error: %s", n.Err))
+	case !n.Executed:
+		details = append(details, "not executed")
+	default:
+		details = append(details, fmt.Sprintf("count: %d", n.Count), fmt.Sprintf("time: %s", n.Duration))
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(sb, " (%s)", strings.Join(details, ", "))
+	}
+	sb.WriteString("\n")
+
+	for _, c := range n.Children {
+		c.write(sb, depth+1, analyzed)
+	}
+}
+
+// filterIndexName returns the Index type name for the field, e.g. SortedIndex
+func filterIndexName(l FilterByName32, fieldName string) string {
+	filter, err := l(fieldName)
+	if err != nil {
+		return err.Error()
+	}
+
+	t := reflect.TypeOf(filter)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	name, _, _ := strings.Cut(t.Name(), "[")
+	if name == "idMapIndex" {
+		return IDMapIndexName
+	}
+	return name
+}
@@ -0,0 +1,99 @@
+package fali
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain_Base(t *testing.T) {
+	il := newPlannerList()
+
+	e, err := il.Explain(`age > uint8(1) and age < uint8(5) and isnew = true and not name contains "B"`)
+	assert.NoError(t, err)
+	assert.False(t, e.Analyzed)
+	assert.Equal(t,
+		`(((age > uint8(1) AND age < uint8(5)) AND isnew = true) AND NOT name CONTAINS "B")`,
+		e.Parsed.(BinaryExpr).String())
+	assert.Equal(t,
+		`((age BETWEEN ]uint8(1), uint8(5)[ AND isnew = true) AND NOT name CONTAINS "B")`,
+		e.Optimized.(BinaryExpr).String())
+
+	assert.Equal(t, ExprAndNot, e.Plan.Expr.kind())
+	assert.Len(t, e.Plan.Children, 2)
+	and := e.Plan.Children[0]
+	assert.Len(t, and.Children, 2)
+	assert.Equal(t, SortedIndexName, and.Children[0].Index)
+	assert.Equal(t, 5, and.Children[0].Estimate)
+	assert.Equal(t, MapIndexName, and.Children[1].Index)
+	assert.Equal(t, 19, and.Children[1].Estimate)
+	assert.Equal(t, TrigramIndexName, e.Plan.Children[1].Index)
+	assert.Equal(t, -1, e.Plan.Children[1].Estimate)
+	assert.False(t, e.Plan.Executed)
+
+	assert.Equal(t, `Query:     age > uint8(1) and age < uint8(5) and isnew = true and not name contains "B"
+Parsed:    (((age > uint8(1) AND age < uint8(5)) AND isnew = true) AND NOT name CONTAINS "B")
+Optimized: ((age BETWEEN ]uint8(1), uint8(5)[ AND isnew = true) AND NOT name CONTAINS "B")
+Plan:
+  AND NOT (estimate: 5)
+    AND (estimate: 5)
+      age BETWEEN ]uint8(1), uint8(5)[ [SortedIndex] (estimate: 5)
+      isnew = true [MapIndex] (estimate: 19)
+    name CONTAINS "B" [TrigramIndex]
+`, e.String())
+}
+
+func TestExplain_Analyze(t *testing.T) {
+	il := newPlannerList()
+
+	e, err := il.ExplainAnalyze(`age = uint8(3) and isnew = true or id = "A"`)
+	assert.NoError(t, err)
+	assert.NoError(t, e.Err)
+	assert.True(t, e.Analyzed)
+
+	// OR
+	assert.True(t, e.Plan.Executed)
+	assert.Equal(t, 1, e.Plan.Count)
+	assert.Positive(t, e.Plan.Duration)
+
+	// without a CompositeIndex, the equality terms are not merged
+	and := e.Plan.Children[0]
+	assert.Equal(t, ExprAnd, and.Expr.kind())
+	assert.Equal(t, SortedIndexName, and.Children[0].Index)
+	assert.Equal(t, MapIndexName, and.Children[1].Index)
+	assert.True(t, and.Executed)
+	assert.Equal(t, 0, and.Count)
+
+	id := e.Plan.Children[1]
+	assert.Equal(t, IDMapIndexName, id.Index)
+	assert.Equal(t, 1, id.Estimate)
+	assert.Equal(t, 1, id.Count)
+
+	// early exit, the age = 99 is empty
+	e, err = il.ExplainAnalyze(`name contains "B" and age > uint8(99)`)
+	assert.NoError(t, err)
+	and = e.Plan
+	assert.Equal(t, 0, and.Count)
+	assert.False(t, and.Children[0].Executed)
+	assert.True(t, and.Children[1].Executed)
+	assert.Contains(t, e.String(), `name CONTAINS "B" [TrigramIndex] (not executed)`)
+	assert.Contains(t, e.String(), `age > uint8(99) [SortedIndex] (estimate: 0, count: 0, time: `)
+}
+
+func TestExplain_Errors(t *testing.T) {
+	il := newPlannerList()
+
+	_, err := il.Explain(`age = `)
+	assert.Error(t, err)
+
+	e, err := il.ExplainAnalyze(`age = "5"`)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, e.Err, ErrInvalidIndexValue[uint8]{"5"})
+	assert.ErrorIs(t, e.Plan.Err, ErrInvalidIndexValue[uint8]{"5"})
+	assert.Contains(t, e.String(), `age = "5" [SortedIndex] (error: `)
+	assert.Contains(t, e.String(), "Error:     ")
+
+	e, err = il.Explain(`unknown = 5`)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrInvalidIndexdName{"unknown"}.Error(), e.Plan.Index)
+}
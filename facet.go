@@ -0,0 +1,168 @@
+package fali
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Facet is a value of a field and the number of Items in the result with this value
+type Facet struct {
+	Value any
+	Count int
+}
+
+// Group is a group of: GROUP BY, the value of the field with the number of Items and the values of the Aggregations
+type Group struct {
+	Value      any
+	Count      int
+	Aggregates []AggregateValue
+}
+
+// bucketer is implemented by Filters, which have the List-Indices for every value (MapIndex, SortedIndex, MultiValueIndex)
+type bucketer[LI Value] interface {
+	buckets(visit func(any, PostingList[LI]) bool)
+}
+
+// Facets counts the Items of the result for every value of the field, e.g. [male: 120, female: 98].
+// The Facets are sorted by the count (descending) and by the value, values without Items are removed.
+// With a MapIndex, SortedIndex or MultiValueIndex the List-Indices of every value are intersected with the result,
+// otherwise the values of the Items are counted.
+func (q *QueryResult[T, ID]) Facets(fieldName string) ([]Facet, error) {
+	var facets []Facet
+	var err error
+	q.readItems(func(items *FreeList[T]) {
+		l := q.filterByName()
+		var filter Filter32
+		if filter, err = l(fieldName); err != nil {
+			return
+		}
+
+		if b, ok := filter.(bucketer[uint32]); ok {
+			b.buckets(func(value any, p PostingList[uint32]) bool {
+				if count := countAnd(p, q.bitSet); count > 0 {
+					facets = append(facets, Facet{Value: value, Count: count})
+				}
+				return true
+			})
+		} else {
+			var groups []group
+			if groups, err = groupByValues(q.bitSet, fieldName, filter, items); err != nil {
+				return
+			}
+			for _, g := range groups {
+				facets = append(facets, Facet{Value: g.value, Count: g.lidxs.Count()})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(facets, func(a, b Facet) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return compareAny(a.Value, b.Value)
+	})
+	return facets, nil
+}
+
+// GroupBy groups the Items of the result by the values of the field and calculates the Aggregations for every group.
+// The Groups are sorted by the value.
+func (q *QueryResult[T, ID]) GroupBy(fieldName string, aggs ...Aggregation) ([]Group, error) {
+	var groups []Group
+	var err error
+	q.readItems(func(items *FreeList[T]) {
+		groups, err = groupBy(q.bitSet, fieldName, aggs, q.filterByName(), items)
+	})
+	return groups, err
+}
+
+// Groups returns the Groups of the GROUP BY of the query string
+func (q *QueryResult[T, ID]) Groups() []Group { return q.groups }
+
+// group are the List-Indices of the result with the value
+type group struct {
+	value any
+	lidxs *BitSet[uint32]
+}
+
+// groupBy groups the result by the values of the field and calculates the Aggregations for every group
+func groupBy[T any](result *BitSet[uint32], fieldName string, aggs []Aggregation, l FilterByName32, items *FreeList[T]) ([]Group, error) {
+	buckets, err := groupBuckets(result, fieldName, l, items)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateGroups(buckets, aggs, l, items)
+}
+
+// groupBuckets returns the List-Indices of the result for every value of the field, sorted by the value
+func groupBuckets[T any](result *BitSet[uint32], fieldName string, l FilterByName32, items *FreeList[T]) ([]group, error) {
+	filter, err := l(fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []group
+	if b, ok := filter.(bucketer[uint32]); ok {
+		b.buckets(func(value any, p PostingList[uint32]) bool {
+			if lidxs := andBitSet(p, result); !lidxs.IsEmpty() {
+				buckets = append(buckets, group{value: value, lidxs: lidxs})
+			}
+			return true
+		})
+	} else if buckets, err = groupByValues(result, fieldName, filter, items); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(buckets, func(a, b group) int { return compareAny(a.value, b.value) })
+	return buckets, nil
+}
+
+// aggregateGroups calculates the Aggregations for every group
+func aggregateGroups[T any](buckets []group, aggs []Aggregation, l FilterByName32, items *FreeList[T]) ([]Group, error) {
+	groups := make([]Group, len(buckets))
+	for i, b := range buckets {
+		values, err := aggregate(b.lidxs, aggs, l, items)
+		if err != nil {
+			return nil, err
+		}
+		groups[i] = Group{Value: b.value, Count: b.lidxs.Count(), Aggregates: values}
+	}
+	return groups, nil
+}
+
+// groupByValues groups the result by the values of the Items
+func groupByValues[T any](result *BitSet[uint32], fieldName string, filter Filter32, items *FreeList[T]) ([]group, error) {
+	valuer, ok := filter.(itemValuer[T])
+	if !ok {
+		return nil, ErrInvalidAggregation{fmt.Sprintf("GROUP BY %s", fieldName)}
+	}
+
+	pos := make(map[any]int)
+	var groups []group
+	result.Values(func(lidx uint32) bool {
+		item, _ := items.Get(int(lidx))
+		value := valuer.itemValue(&item)
+		i, found := pos[value]
+		if !found {
+			i = len(groups)
+			pos[value] = i
+			groups = append(groups, group{value: value, lidxs: NewBitSet[uint32]()})
+		}
+		groups[i].lidxs.Set(lidx)
+		return true
+	})
+	return groups, nil
+}
+
+// compareAny compares values with the same ordered kind (see: compareValues), other values by the formatted value
+func compareAny(a, b any) int {
+	ka, kb := reflect.ValueOf(a).Kind(), reflect.ValueOf(b).Kind()
+	if ka == kb && isOrdered(ka) {
+		return compareValues(a, b)
+	}
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
@@ -0,0 +1,162 @@
+package fali
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFacets(t *testing.T) {
+	il := newPlannerList()
+	_ = il.CreateIndex("decade", NewSortedIndex(func(c *car) uint8 { return c.age / 10 }))
+
+	qr, err := il.QueryStr(`age < uint8(15)`)
+	assert.NoError(t, err)
+
+	// MapIndex
+	facets, err := qr.Facets("isnew")
+	assert.NoError(t, err)
+	assert.Equal(t, []Facet{{Value: true, Count: 14}, {Value: false, Count: 1}}, facets)
+
+	// SortedIndex
+	facets, err = qr.Facets("decade")
+	assert.NoError(t, err)
+	assert.Equal(t, []Facet{{Value: uint8(0), Count: 10}, {Value: uint8(1), Count: 5}}, facets)
+
+	// TrigramIndex, counts the values of the Items, equal counts are sorted by the value
+	qr, err = il.QueryStr(`age < uint8(3)`)
+	assert.NoError(t, err)
+	facets, err = qr.Facets("name")
+	assert.NoError(t, err)
+	assert.Equal(t, []Facet{{Value: "A", Count: 1}, {Value: "B", Count: 1}, {Value: "C", Count: 1}}, facets)
+
+	// values without Items in the result are removed
+	qr, err = il.QueryStr(`age > uint8(99)`)
+	assert.NoError(t, err)
+	facets, err = qr.Facets("isnew")
+	assert.NoError(t, err)
+	assert.Empty(t, facets)
+
+	_, err = qr.Facets("unknown")
+	assert.ErrorIs(t, err, ErrInvalidIndexdName{"unknown"})
+}
+
+func TestFacets_MultiValueIndex(t *testing.T) {
+	il := NewIndexList[article]()
+	assert.NoError(t, il.CreateIndex("tags", NewMultiValueIndex((*article).Tags)))
+	_, _ = il.Insert(article{title: "fali", tags: []string{"go", "db"}})
+	_, _ = il.Insert(article{title: "hello", tags: []string{"go"}})
+	_, _ = il.Insert(article{title: "sled", tags: []string{"rust", "db"}})
+
+	qr, err := il.QueryStr(`tags has any ("go", "rust")`)
+	assert.NoError(t, err)
+	facets, err := qr.Facets("tags")
+	assert.NoError(t, err)
+	assert.Equal(t, []Facet{{Value: "db", Count: 2}, {Value: "go", Count: 2}, {Value: "rust", Count: 1}}, facets)
+}
+
+func TestGroupBy(t *testing.T) {
+	il := newPlannerList()
+
+	qr, err := il.QueryStr(`age > uint8(1) and age < uint8(6)`)
+	assert.NoError(t, err)
+
+	groups, err := qr.GroupBy("isnew", Aggregation{Func: AggSum, Field: "age"}, Aggregation{Func: AggMax, Field: "name"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Group{
+		{Value: false, Count: 1, Aggregates: []AggregateValue{
+			{Aggregation: Aggregation{Func: AggSum, Field: "age"}, Value: uint64(3)},
+			{Aggregation: Aggregation{Func: AggMax, Field: "name"}, Value: "D"},
+		}},
+		{Value: true, Count: 3, Aggregates: []AggregateValue{
+			{Aggregation: Aggregation{Func: AggSum, Field: "age"}, Value: uint64(11)},
+			{Aggregation: Aggregation{Func: AggMax, Field: "name"}, Value: "F"},
+		}},
+	}, groups)
+
+	// without an Index with buckets
+	groups, err = qr.GroupBy("id")
+	assert.NoError(t, err)
+	assert.Len(t, groups, 4)
+	assert.Equal(t, Group{Value: "C", Count: 1, Aggregates: []AggregateValue{}}, groups[0])
+
+	_, err = qr.GroupBy("isnew", Aggregation{Func: AggSum, Field: "name"})
+	assert.ErrorIs(t, err, ErrInvalidAggregation{"SUM(name)"})
+}
+
+func TestGroupBy_Clauses(t *testing.T) {
+	il := newPlannerList()
+
+	// LIMIT and OFFSET cut the Groups
+	qr, err := il.QueryStr(`SELECT isnew, COUNT(*) WHERE age > uint8(0) GROUP BY isnew LIMIT 1`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Group{
+		{Value: false, Count: 1, Aggregates: []AggregateValue{{Aggregation: Aggregation{Func: AggCount}, Value: 1}}},
+	}, qr.Groups())
+	assert.Equal(t, 1, qr.Count())
+
+	qr, err = il.QueryStr(`SELECT isnew, COUNT(*) WHERE age > uint8(0) GROUP BY isnew LIMIT 1 OFFSET 1`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Group{
+		{Value: true, Count: 18, Aggregates: []AggregateValue{{Aggregation: Aggregation{Func: AggCount}, Value: 18}}},
+	}, qr.Groups())
+	assert.Equal(t, 18, qr.Count())
+
+	// ORDER BY the GROUP BY field
+	qr, err = il.QueryStr(`SELECT isnew, COUNT(*) WHERE age > uint8(0) GROUP BY isnew ORDER BY isnew DESC`)
+	assert.NoError(t, err)
+	assert.Len(t, qr.Groups(), 2)
+	assert.Equal(t, true, qr.Groups()[0].Value)
+	assert.Equal(t, false, qr.Groups()[1].Value)
+	assert.Equal(t, 19, qr.Count())
+
+	qr, err = il.QueryStr(`age > uint8(0) GROUP BY isnew ORDER BY isnew DESC OFFSET 5`)
+	assert.NoError(t, err)
+	assert.Empty(t, qr.Groups())
+	assert.True(t, qr.IsEmpty())
+}
+
+func TestGroupBy_Select(t *testing.T) {
+	il := newPlannerList()
+
+	qr, err := il.QueryStr(`SELECT isnew, COUNT(*), MIN(age) WHERE age < uint8(5) GROUP BY isnew`)
+	assert.NoError(t, err)
+	assert.Nil(t, qr.Aggregates())
+	assert.Equal(t, []Group{
+		{Value: false, Count: 1, Aggregates: []AggregateValue{
+			{Aggregation: Aggregation{Func: AggCount}, Value: 1},
+			{Aggregation: Aggregation{Func: AggMin, Field: "age"}, Value: uint8(3)},
+		}},
+		{Value: true, Count: 4, Aggregates: []AggregateValue{
+			{Aggregation: Aggregation{Func: AggCount}, Value: 4},
+			{Aggregation: Aggregation{Func: AggMin, Field: "age"}, Value: uint8(0)},
+		}},
+	}, qr.Groups())
+
+	// GROUP BY without SELECT
+	qr, err = il.QueryStr(`age < uint8(5) group by isnew`)
+	assert.NoError(t, err)
+	assert.Len(t, qr.Groups(), 2)
+	assert.Equal(t, 5, qr.Count())
+
+	stmt, err := ParseStatement(`select count(*) where age > 1 group by isnew order by isnew desc limit 2`)
+	assert.NoError(t, err)
+	assert.Equal(t, "isnew", stmt.GroupBy)
+	assert.Equal(t, 2, stmt.Limit)
+
+	_, err = ParseStatement(`select count(*) where age > 1 group by isnew order by age`)
+	assert.ErrorIs(t, err, ErrGroupOrderBy{field: "age", groupBy: "isnew"})
+	_, err = il.QueryStatement(Statement{Query: All(), GroupBy: "isnew", OrderBy: []OrderBy{{Field: "age"}}})
+	assert.ErrorIs(t, err, ErrGroupOrderBy{field: "age", groupBy: "isnew"})
+
+	_, err = Parse(`age > 1 group by isnew`)
+	assert.ErrorIs(t, err, ErrStatementClause{"GROUP BY"})
+	_, err = ParseStatement(`select age, count(*) where age > 1 group by isnew`)
+	assert.ErrorIs(t, err, ErrInvalidAggregation{"age"})
+	_, err = ParseStatement(`select age, count(*) where age > 1`)
+	assert.ErrorIs(t, err, ErrInvalidAggregation{"age"})
+	_, err = ParseStatement(`age > 1 group isnew`)
+	assert.ErrorIs(t, err, ErrUnexpectedToken{token: token{Op: OpIdent, Start: 14, End: 19}, expected: OpIdent})
+	_, err = il.QueryStr(`age > 1 group by unknown`)
+	assert.ErrorIs(t, err, ErrInvalidIndexdName{"unknown"})
+}
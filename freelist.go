@@ -1,6 +1,9 @@
-package main
+package fali
 
-import "iter"
+import (
+	"iter"
+	"slices"
+)
 
 // Slot holds the data or the pointer to the next free space
 type slot[T any] struct {
@@ -9,29 +12,94 @@ type slot[T any] struct {
 	occupied bool // Simple flag to know if this is data or a free link
 }
 
+// freeListPageBits defines the page size of a FreeList: 1024 slots
+const freeListPageBits = 10
+
+const freeListPageSize = 1 << freeListPageBits
+
 // FreeList don't delete an Item, instead mark it as not occupied.
 // With one of the Compact Methods, you can remove thes palceholders and make the list smaller.
+// The slots are saved in pages, a snapshot shares the pages, which are copied by the next change (copy-on-write per page).
 type FreeList[T any] struct {
-	slots    []slot[T]
+	pages    [][]slot[T]
+	length   int // the number of slots
 	freeHead int // Index of the first free slot (-1 if none)
 	count    int
+	// the pages are shared with a snapshot, copy before writing
+	cow cow[int]
 }
 
 func NewFreeList[T any]() FreeList[T] {
 	return FreeList[T]{
-		slots:    make([]slot[T], 0),
 		freeHead: -1, // -1 means "No free slots, append new ones"
 	}
 }
 
+// snapshot returns a FreeList, which shares the pages with this FreeList.
+// This FreeList copies the changed pages by the next change (copy-on-write).
+func (l *FreeList[T]) snapshot() FreeList[T] {
+	l.cow.snapshot()
+	return FreeList[T]{pages: l.pages, length: l.length, freeHead: l.freeHead, count: l.count}
+}
+
+// ownRoot copies the page list, if it is shared with a snapshot
+//
+//go:inline
+func (l *FreeList[T]) ownRoot() {
+	if l.cow.ownRoot() {
+		l.pages = slices.Clone(l.pages)
+	}
+}
+
+// slot returns the slot on the given index for changing, copy the page if it is shared with a snapshot
+func (l *FreeList[T]) slot(index int) *slot[T] {
+	l.ownRoot()
+
+	p := index >> freeListPageBits
+	if l.cow.own(p) {
+		l.pages[p] = append(make([]slot[T], 0, freeListPageSize), l.pages[p]...)
+	}
+	return &l.pages[p][index&(freeListPageSize-1)]
+}
+
+// get returns the slot on the given index for reading
+//
+//go:inline
+func (l *FreeList[T]) get(index int) slot[T] {
+	return l.pages[index>>freeListPageBits][index&(freeListPageSize-1)]
+}
+
+// push appends the slot to the end of the list
+func (l *FreeList[T]) push(s slot[T]) {
+	if l.length&(freeListPageSize-1) == 0 {
+		l.ownRoot()
+		l.pages = append(l.pages, make([]slot[T], 0, freeListPageSize))
+	} else {
+		// copy the last page, if it is shared
+		l.slot(l.length - 1)
+	}
+
+	last := len(l.pages) - 1
+	l.pages[last] = append(l.pages[last], s)
+	l.length++
+}
+
+// nextIndex returns the index, which is used by the next Insert
+func (l *FreeList[T]) nextIndex() int {
+	if l.freeHead == -1 {
+		return l.length
+	}
+	return l.freeHead
+}
+
 // Insert an Item to the end of the List or use a free slot, to add this item
 func (l *FreeList[T]) Insert(item T) int {
 	l.count++
 
 	// no free slots in the list, append to the end
 	if l.freeHead == -1 {
-		idx := len(l.slots)
-		l.slots = append(l.slots, slot[T]{
+		idx := l.length
+		l.push(slot[T]{
 			value:    item,
 			occupied: true,
 			nextFree: -1,
@@ -40,8 +108,9 @@ func (l *FreeList[T]) Insert(item T) int {
 	}
 
 	idx := l.freeHead
-	l.freeHead = l.slots[idx].nextFree
-	l.slots[idx] = slot[T]{
+	s := l.slot(idx)
+	l.freeHead = s.nextFree
+	*s = slot[T]{
 		value:    item,
 		occupied: true,
 		nextFree: -1,
@@ -53,17 +122,18 @@ func (l *FreeList[T]) Insert(item T) int {
 // Remove mark the Item on the given index as deleted.
 // index must be >=0 and < len(slots), otherwise return Remove false and do nothing.
 func (l *FreeList[T]) Remove(index int) bool {
-	if index < 0 || index >= len(l.slots) || !l.slots[index].occupied {
+	if index < 0 || index >= l.length || !l.get(index).occupied {
 		return false
 	}
+	s := l.slot(index)
 
 	// clear the value to prevent memory leaks
 	var null T
-	l.slots[index].value = null
-	l.slots[index].occupied = false
+	s.value = null
+	s.occupied = false
 
 	// make this slot point to the current head
-	l.slots[index].nextFree = l.freeHead
+	s.nextFree = l.freeHead
 	// make this slot the new head
 	l.freeHead = index
 	l.count--
@@ -74,12 +144,12 @@ func (l *FreeList[T]) Remove(index int) bool {
 // Get the Item on the given index, or the zero value and false, if it not exist.
 // index must be >=0 and < len(slots), otherwise return Get zero value and false and do nothing.
 func (l *FreeList[T]) Get(index int) (T, bool) {
-	if index < 0 || index >= len(l.slots) {
+	if index < 0 || index >= l.length {
 		var null T
 		return null, false
 	}
 
-	slot := l.slots[index]
+	slot := l.get(index)
 	if !slot.occupied {
 		var null T
 		return null, false
@@ -93,7 +163,7 @@ func (l *FreeList[T]) Get(index int) (T, bool) {
 // index must be >=0 and < len(slots), otherwise return Set zero value and false and do nothing.
 func (l *FreeList[T]) Set(index int, newItem T) (T, bool) {
 	if oldItem, found := l.Get(index); found {
-		l.slots[index].value = newItem
+		l.slot(index).value = newItem
 		return oldItem, true
 	}
 
@@ -101,16 +171,51 @@ func (l *FreeList[T]) Set(index int, newItem T) (T, bool) {
 	return null, false
 }
 
+// undoInsert reverts the last Insert on the given index.
+// appended means, the Item was added to the end of the list and not in a free slot.
+func (l *FreeList[T]) undoInsert(index int, appended bool) {
+	if !appended {
+		l.Remove(index)
+		return
+	}
+
+	// clear the value to prevent memory leaks
+	var null T
+	l.slot(index).value = null
+
+	last := len(l.pages) - 1
+	l.pages[last] = l.pages[last][:len(l.pages[last])-1]
+	if len(l.pages[last]) == 0 {
+		l.pages = l.pages[:last]
+	}
+	l.length--
+	l.count--
+}
+
+// undoRemove reverts the last Remove on the given index, the Item is set again.
+func (l *FreeList[T]) undoRemove(index int, item T) {
+	s := l.slot(index)
+	l.freeHead = s.nextFree
+	*s = slot[T]{
+		value:    item,
+		occupied: true,
+		nextFree: -1,
+	}
+	l.count++
+}
+
 // Count returns the count of the occupied slots
 func (l *FreeList[T]) Count() int { return l.count }
 
 // Iter create an Iterator, to iterate over all saved Indices and Items
 func (l *FreeList[T]) Iter() iter.Seq2[int, T] {
 	return func(yield func(int, T) bool) {
-		for i, item := range l.slots {
-			if item.occupied {
-				if !yield(i, item.value) {
-					return
+		for p, page := range l.pages {
+			for i, item := range page {
+				if item.occupied {
+					if !yield(p<<freeListPageBits+i, item.value) {
+						return
+					}
 				}
 			}
 		}
@@ -119,41 +224,21 @@ func (l *FreeList[T]) Iter() iter.Seq2[int, T] {
 
 // CompactUnstable removes not used slots. Unstable means, the Indices breaks.
 func (l *FreeList[T]) CompactUnstable() {
-	keep := 0
-	slots := l.slots
-
-	for _, s := range slots {
-		if s.occupied {
-			slots[keep] = s
-			keep++
-		}
-	}
-
-	l.slots = slots[:keep]
-	l.freeHead = -1
+	l.CompactLinear(func(int, int) {})
 }
 
 // CompactLinear removes not used slote.
 // If an Index has changed, yout get this Info with the Callback: onMove
 func (l *FreeList[T]) CompactLinear(onMove func(oldIndex, newIndex int)) {
-	var null T
-	keep := 0
-	slots := l.slots
-
-	for i, s := range slots {
-		if s.occupied {
-			// If the read and write pointers are different, we need to move the data
-			if i != keep {
-				slots[keep] = s
-				onMove(i, keep)
-
-				// clear the old slot to prevent memory leaks
-				slots[i] = slot[T]{value: null, occupied: false, nextFree: -1}
-			}
-			keep++
+	// the compacted slots are saved in new pages, which are not shared with a snapshot
+	compacted := FreeList[T]{freeHead: -1, count: l.count}
+	for i, item := range l.Iter() {
+		// If the read and write pointers are different, the Index has changed
+		if i != compacted.length {
+			onMove(i, compacted.length)
 		}
+		compacted.push(slot[T]{value: item, occupied: true, nextFree: -1})
 	}
 
-	l.slots = slots[:keep]
-	l.freeHead = -1
+	*l = compacted
 }
@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"testing"
@@ -72,7 +72,7 @@ func TestFreeList_CompactUnstable(t *testing.T) {
 	assert.Equal(t, 3, l.Count())
 
 	l.CompactUnstable()
-	assert.Equal(t, 3, len(l.slots))
+	assert.Equal(t, 3, l.length)
 	assert.Equal(t, 3, l.Count())
 
 	val, found := l.Get(0)
@@ -109,7 +109,7 @@ func TestFreeList_CompactLinear(t *testing.T) {
 	})
 	// the index 0 is not moved
 	assert.Equal(t, []int{3, 5}, removed)
-	assert.Equal(t, 3, len(l.slots))
+	assert.Equal(t, 3, l.length)
 
 	val, found := l.Get(0)
 	assert.True(t, found)
@@ -156,3 +156,66 @@ func TestFreeList_Iter(t *testing.T) {
 		}
 	}
 }
+
+func TestFreeList_Undo(t *testing.T) {
+	l := NewFreeList[string]()
+	l.Insert("a")
+	l.Insert("b")
+	l.Insert("c")
+	l.Remove(1)
+
+	before := l.snapshot()
+
+	// reuse the free slot and append
+	assert.Equal(t, 1, l.Insert("x"))
+	assert.Equal(t, 3, l.Insert("y"))
+	assert.True(t, l.Remove(0))
+
+	l.undoRemove(0, "a")
+	l.undoInsert(3, true)
+	l.undoInsert(1, false)
+
+	assertSameSlots(t, before, l)
+}
+
+// assertSameSlots compares the slots, the free slots and the count of the FreeLists
+func assertSameSlots[T any](t *testing.T, expected, actual FreeList[T]) {
+	t.Helper()
+	assert.Equal(t, expected.pages, actual.pages)
+	assert.Equal(t, expected.length, actual.length)
+	assert.Equal(t, expected.freeHead, actual.freeHead)
+	assert.Equal(t, expected.count, actual.count)
+}
+
+func TestFreeList_Snapshot(t *testing.T) {
+	l := NewFreeList[int]()
+	for i := range 3 * freeListPageSize {
+		l.Insert(i)
+	}
+
+	snap := l.snapshot()
+	l.Set(1, 100)
+	assert.True(t, l.Remove(2))
+	assert.Equal(t, 2, l.Insert(-1))
+	assert.Equal(t, 3*freeListPageSize, l.Insert(-2))
+
+	// only the changed first page is copied
+	assert.NotSame(t, &snap.pages[0][0], &l.pages[0][0])
+	assert.Same(t, &snap.pages[1][0], &l.pages[1][0])
+	assert.Same(t, &snap.pages[2][0], &l.pages[2][0])
+	assert.Len(t, l.pages, 4)
+
+	// the snapshot is not changed
+	assert.Len(t, snap.pages, 3)
+	assert.Equal(t, 3*freeListPageSize, snap.Count())
+	v, _ := snap.Get(1)
+	assert.Equal(t, 1, v)
+	v, _ = snap.Get(2)
+	assert.Equal(t, 2, v)
+
+	v, _ = l.Get(1)
+	assert.Equal(t, 100, v)
+	v, _ = l.Get(2)
+	assert.Equal(t, -1, v)
+	assert.Equal(t, 3*freeListPageSize+1, l.Count())
+}
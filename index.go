@@ -1,10 +1,12 @@
-package main
+package fali
 
 import (
 	"cmp"
 	"fmt"
+	"maps"
 	"reflect"
 	"slices"
+	"strings"
 	"unsafe"
 )
 
@@ -12,16 +14,22 @@ const IDIndexFieldName = "id"
 
 // fieldIndexMap maps a given field name to an Index
 type indexMap[OBJ any, ID comparable] struct {
-	idIndex idIndex[OBJ, ID]
-	index   map[string]Index32[OBJ]
-	allIDs  *BitSet[uint32]
+	idIndex   idIndex[OBJ, ID]
+	index     map[string]Index32[OBJ]
+	composite composites
+	allIDs    *BitSet[uint32]
+	// the allIDs are shared with a snapshot, copy before writing (see: ids)
+	sharedIDs bool
+	subs      *subscriptions[OBJ]
 }
 
 func newIndexMap[OBJ any, ID comparable](idIndex idIndex[OBJ, ID]) indexMap[OBJ, ID] {
 	return indexMap[OBJ, ID]{
-		idIndex: idIndex,
-		index:   make(map[string]Index32[OBJ]),
-		allIDs:  NewBitSet[uint32](),
+		idIndex:   idIndex,
+		index:     make(map[string]Index32[OBJ]),
+		composite: newComposites(),
+		allIDs:    NewBitSet[uint32](),
+		subs:      newSubscriptions[OBJ](),
 	}
 }
 
@@ -38,31 +46,80 @@ func (i indexMap[OBJ, ID]) FilterByName(fieldName string) (Filter32, error) {
 		return idx, nil
 	}
 
+	if name, found := i.composite.names[fieldName]; found {
+		return i.index[name], nil
+	}
+	if fieldName == compositesName {
+		return i.composite.fields, nil
+	}
+
 	return nil, ErrInvalidIndexdName{fieldName}
 }
 
-func (i indexMap[OBJ, ID]) Set(obj *OBJ, idx int) {
+// ids returns the allIDs for changing, copy the allIDs if they are shared with a snapshot
+func (i *indexMap[OBJ, ID]) ids() *BitSet[uint32] {
+	if i.sharedIDs {
+		i.allIDs = i.allIDs.Copy()
+		i.sharedIDs = false
+	}
+	return i.allIDs
+}
+
+func (i *indexMap[OBJ, ID]) Set(obj *OBJ, idx int) {
 	if i.idIndex != nil {
 		i.idIndex.Set(obj, idx)
 	}
 
 	uidx := uint32(idx)
-	i.allIDs.Set(uidx)
+	i.ids().Set(uidx)
 	for _, fieldIndex := range i.index {
 		fieldIndex.Set(obj, uidx)
 	}
+
+	i.notifyInsert(obj, uidx)
 }
 
-func (i indexMap[OBJ, ID]) UnSet(obj *OBJ, idx int) {
+// Update re-indexes the changed Item, the ID is not changed
+func (i indexMap[OBJ, ID]) Update(oldObj, obj *OBJ, idx int) {
+	uidx := uint32(idx)
+	for _, fieldIndex := range i.index {
+		// TODO: do it better: check is it neccesary/dirty
+		fieldIndex.UnSet(oldObj, uidx)
+		fieldIndex.Set(obj, uidx)
+	}
+
+	i.notifyUpdate(oldObj, obj, uidx)
+}
+
+func (i *indexMap[OBJ, ID]) UnSet(obj *OBJ, idx int) {
 	if i.idIndex != nil {
 		i.idIndex.UnSet(obj, idx)
 	}
 
 	uidx := uint32(idx)
-	i.allIDs.UnSet(uidx)
+	i.ids().UnSet(uidx)
 	for _, fieldIndex := range i.index {
 		fieldIndex.UnSet(obj, uidx)
 	}
+
+	i.notifyRemove(obj, uidx)
+}
+
+// check returns an error, if an Index rejects the Item with the List-Index (see: Constraint)
+func (i indexMap[OBJ, ID]) check(obj *OBJ, idx int) error {
+	for fieldName, fieldIndex := range i.index {
+		if c, ok := fieldIndex.(Constraint[OBJ, uint32]); ok {
+			if err := c.Check(obj, uint32(idx)); err != nil {
+				if uv, ok := err.(ErrUniqueViolation); ok {
+					uv.field = fieldName
+					return uv
+				}
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
 func (i indexMap[OBJ, ID]) getIndexByID(id ID) (int, error) {
@@ -87,6 +144,7 @@ type idIndex[OBJ any, ID comparable] interface {
 	UnSet(*OBJ, int)
 	GetIndex(ID) (int, error)
 	GetID(*OBJ) (ID, int, error)
+	snapshot() idIndex[OBJ, ID]
 	Filter32
 }
 
@@ -95,6 +153,7 @@ const IDMapIndexName = "IDMapIndex"
 type idMapIndex[OBJ any, ID comparable] struct {
 	data       map[ID]int
 	fieldGetFn FromField[OBJ, ID]
+	cow        cow[ID]
 }
 
 func newIDMapIndex[OBJ any, ID comparable](fieldGetFn FromField[OBJ, ID]) idIndex[OBJ, ID] {
@@ -106,14 +165,32 @@ func newIDMapIndex[OBJ any, ID comparable](fieldGetFn FromField[OBJ, ID]) idInde
 
 func (mi *idMapIndex[OBJ, ID]) Set(obj *OBJ, lidx int) {
 	id := mi.fieldGetFn(obj)
+	mi.own()
 	mi.data[id] = lidx
 }
 
+// UnSet removes the ID, if the ID is mapped to the given List-Index
 func (mi *idMapIndex[OBJ, ID]) UnSet(obj *OBJ, lidx int) {
 	id := mi.fieldGetFn(obj)
+	if idx, found := mi.data[id]; !found || idx != lidx {
+		return
+	}
+	mi.own()
 	delete(mi.data, id)
 }
 
+func (mi *idMapIndex[OBJ, ID]) snapshot() idIndex[OBJ, ID] {
+	mi.cow.shared = true
+	return &idMapIndex[OBJ, ID]{data: mi.data, fieldGetFn: mi.fieldGetFn}
+}
+
+//go:inline
+func (mi *idMapIndex[OBJ, ID]) own() {
+	if mi.cow.ownRoot() {
+		mi.data = maps.Clone(mi.data)
+	}
+}
+
 func (mi *idMapIndex[OBJ, ID]) GetIndex(id ID) (int, error) {
 	if lidx, found := mi.data[id]; found {
 		return lidx, nil
@@ -150,6 +227,26 @@ func (mi *idMapIndex[OBJ, ID]) Match(op Op, value any) (*BitSet[uint32], error)
 
 }
 
+// ValueType returns the type of the IDs
+func (mi *idMapIndex[OBJ, ID]) ValueType() reflect.Type { return reflect.TypeFor[ID]() }
+func (mi *idMapIndex[OBJ, ID]) itemValue(obj *OBJ) any  { return mi.fieldGetFn(obj) }
+
+// Estimate returns 1, if the ID exists, otherwise 0
+func (mi *idMapIndex[OBJ, ID]) Estimate(op Op, values ...any) (int, error) {
+	if op != OpEq || len(values) != 1 {
+		return 0, ErrInvalidOperation{IDMapIndexName, op}
+	}
+	id, ok := values[0].(ID)
+	if !ok {
+		return 0, ErrInvalidIndexValue[ID]{values[0]}
+	}
+
+	if _, found := mi.data[id]; found {
+		return 1, nil
+	}
+	return 0, nil
+}
+
 // MatchMany is not supported by idMapIndex, so that always returns an error
 func (mi *idMapIndex[OBJ, ID]) MatchMany(op Op, values ...any) (*BitSet[uint32], error) {
 	return nil, ErrInvalidOperation{IDMapIndexName, op}
@@ -170,6 +267,12 @@ type Index[OBJ any, LI Value] interface {
 	Filter[LI]
 }
 
+// Constraint is implemented by Indices, which can reject an Item (e.g. UniqueIndex).
+// Check is called before the Item is saved with the List-Index, returns an error, the Item is rejected.
+type Constraint[OBJ any, LI Value] interface {
+	Check(obj *OBJ, lidx LI) error
+}
+
 // Filter32 the IndexList only supports uint32 List-Indices
 type Filter32 = Filter[uint32]
 
@@ -179,6 +282,12 @@ type Filter[LI Value] interface {
 	MatchMany(op Op, values ...any) (*BitSet[LI], error)
 }
 
+// RangeFilter is implemented by Filters, which can find a range with included or excluded bounds.
+// Filters without RangeFilter are queried with: OpBetween (the bounds included) and the excluded bounds removed.
+type RangeFilter[LI Value] interface {
+	MatchRange(from, to any, fromIncl, toIncl bool) (*BitSet[LI], error)
+}
+
 // FromField is a function, which returns a value from an given object.
 // example:
 // Person{name string}
@@ -243,38 +352,41 @@ const MapIndexName = "MapIndex"
 
 // MapIndex is a mapping of any value to the Index in the List.
 // This index only supported Queries with the Equal Ralation!
+// The List-Indices are saved in a Posting, so values with a few (big) List-Indices need only a few bytes.
 type MapIndex[OBJ any, V any, LI Value] struct {
-	data       map[any]*BitSet[LI]
+	data       map[any]*Posting[LI]
 	fieldGetFn FromField[OBJ, V]
+	cow        cow[any]
 }
 
 func NewMapIndex[OBJ any, V any](fromField FromField[OBJ, V]) Index32[OBJ] {
 	return &MapIndex[OBJ, V, uint32]{
-		data:       make(map[any]*BitSet[uint32]),
+		data:       make(map[any]*Posting[uint32]),
 		fieldGetFn: fromField,
 	}
 }
 
 func (mi *MapIndex[OBJ, V, LI]) Set(obj *OBJ, lidx LI) {
 	value := mi.fieldGetFn(obj)
-	bs, found := mi.data[value]
+	p, found := mi.posting(value)
 	if !found {
-		bs = NewBitSet[LI]()
+		p = NewPosting[LI]()
 	}
-	bs.Set(lidx)
-	mi.data[value] = bs
+	p.Set(lidx)
+	mi.data[value] = p
 }
 
 func (mi *MapIndex[OBJ, V, LI]) UnSet(obj *OBJ, lidx LI) {
 	value := mi.fieldGetFn(obj)
-	if bs, found := mi.data[value]; found {
-		bs.UnSet(lidx)
-		if bs.Count() == 0 {
+	if p, found := mi.posting(value); found {
+		p.UnSet(lidx)
+		if p.IsEmpty() {
 			delete(mi.data, value)
 		}
 	}
 }
 
+// Match returns the List-Indices as BitSet, a Query uses the saved Posting without a BitSet (see: matchPosting)
 func (mi *MapIndex[OBJ, V, LI]) Match(op Op, value any) (*BitSet[LI], error) {
 	if _, ok := value.(V); !ok {
 		return nil, ErrInvalidIndexValue[V]{value}
@@ -284,12 +396,24 @@ func (mi *MapIndex[OBJ, V, LI]) Match(op Op, value any) (*BitSet[LI], error) {
 		return nil, ErrInvalidOperation{MapIndexName, op}
 	}
 
-	bs, found := mi.data[value]
+	p, found := mi.data[value]
 	if !found {
 		return NewBitSet[LI](), nil
 	}
 
-	return bs, nil
+	return p.asBitSet(), nil
+}
+
+// matchPosting returns the saved Posting of the value, without creating a BitSet
+func (mi *MapIndex[OBJ, V, LI]) matchPosting(value any) (PostingList[LI], error) {
+	if _, ok := value.(V); !ok {
+		return nil, ErrInvalidIndexValue[V]{value}
+	}
+
+	if p, found := mi.data[value]; found {
+		return p, nil
+	}
+	return NewPosting[LI](), nil
 }
 
 // MatchMany is not supported by MapIndex, so that always returns an error
@@ -297,41 +421,88 @@ func (mi *MapIndex[OBJ, V, LI]) MatchMany(op Op, values ...any) (*BitSet[LI], er
 	return nil, ErrInvalidOperation{MapIndexName, op}
 }
 
+// ValueType returns the type of the indexed values
+func (mi *MapIndex[OBJ, V, LI]) ValueType() reflect.Type { return reflect.TypeFor[V]() }
+func (mi *MapIndex[OBJ, V, LI]) itemValue(obj *OBJ) any  { return mi.fieldGetFn(obj) }
+
+// buckets calls visit for every value with the List-Indices
+func (mi *MapIndex[OBJ, V, LI]) buckets(visit func(any, PostingList[LI]) bool) {
+	for value, p := range mi.data {
+		if !visit(value, p) {
+			return
+		}
+	}
+}
+
+// Estimate returns the number of Items with the given value
+func (mi *MapIndex[OBJ, V, LI]) Estimate(op Op, values ...any) (int, error) {
+	if op != OpEq || len(values) != 1 {
+		return 0, ErrInvalidOperation{MapIndexName, op}
+	}
+
+	if _, ok := values[0].(V); !ok {
+		return 0, ErrInvalidIndexValue[V]{values[0]}
+	}
+	if p, found := mi.data[values[0]]; found {
+		return p.Count(), nil
+	}
+	return 0, nil
+}
+
+// Snapshot returns an immutable MapIndex, which shares the data until this MapIndex is changed.
+func (mi *MapIndex[OBJ, V, LI]) Snapshot() Filter[LI] {
+	mi.cow.snapshot()
+	return &MapIndex[OBJ, V, LI]{data: mi.data, fieldGetFn: mi.fieldGetFn}
+}
+
+// posting returns the Posting for changing, copy the Posting if it is shared with a snapshot
+func (mi *MapIndex[OBJ, V, LI]) posting(value any) (*Posting[LI], bool) {
+	if mi.cow.ownRoot() {
+		mi.data = maps.Clone(mi.data)
+	}
+
+	p, found := mi.data[value]
+	if found && mi.cow.own(value) {
+		p = p.Copy()
+		mi.data[value] = p
+	}
+	return p, found
+}
+
 const SortedIndexName = "SortedIndex"
 
 // SortedIndex is well suited for Queries with: Range, Min, Max, Greater and Less
 type SortedIndex[OBJ any, V cmp.Ordered, LI Value] struct {
-	skipList   SkipList[V, *BitSet[LI]]
+	sorted     sortedPages[V, *Posting[LI]]
 	fieldGetFn FromField[OBJ, V]
+	cow        cow[V]
 }
 
 func NewSortedIndex[OBJ any, V cmp.Ordered](fieldGetFn FromField[OBJ, V]) Index32[OBJ] {
-	return &SortedIndex[OBJ, V, uint32]{
-		skipList:   NewSkipList[V, *BitSet[uint32]](),
-		fieldGetFn: fieldGetFn,
-	}
+	return &SortedIndex[OBJ, V, uint32]{fieldGetFn: fieldGetFn}
 }
 
 func (si *SortedIndex[OBJ, V, LI]) Set(obj *OBJ, lidx LI) {
 	value := si.fieldGetFn(obj)
-	bs, found := si.skipList.Get(value)
+	p, found := si.posting(value)
 	if !found {
-		bs = NewBitSet[LI]()
+		p = NewPosting[LI]()
 	}
-	bs.Set(lidx)
-	si.skipList.Put(value, bs)
+	p.Set(lidx)
+	si.sorted.Put(value, p)
 }
 
 func (si *SortedIndex[OBJ, V, LI]) UnSet(obj *OBJ, lidx LI) {
 	value := si.fieldGetFn(obj)
-	if bs, found := si.skipList.Get(value); found {
-		bs.UnSet(lidx)
-		if bs.Count() == 0 {
-			si.skipList.Delete(value)
+	if p, found := si.posting(value); found {
+		p.UnSet(lidx)
+		if p.IsEmpty() {
+			si.sorted.Delete(value)
 		}
 	}
 }
 
+// Match returns the List-Indices as BitSet, a Query for OpEq uses the saved Posting without a BitSet (see: matchPosting)
 func (si *SortedIndex[OBJ, V, LI]) Match(op Op, value any) (*BitSet[LI], error) {
 	if _, ok := value.(V); !ok {
 		return nil, ErrInvalidIndexValue[V]{value}
@@ -339,35 +510,35 @@ func (si *SortedIndex[OBJ, V, LI]) Match(op Op, value any) (*BitSet[LI], error)
 
 	switch op {
 	case OpEq:
-		if bs, found := si.skipList.Get(value.(V)); found {
-			return bs, nil
+		if p, found := si.sorted.Get(value.(V)); found {
+			return p.asBitSet(), nil
 		}
 		return NewBitSet[LI](), nil
 	case OpLt:
 		result := NewBitSet[LI]()
-		si.skipList.Less(value.(V), func(v V, bs *BitSet[LI]) bool {
-			result.Or(bs)
+		si.sorted.Less(value.(V), func(_ V, p *Posting[LI]) bool {
+			p.orInto(result)
 			return true
 		})
 		return result, nil
 	case OpLe:
 		result := NewBitSet[LI]()
-		si.skipList.LessEqual(value.(V), func(_ V, bs *BitSet[LI]) bool {
-			result.Or(bs)
+		si.sorted.LessEqual(value.(V), func(_ V, p *Posting[LI]) bool {
+			p.orInto(result)
 			return true
 		})
 		return result, nil
 	case OpGt:
 		result := NewBitSet[LI]()
-		si.skipList.Greater(value.(V), func(v V, bs *BitSet[LI]) bool {
-			result.Or(bs)
+		si.sorted.Greater(value.(V), func(_ V, p *Posting[LI]) bool {
+			p.orInto(result)
 			return true
 		})
 		return result, nil
 	case OpGe:
 		result := NewBitSet[LI]()
-		si.skipList.GreaterEqual(value.(V), func(_ V, bs *BitSet[LI]) bool {
-			result.Or(bs)
+		si.sorted.GreaterEqual(value.(V), func(_ V, p *Posting[LI]) bool {
+			p.orInto(result)
 			return true
 		})
 		return result, nil
@@ -377,8 +548,8 @@ func (si *SortedIndex[OBJ, V, LI]) Match(op Op, value any) (*BitSet[LI], error)
 		}
 
 		result := NewBitSet[LI]()
-		si.skipList.StringStartsWith(value.(V), func(_ V, bs *BitSet[LI]) bool {
-			result.Or(bs)
+		si.sorted.StringStartsWith(value.(V), func(_ V, p *Posting[LI]) bool {
+			p.orInto(result)
 			return true
 		})
 		return result, nil
@@ -387,27 +558,26 @@ func (si *SortedIndex[OBJ, V, LI]) Match(op Op, value any) (*BitSet[LI], error)
 	}
 }
 
+// matchPosting returns the saved Posting of the value, without creating a BitSet
+func (si *SortedIndex[OBJ, V, LI]) matchPosting(value any) (PostingList[LI], error) {
+	v, ok := value.(V)
+	if !ok {
+		return nil, ErrInvalidIndexValue[V]{value}
+	}
+
+	if p, found := si.sorted.Get(v); found {
+		return p, nil
+	}
+	return NewPosting[LI](), nil
+}
+
 func (si *SortedIndex[OBJ, V, LI]) MatchMany(op Op, values ...any) (*BitSet[LI], error) {
 	switch op {
 	case OpBetween:
 		if len(values) != 2 {
 			return nil, ErrInvalidArgsLen{defined: "2", got: len(values)}
 		}
-		min, ok := values[0].(V)
-		if !ok {
-			return nil, ErrInvalidIndexValue[V]{values[0]}
-		}
-		max, ok := values[1].(V)
-		if !ok {
-			return nil, ErrInvalidIndexValue[V]{values[1]}
-		}
-
-		result := NewBitSet[LI]()
-		si.skipList.Range(min, max, func(_ V, bs *BitSet[LI]) bool {
-			result.Or(bs)
-			return true
-		})
-		return result, nil
+		return si.MatchRange(values[0], values[1], true, true)
 	case OpIn:
 		if len(values) == 0 {
 			return NewBitSet[LI](), nil
@@ -423,8 +593,8 @@ func (si *SortedIndex[OBJ, V, LI]) MatchMany(op Op, values ...any) (*BitSet[LI],
 		slices.Sort(keys)
 
 		result := NewBitSet[LI]()
-		si.skipList.FindSortedKeys(func(_ V, bs *BitSet[LI]) bool {
-			result.Or(bs)
+		si.sorted.FindSortedKeys(func(_ V, p *Posting[LI]) bool {
+			p.orInto(result)
 			return true
 		}, keys...)
 		return result, nil
@@ -433,3 +603,131 @@ func (si *SortedIndex[OBJ, V, LI]) MatchMany(op Op, values ...any) (*BitSet[LI],
 		return nil, ErrInvalidOperation{SortedIndexName, op}
 	}
 }
+
+// MatchRange returns all List-Indices with a value between from and to,
+// fromIncl and toIncl defines, are the bounds included or excluded.
+func (si *SortedIndex[OBJ, V, LI]) MatchRange(from, to any, fromIncl, toIncl bool) (*BitSet[LI], error) {
+	min, ok := from.(V)
+	if !ok {
+		return nil, ErrInvalidIndexValue[V]{from}
+	}
+	max, ok := to.(V)
+	if !ok {
+		return nil, ErrInvalidIndexValue[V]{to}
+	}
+
+	result := NewBitSet[LI]()
+	si.sorted.RangeBounds(min, max, fromIncl, toIncl, func(_ V, p *Posting[LI]) bool {
+		p.orInto(result)
+		return true
+	})
+	return result, nil
+}
+
+// ValueType returns the type of the indexed values
+func (si *SortedIndex[OBJ, V, LI]) ValueType() reflect.Type { return reflect.TypeFor[V]() }
+func (si *SortedIndex[OBJ, V, LI]) itemValue(obj *OBJ) any  { return si.fieldGetFn(obj) }
+
+// buckets calls visit for every value with the List-Indices
+func (si *SortedIndex[OBJ, V, LI]) buckets(visit func(any, PostingList[LI]) bool) {
+	si.sorted.Traverse(func(value V, p *Posting[LI]) bool { return visit(value, p) })
+}
+
+// walkSorted calls visit with the List-Indices of every value, in ascending or descending order of the values.
+// If from is a value, the walk starts with this value.
+func (si *SortedIndex[OBJ, V, LI]) walkSorted(desc bool, from any, visit func(PostingList[LI]) bool) {
+	fn := func(_ V, p *Posting[LI]) bool { return visit(p) }
+	key, seek := from.(V)
+
+	switch {
+	case desc && seek:
+		si.sorted.ReverseLessEqual(key, fn)
+	case desc:
+		si.sorted.ReverseTraverse(fn)
+	case seek:
+		si.sorted.GreaterEqual(key, fn)
+	default:
+		si.sorted.Traverse(fn)
+	}
+}
+
+// Estimate returns the estimated number of Items, for Eq the exact number,
+// for ranges: the number of values in the range * the average Items per value.
+func (si *SortedIndex[OBJ, V, LI]) Estimate(op Op, values ...any) (int, error) {
+	keys := make([]V, len(values))
+	var ok bool
+	for i, val := range values {
+		if keys[i], ok = val.(V); !ok {
+			return 0, ErrInvalidIndexValue[V]{val}
+		}
+	}
+
+	// before and behind define the range of the keys, walk visits the keys for sampling the Postings
+	var before, behind func(V) bool
+	var walk func(VisitFn[V, *Posting[LI]])
+	never := func(V) bool { return false }
+
+	switch {
+	case op == OpIn:
+		slices.Sort(keys)
+		count := 0
+		si.sorted.FindSortedKeys(func(_ V, p *Posting[LI]) bool {
+			count += p.Count()
+			return true
+		}, keys...)
+		return count, nil
+	case op == OpBetween && len(keys) == 2:
+		before = func(k V) bool { return k < keys[0] }
+		behind = func(k V) bool { return k > keys[1] }
+		walk = func(visit VisitFn[V, *Posting[LI]]) { si.sorted.Range(keys[0], keys[1], visit) }
+	case op == OpBetween:
+		return 0, ErrInvalidArgsLen{defined: "2", got: len(keys)}
+	case len(keys) != 1:
+		return 0, ErrInvalidArgsLen{defined: "1", got: len(keys)}
+	case op == OpEq:
+		if p, found := si.sorted.Get(keys[0]); found {
+			return p.Count(), nil
+		}
+		return 0, nil
+	case op == OpLt:
+		before, behind = never, func(k V) bool { return k >= keys[0] }
+		walk = func(visit VisitFn[V, *Posting[LI]]) { si.sorted.Less(keys[0], visit) }
+	case op == OpLe:
+		before, behind = never, func(k V) bool { return k > keys[0] }
+		walk = func(visit VisitFn[V, *Posting[LI]]) { si.sorted.LessEqual(keys[0], visit) }
+	case op == OpGt:
+		before, behind = func(k V) bool { return k <= keys[0] }, never
+		walk = func(visit VisitFn[V, *Posting[LI]]) { si.sorted.Greater(keys[0], visit) }
+	case op == OpGe:
+		before, behind = func(k V) bool { return k < keys[0] }, never
+		walk = func(visit VisitFn[V, *Posting[LI]]) { si.sorted.GreaterEqual(keys[0], visit) }
+	case op == OpStartsWith:
+		prefix, ok := values[0].(string)
+		if !ok {
+			return 0, ErrInvalidIndexValue[string]{values[0]}
+		}
+		before = func(k V) bool { return k < keys[0] }
+		behind = func(k V) bool { return !strings.HasPrefix(any(k).(string), prefix) }
+		walk = func(visit VisitFn[V, *Posting[LI]]) { si.sorted.StringStartsWith(keys[0], visit) }
+	default:
+		return 0, ErrInvalidOperation{SortedIndexName, op}
+	}
+
+	return estimateKeys(si.sorted.countKeys(before, behind), walk), nil
+}
+
+// Snapshot returns an immutable SortedIndex, which shares the data until this SortedIndex is changed.
+func (si *SortedIndex[OBJ, V, LI]) Snapshot() Filter[LI] {
+	si.cow.snapshot()
+	return &SortedIndex[OBJ, V, LI]{sorted: si.sorted.snapshot(), fieldGetFn: si.fieldGetFn}
+}
+
+// posting returns the Posting for changing, copy the Posting if it is shared with a snapshot
+func (si *SortedIndex[OBJ, V, LI]) posting(value V) (*Posting[LI], bool) {
+	p, found := si.sorted.Get(value)
+	if found && si.cow.own(value) {
+		p = p.Copy()
+		si.sorted.Put(value, p)
+	}
+	return p, found
+}
@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"testing"
@@ -117,6 +117,44 @@ func TestSortedIndex_Between(t *testing.T) {
 	assert.ErrorIs(t, ErrInvalidIndexValue[string]{1}, err)
 }
 
+func TestSortedIndex_MatchRange(t *testing.T) {
+	si := NewSortedIndex(FromValue[string]())
+	set(si, "a", 1)
+	set(si, "b", 2)
+	set(si, "c", 3)
+
+	rf, ok := si.(RangeFilter[uint32])
+	assert.True(t, ok)
+
+	bs, err := rf.MatchRange("a", "c", false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{2}, bs.ToSlice())
+
+	bs, err = rf.MatchRange("a", "c", true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1, 2}, bs.ToSlice())
+
+	bs, err = rf.MatchRange("a", "c", false, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{2, 3}, bs.ToSlice())
+
+	_, err = rf.MatchRange(1, "c", true, true)
+	assert.ErrorIs(t, ErrInvalidIndexValue[string]{1}, err)
+
+	il := NewIndexList[string]()
+	assert.NoError(t, il.CreateIndex("val", NewSortedIndex(FromValue[string]())))
+	_, _ = il.Insert("a")
+	_, _ = il.Insert("b")
+	_, _ = il.Insert("c")
+	qr, err := il.Query(Range("val", "a", "c", false, true))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, qr.Values())
+
+	qr, err = il.Query(Between("val", "a", "b"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, qr.Values())
+}
+
 func TestSortedIndex_In(t *testing.T) {
 	si := NewSortedIndex(FromValue[string]())
 	set(si, "a", 1)
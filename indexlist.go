@@ -1,7 +1,14 @@
-package main
+// Package fali (Fast List) provides a list, which finds Items quickly through the use of Indices.
+//
+// The Items are saved in an IndexList. For the fields of an Item you can create Indices
+// (MapIndex, SortedIndex, TrigramIndex, CompositeIndex, MultiValueIndex, UniqueIndex) and find the Items
+// with a Query, build with the query functions (Eq, Lt, And, ...) or parsed from a query string.
+package fali
 
 import (
 	"fmt"
+	"iter"
+	"math"
 	"sort"
 	"strings"
 	"sync"
@@ -11,8 +18,12 @@ import (
 type IndexList[T any, ID comparable] struct {
 	list     FreeList[T]
 	indexMap indexMap[T, ID]
+	codec    Codec[T]
+	wal      *wal
 
 	lock sync.RWMutex
+	// serializes the checkpoints, it is held until the snapshot is written and the old generations are removed
+	checkpointLock sync.Mutex
 }
 
 // NewIndexList create a new IndexList
@@ -37,6 +48,7 @@ func NewIndexListWithID[T any, ID comparable](fieldIDGetFn func(*T) ID) *IndexLi
 //   - Index: a impl of the Index interface
 //
 // Hint: empty field-name or the field-name ID are not allowed!
+// If the Index is a Constraint (e.g. UniqueIndex) and an existing Item violates the Constraint, returns an error.
 func (l *IndexList[T, ID]) CreateIndex(fieldName string, index Index32[T]) error {
 	if fieldName == "" {
 		return fmt.Errorf("empty fieldName is not allowed")
@@ -52,11 +64,24 @@ func (l *IndexList[T, ID]) CreateIndex(fieldName string, index Index32[T]) error
 		return fmt.Errorf("field-name: %s already exists", fieldName)
 	}
 
+	c, isConstraint := index.(Constraint[T, uint32])
 	for idx, item := range l.list.Iter() {
+		if isConstraint {
+			if err := c.Check(&item, uint32(idx)); err != nil {
+				if uv, ok := err.(ErrUniqueViolation); ok {
+					uv.field = fieldName
+					return uv
+				}
+				return err
+			}
+		}
 		index.Set(&item, uint32(idx))
 	}
 
 	l.indexMap.index[fieldName] = index
+	if c, ok := index.(interface{ Fields() []string }); ok {
+		l.indexMap.composite.add(c.Fields(), fieldName)
+	}
 	return nil
 }
 
@@ -82,43 +107,51 @@ func (l *IndexList[T, ID]) RemoveIndex(fieldName string) {
 	}
 
 	delete(l.indexMap.index, fieldName)
+	l.indexMap.composite.remove(fieldName)
 }
 
-// Insert add the given Item to the list,
-// There is NO check, for existing this Item in the list, it will ALWAYS inserting!
-func (l *IndexList[T, ID]) Insert(item T) int {
+// Insert add the given Item to the list and returns the List-Index.
+// There is NO check, for existing this Item in the list, it will inserting,
+// only a Constraint (e.g. UniqueIndex) can reject the Item, then returns an error (e.g. ErrUniqueViolation).
+//
+// If the write-ahead log is opened and writing fails, the Item is not inserted and the error is returned.
+func (l *IndexList[T, ID]) Insert(item T) (int, error) {
+	defer l.indexMap.subs.deliver()
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
-	idx := l.list.Insert(item)
-	l.indexMap.Set(&item, idx)
+	if err := l.indexMap.check(&item, l.list.nextIndex()); err != nil {
+		return 0, err
+	}
 
-	return idx
+	if err := l.logOps(walOp[T]{kind: walInsert, item: item}); err != nil {
+		return 0, err
+	}
+
+	return l.insertNoLock(item), nil
 }
 
 // Update replaces an item and consistently updates all registered indexes.
+// A Constraint (e.g. UniqueIndex) can reject the Item, then the saved Item is not changed.
 func (l *IndexList[T, ID]) Update(item T) error {
+	defer l.indexMap.subs.deliver()
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
-	id, idx, err := l.indexMap.getIDByItem(&item)
+	idx, err := l.indexOfItemNoLock(&item)
 	if err != nil {
 		return err
 	}
 
-	// overwrite the data in the main list
-	oldItem, ok := l.list.Set(idx, item)
-	if !ok {
-		return ErrValueNotFound{id}
+	if err := l.indexMap.check(&item, idx); err != nil {
+		return err
 	}
 
-	// re-index
-	for _, index := range l.indexMap.index {
-		// TODO: do it better: check is it neccesary/dirty
-		index.UnSet(&oldItem, uint32(idx))
-		index.Set(&item, uint32(idx))
+	if err := l.logOps(walOp[T]{kind: walUpdate, item: item}); err != nil {
+		return err
 	}
 
+	l.updateNoLock(idx, item)
 	return nil
 }
 
@@ -129,6 +162,7 @@ func (l *IndexList[T, ID]) Update(item T) error {
 // - ID not found
 // - no ID defined
 func (l *IndexList[T, ID]) Remove(id ID) (bool, error) {
+	defer l.indexMap.subs.deliver()
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
@@ -137,6 +171,14 @@ func (l *IndexList[T, ID]) Remove(id ID) (bool, error) {
 		return false, err
 	}
 
+	if _, found := l.list.Get(idx); !found {
+		return false, nil
+	}
+
+	if err := l.logOps(walOp[T]{kind: walRemove, lidx: idx}); err != nil {
+		return false, err
+	}
+
 	_, removed := l.removeNoLock(idx)
 	return removed, nil
 }
@@ -171,30 +213,36 @@ func (l *IndexList[T, ID]) Contains(id ID) bool {
 	return err == nil
 }
 
+// QueryStr parses and executes the query string, with the optional clauses: ORDER BY, LIMIT and OFFSET
 func (l *IndexList[T, ID]) QueryStr(queryStr string) (QueryResult[T, ID], error) {
-	query, err := Parse(queryStr)
+	stmt, err := ParseStatement(queryStr)
 	if err != nil {
 		return QueryResult[T, ID]{}, err
 	}
 
-	return l.Query(query)
+	return l.QueryStatement(stmt)
 }
 
 // Query execute the given Query.
 func (l *IndexList[T, ID]) Query(query Query32) (QueryResult[T, ID], error) {
+	return l.QueryStatement(Statement{Query: query})
+}
+
+// QueryStatement execute the Query of the Statement and orders and cuts the result with the clauses.
+func (l *IndexList[T, ID]) QueryStatement(stmt Statement) (QueryResult[T, ID], error) {
 	l.lock.RLock()
 	defer l.lock.RUnlock()
 
-	bs, canMutate, err := query(l.indexMap.FilterByName, l.indexMap.allIDs)
+	bs, err := ensureMutable(stmt.Query(l.indexMap.FilterByName, l.indexMap.allIDs))
 	if err != nil {
 		return QueryResult[T, ID]{}, err
 	}
 
-	if !canMutate {
-		bs = bs.Copy()
+	result := QueryResult[T, ID]{bitSet: bs, list: l}
+	if err := result.applyClauses(stmt, l.indexMap.FilterByName, &l.list); err != nil {
+		return QueryResult[T, ID]{}, err
 	}
-
-	return QueryResult[T, ID]{bitSet: bs, list: l}, nil
+	return result, nil
 }
 
 // Count the Items, which in this list exist
@@ -205,6 +253,44 @@ func (l *IndexList[T, ID]) Count() int {
 	return l.list.Count()
 }
 
+// clearNoLock removes all Items from the list and the Indices
+func (l *IndexList[T, ID]) clearNoLock() {
+	for idx, item := range l.list.Iter() {
+		l.indexMap.UnSet(&item, idx)
+	}
+	l.list = NewFreeList[T]()
+}
+
+//go:inline
+func (l *IndexList[T, ID]) insertNoLock(item T) int {
+	idx := l.list.Insert(item)
+	l.indexMap.Set(&item, idx)
+	return idx
+}
+
+// indexOfItemNoLock returns the List-Index of the saved Item with the same ID
+func (l *IndexList[T, ID]) indexOfItemNoLock(item *T) (int, error) {
+	id, idx, err := l.indexMap.getIDByItem(item)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, found := l.list.Get(idx); !found {
+		return 0, ErrValueNotFound{id}
+	}
+
+	return idx, nil
+}
+
+//go:inline
+func (l *IndexList[T, ID]) updateNoLock(idx int, item T) {
+	// overwrite the data in the main list
+	oldItem, _ := l.list.Set(idx, item)
+
+	// re-index
+	l.indexMap.Update(&oldItem, &item, idx)
+}
+
 //go:inline
 func (l *IndexList[T, ID]) removeNoLock(index int) (t T, removed bool) {
 	item, found := l.list.Get(index)
@@ -220,24 +306,62 @@ func (l *IndexList[T, ID]) removeNoLock(index int) (t T, removed bool) {
 
 type QueryResult[T any, ID comparable] struct {
 	bitSet *BitSet[uint32]
+	// the List-Indices in the order of: ORDER BY, nil if the result is not ordered
+	ordered []uint32
+	orderBy []OrderBy
+	// the values of the SELECT Aggregations
+	aggregates []AggregateValue
+	// the Groups of GROUP BY
+	groups []Group
 	list   *IndexList[T, ID]
+	// the result is from a Snapshot, the Items are read from the Snapshot
+	snapshot *Snapshot[T, ID]
 }
 
 func (q *QueryResult[T, ID]) Count() int    { return q.bitSet.Count() }
 func (q *QueryResult[T, ID]) IsEmpty() bool { return q.bitSet.IsEmpty() }
 
-func (q *QueryResult[T, ID]) Values() []T {
-	list := make([]T, 0, q.bitSet.Count())
+// readItems calls the read function with the FreeList, which contains the Items.
+// The list of an IndexList is locked, the list of a Snapshot not.
+func (q *QueryResult[T, ID]) readItems(read func(*FreeList[T])) {
+	if q.snapshot != nil {
+		read(&q.snapshot.list)
+		return
+	}
 
 	q.list.lock.RLock()
 	defer q.list.lock.RUnlock()
 
-	q.bitSet.Values(func(r uint32) bool {
-		// get from the FreeList without lock
-		o, _ := q.list.list.Get(int(r))
-		list = append(list, o)
+	read(&q.list.list)
+}
 
-		return true
+// filterByName returns the FilterByName of the list or of the Snapshot, the list must be locked
+func (q *QueryResult[T, ID]) filterByName() FilterByName32 {
+	if q.snapshot != nil {
+		return q.snapshot.FilterByName
+	}
+	return q.list.indexMap.FilterByName
+}
+
+func (q *QueryResult[T, ID]) Values() []T {
+	list := make([]T, 0, q.bitSet.Count())
+
+	q.readItems(func(items *FreeList[T]) {
+		if q.ordered != nil {
+			for _, r := range q.ordered {
+				o, _ := items.Get(int(r))
+				list = append(list, o)
+			}
+			return
+		}
+
+		q.bitSet.Values(func(r uint32) bool {
+			// get from the FreeList without lock
+			o, _ := items.Get(int(r))
+			list = append(list, o)
+
+			return true
+		})
 	})
 
 	return list
@@ -249,49 +373,120 @@ func (q *QueryResult[T, ID]) Sort(less func(*T, *T) bool) []T {
 	return list
 }
 
-func (q *QueryResult[T, ID]) RemoveAll() {
+// SortedBy returns an Iterator over the Items, sorted by the values of the field.
+// With a SortedIndex, the sorted values are walked and only the Items of the result are read,
+// so the first Items are found without sorting all Items (top-N).
+// The IndexList is read-locked during the iteration, the list must not be changed in the loop.
+func (q *QueryResult[T, ID]) SortedBy(fieldName string, desc bool) (iter.Seq[T], error) {
+	var err error
+	q.readItems(func(*FreeList[T]) { _, err = sortableFilter[T](q.filterByName(), fieldName) })
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(T) bool) {
+		q.readItems(func(items *FreeList[T]) {
+			q.walkSortedBy(items, fieldName, desc, nil, func(_ uint32, item T) bool { return yield(item) })
+		})
+	}, nil
+}
+
+// walkSortedBy calls visit for the List-Indices and the Items of the result, sorted by the values of the field.
+// Items with equal values are visited in the order of the List-Indices.
+// With a SortedIndex, the walk starts with the value from (nil: the first value), otherwise from is ignored.
+func (q *QueryResult[T, ID]) walkSortedBy(items *FreeList[T], fieldName string, desc bool, from any, visit func(uint32, T) bool) {
+	l := q.filterByName()
+	filter, _ := l(fieldName)
+	walker, ok := filter.(sortedWalker[uint32])
+	if !ok {
+		ordered, _ := orderLidxs(q.bitSet, []OrderBy{{Field: fieldName, Desc: desc}}, 0, l, items)
+		for _, lidx := range ordered {
+			item, _ := items.Get(int(lidx))
+			if !visit(lidx, item) {
+				return
+			}
+		}
+		return
+	}
+
+	next := true
+	walker.walkSorted(desc, from, func(p PostingList[uint32]) bool {
+		p.Values(func(lidx uint32) bool {
+			if q.bitSet.Contains(lidx) {
+				item, _ := items.Get(int(lidx))
+				next = visit(lidx, item)
+			}
+			return next
+		})
+		return next
+	})
+}
+
+// RemoveAll removes all Items of this result from the list.
+// If writing the write-ahead log fails, no Item is removed and the error is returned.
+// The result of a Snapshot is read-only, this returns the error: ErrReadOnly
+func (q *QueryResult[T, ID]) RemoveAll() error {
+	if q.snapshot != nil {
+		return ErrReadOnly{}
+	}
+
+	defer q.list.indexMap.subs.deliver()
 	q.list.lock.Lock()
 	defer q.list.lock.Unlock()
 
+	ops := make([]walOp[T], 0, q.bitSet.Count())
 	q.bitSet.Values(func(r uint32) bool {
-		q.list.removeNoLock(int(r))
+		if _, found := q.list.list.Get(int(r)); found {
+			ops = append(ops, walOp[T]{kind: walRemove, lidx: int(r)})
+		}
 		return true
 	})
 
+	if err := q.list.logOps(ops...); err != nil {
+		return err
+	}
+
+	for _, op := range ops {
+		q.list.removeNoLock(op.lidx)
+	}
+
 	q.bitSet.Clear()
+	return nil
 }
 
 type PageInfo struct {
 	Offset uint32
 	Limit  uint32
 	Count  int
-	Total  int
+	Total  int // the number of Items in the result
 }
 
+// Pagination returns the Items on the positions: offset until offset+limit of the result.
+// The positions are in the order of the result (ORDER BY or the List-Indices).
 func (q *QueryResult[T, ID]) Pagination(offset, limit uint32) ([]T, PageInfo) {
-	pi := PageInfo{Offset: offset, Limit: limit, Total: q.list.Count()}
-
-	if offset > uint32(pi.Total) {
-		return []T{}, pi
-	}
-
-	capacity := limit
-	if offset+limit > uint32(pi.Total) {
-		capacity = uint32(pi.Total) - offset
-	}
-	list := make([]T, 0, capacity)
-
-	q.list.lock.RLock()
-	defer q.list.lock.RUnlock()
-
-	q.bitSet.Range(offset, offset+limit, func(idx uint32) bool {
-		if idx == offset+limit {
-			return false
+	pi := PageInfo{Offset: offset, Limit: limit, Total: q.bitSet.Count()}
+	from := min(int(offset), pi.Total)
+	to := min(int(offset)+int(limit), pi.Total)
+	list := make([]T, 0, to-from)
+
+	q.readItems(func(items *FreeList[T]) {
+		if q.ordered != nil {
+			for _, lidx := range q.ordered[from:to] {
+				val, _ := items.Get(int(lidx))
+				list = append(list, val)
+			}
+			return
 		}
 
-		val, _ := q.list.list.Get(int(idx))
-		list = append(list, val)
-		return true
+		start, found := q.bitSet.Select(from)
+		if !found || from == to {
+			return
+		}
+		q.bitSet.Range(start, math.MaxUint32, func(lidx uint32) bool {
+			val, _ := items.Get(int(lidx))
+			list = append(list, val)
+			return len(list) < to-from
+		})
 	})
 
 	pi.Count = len(list)
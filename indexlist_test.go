@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"strings"
@@ -27,10 +27,10 @@ func TestIndexList_Base(t *testing.T) {
 	err = il.CreateIndex("isnew", NewMapIndex((*car).IsNew))
 	assert.NoError(t, err)
 
-	il.Insert(car{name: "Dacia", age: 22, color: "red"})
-	il.Insert(car{name: "Opel", age: 22})
-	il.Insert(car{name: "Mercedes", age: 5, isNew: true})
-	il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Insert(car{name: "Dacia", age: 22, color: "red"})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
 	assert.Equal(t, 4, il.Count())
 
 	err = il.CreateIndex("age", NewMapIndex((*car).Age))
@@ -94,7 +94,7 @@ func TestIndexList_RemoveIndex(t *testing.T) {
 	il := NewIndexList[car]()
 	assert.Equal(t, 0, len(il.indexMap.index))
 	assert.Nil(t, il.indexMap.idIndex)
-	il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
 
 	err := il.CreateIndex("age", NewMapIndex((*car).Age))
 	assert.NoError(t, err)
@@ -120,7 +120,7 @@ func TestIndexList_RemoveIndex(t *testing.T) {
 func TestIndexList_RemoveIndexWithId(t *testing.T) {
 	il := NewIndexListWithID((*car).Name)
 	assert.NotNil(t, il.indexMap.idIndex)
-	il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
 	assert.Equal(t, 1, il.Count())
 
 	opel, err := il.Get("Opel")
@@ -143,9 +143,9 @@ func TestIndexList_Update(t *testing.T) {
 	err = il.CreateIndex("age", NewMapIndex((*car).Age))
 	assert.NoError(t, err)
 
-	il.Insert(car{name: "Opel", age: 22})
-	il.Insert(car{name: "Mercedes", age: 5, isNew: true})
-	il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
 
 	err = il.Update(car{name: "Dacia", age: 25})
 	assert.NoError(t, err)
@@ -170,11 +170,11 @@ func TestIndexList_QueryResult(t *testing.T) {
 	err := il.CreateIndex("age", NewMapIndex((*car).Age))
 	assert.NoError(t, err)
 
-	il.Insert(car{name: "Mercedes", age: 22, color: "red"})
-	il.Insert(car{name: "Opel", age: 22})
-	il.Insert(car{name: "Dacia", age: 5, isNew: true})
-	il.Insert(car{name: "Dacia", age: 22})
-	il.Insert(car{name: "Audi", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 22, color: "red"})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Dacia", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Insert(car{name: "Audi", age: 22})
 
 	qr, err := il.Query(Eq("age", uint8(22)))
 	assert.NoError(t, err)
@@ -208,11 +208,11 @@ func TestIndexList_Remove(t *testing.T) {
 	err = il.CreateIndex("age", NewMapIndex((*car).Age))
 	assert.NoError(t, err)
 
-	il.Insert(car{name: "Mercedes", age: 22, color: "red"})
-	il.Insert(car{name: "Opel", age: 22})
-	il.Insert(car{name: "Dacia", age: 5, isNew: true})
-	il.Insert(car{name: "Dacia", age: 22})
-	il.Insert(car{name: "Audi", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 22, color: "red"})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Dacia", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Insert(car{name: "Audi", age: 22})
 	assert.Equal(t, 5, il.Count())
 
 	qr, err := il.Query(All())
@@ -244,7 +244,7 @@ func TestIndexList_Remove(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 3, qr.Count())
 
-	qr.RemoveAll()
+	assert.NoError(t, qr.RemoveAll())
 	assert.Equal(t, 1, il.Count())
 
 	c, found := il.list.Get(2)
@@ -259,11 +259,11 @@ func TestIndexList_RemoveLater(t *testing.T) {
 	err = il.CreateIndex("age", NewMapIndex((*car).Age))
 	assert.NoError(t, err)
 
-	il.Insert(car{name: "Mercedes", age: 22, color: "red"})
-	il.Insert(car{name: "Opel", age: 22})
-	il.Insert(car{name: "Dacia", age: 5, isNew: true})
-	il.Insert(car{name: "Dacia", age: 22})
-	il.Insert(car{name: "Audi", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 22, color: "red"})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Dacia", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Insert(car{name: "Audi", age: 22})
 	assert.Equal(t, 5, il.Count())
 
 	qr1, err := il.Query(Eq("name", "Dacia"))
@@ -274,7 +274,7 @@ func TestIndexList_RemoveLater(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 2, qr2.Count())
 
-	qr1.RemoveAll()
+	assert.NoError(t, qr1.RemoveAll())
 	assert.Equal(t, 0, qr1.Count())
 	assert.Equal(t, 2, qr2.Count())
 	assert.Equal(t, 3, il.Count())
@@ -285,7 +285,7 @@ func TestIndexList_RemoveLater(t *testing.T) {
 	assert.True(t, qr.IsEmpty())
 
 	// qr1 has allready remove all Dacia
-	qr2.RemoveAll()
+	assert.NoError(t, qr2.RemoveAll())
 	assert.Equal(t, 3, il.Count())
 }
 
@@ -296,11 +296,11 @@ func TestIndexList_RemoveLaterAsync(t *testing.T) {
 	err = il.CreateIndex("age", NewMapIndex((*car).Age))
 	assert.NoError(t, err)
 
-	il.Insert(car{name: "Mercedes", age: 22, color: "red"})
-	il.Insert(car{name: "Opel", age: 22})
-	il.Insert(car{name: "Dacia", age: 5, isNew: true})
-	il.Insert(car{name: "Dacia", age: 22})
-	il.Insert(car{name: "Audi", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 22, color: "red"})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Dacia", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Insert(car{name: "Audi", age: 22})
 	assert.Equal(t, 5, il.Count())
 
 	qr1, err := il.Query(Eq("name", "Dacia"))
@@ -314,12 +314,12 @@ func TestIndexList_RemoveLaterAsync(t *testing.T) {
 	var wg sync.WaitGroup
 
 	wg.Go(func() {
-		qr1.RemoveAll()
+		assert.NoError(t, qr1.RemoveAll())
 		assert.Equal(t, 0, qr1.Count())
 	})
 
 	wg.Go(func() {
-		qr2.RemoveAll()
+		assert.NoError(t, qr2.RemoveAll())
 		assert.Equal(t, 0, qr2.Count())
 	})
 
@@ -329,10 +329,10 @@ func TestIndexList_RemoveLaterAsync(t *testing.T) {
 
 func TestIndexList_CreateIndex(t *testing.T) {
 	il := NewIndexList[car]()
-	il.Insert(car{name: "Dacia", age: 22, color: "red"})
-	il.Insert(car{name: "Opel", age: 22})
-	il.Insert(car{name: "Mercedes", age: 5, isNew: true})
-	il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Insert(car{name: "Dacia", age: 22, color: "red"})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
 
 	_, err := il.Query(Eq("name", "Opel"))
 	assert.Error(t, err)
@@ -354,10 +354,10 @@ func TestIndexList_CreateIndexVarious(t *testing.T) {
 	err = il.CreateIndex("age", NewSortedIndex((*car).Age))
 	assert.NoError(t, err)
 
-	il.Insert(car{name: "Dacia", age: 2, color: "red"})
-	il.Insert(car{name: "Opel", age: 12})
-	il.Insert(car{name: "Mercedes", age: 5, isNew: true})
-	il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Insert(car{name: "Dacia", age: 2, color: "red"})
+	_, _ = il.Insert(car{name: "Opel", age: 12})
+	_, _ = il.Insert(car{name: "Mercedes", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
 
 	qr, err := il.Query(Eq("name", "Opel"))
 	assert.NoError(t, err)
@@ -404,10 +404,10 @@ func TestIndexList_StringItem(t *testing.T) {
 	err := il.CreateIndex("val", NewMapIndex(FromValue[string]()))
 	assert.NoError(t, err)
 
-	il.Insert("Dacia")
-	il.Insert("Opel")
-	il.Insert("Mercedes")
-	il.Insert("Dacia")
+	_, _ = il.Insert("Dacia")
+	_, _ = il.Insert("Opel")
+	_, _ = il.Insert("Mercedes")
+	_, _ = il.Insert("Dacia")
 
 	qr, err := il.Query(Eq("val", "Dacia"))
 	assert.NoError(t, err)
@@ -421,9 +421,9 @@ func TestIndexList_StringPtrItemWithNil(t *testing.T) {
 	assert.NoError(t, err)
 
 	dacia := "Dacia"
-	il.Insert(&dacia)
-	il.Insert(nil)
-	il.Insert(&dacia)
+	_, _ = il.Insert(&dacia)
+	_, _ = il.Insert(nil)
+	_, _ = il.Insert(&dacia)
 
 	qr, err := il.Query(Eq("val", &dacia))
 	assert.NoError(t, err)
@@ -454,9 +454,9 @@ func TestIndexList_WithID(t *testing.T) {
 	err := il.CreateIndex("isnew", NewMapIndex((*car).IsNew))
 	assert.NoError(t, err)
 
-	il.Insert(car{name: "Opel", age: 22})
-	il.Insert(car{name: "Mercedes", age: 5, isNew: true})
-	il.Insert(car{name: "Dacia", age: 42})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 42})
 
 	dacia, err := il.Get("Dacia")
 	assert.NoError(t, err)
@@ -488,9 +488,9 @@ func TestIndexList_NoID_QueryIDs(t *testing.T) {
 func TestIndexList_QueryIDs(t *testing.T) {
 	il := NewIndexListWithID((*car).Name)
 
-	il.Insert(car{name: "Opel", age: 22})
-	il.Insert(car{name: "Mercedes", age: 5, isNew: true})
-	il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
 
 	qr, err := il.Query(ID("Opel"))
 	assert.NoError(t, err)
@@ -516,9 +516,9 @@ func TestIndexList_QueryIDs(t *testing.T) {
 func TestIndexList_Pagination(t *testing.T) {
 	il := NewIndexListWithID((*car).Name)
 
-	il.Insert(car{name: "Opel", age: 22})
-	il.Insert(car{name: "Mercedes", age: 5, isNew: true})
-	il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
 
 	qr, err := il.Query(All())
 	assert.NoError(t, err)
@@ -564,6 +564,37 @@ func TestIndexList_Pagination(t *testing.T) {
 	assert.Equal(t, []car{}, result)
 }
 
+func TestIndexList_PaginationOfResult(t *testing.T) {
+	il := newPlannerList()
+
+	// the offset is the position in the result, not the List-Index
+	qr, err := il.QueryStr(`age > uint8(1) and age < uint8(7)`)
+	assert.NoError(t, err)
+
+	result, pi := qr.Pagination(1, 2)
+	assert.Equal(t, PageInfo{Offset: 1, Limit: 2, Count: 2, Total: 5}, pi)
+	assert.Equal(t, []string{"D", "E"}, names(result))
+
+	result, pi = qr.Pagination(4, 2)
+	assert.Equal(t, PageInfo{Offset: 4, Limit: 2, Count: 1, Total: 5}, pi)
+	assert.Equal(t, []string{"G"}, names(result))
+
+	result, pi = qr.Pagination(5, 2)
+	assert.Equal(t, PageInfo{Offset: 5, Limit: 2, Count: 0, Total: 5}, pi)
+	assert.Equal(t, []string{}, names(result))
+
+	result, pi = qr.Pagination(0, 0)
+	assert.Equal(t, PageInfo{Offset: 0, Limit: 0, Count: 0, Total: 5}, pi)
+	assert.Empty(t, result)
+
+	// in the order of: ORDER BY
+	qr, err = il.QueryStr(`age > uint8(1) and age < uint8(7) order by age desc`)
+	assert.NoError(t, err)
+	result, pi = qr.Pagination(1, 2)
+	assert.Equal(t, PageInfo{Offset: 1, Limit: 2, Count: 2, Total: 5}, pi)
+	assert.Equal(t, []string{"F", "E"}, names(result))
+}
+
 func TestIndexList_QueryStr(t *testing.T) {
 	il := NewIndexListWithID((*car).Name)
 	err := il.CreateIndex("name", NewSortedIndex((*car).Name))
@@ -571,10 +602,10 @@ func TestIndexList_QueryStr(t *testing.T) {
 	err = il.CreateIndex("age", NewSortedIndex((*car).Age))
 	assert.NoError(t, err)
 
-	il.Insert(car{name: "Opel", age: 22})
-	il.Insert(car{name: "Mercedes", age: 5})
-	il.Insert(car{name: "Dacia", age: 22})
-	il.Insert(car{name: "Opel", age: 5})
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 5})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Insert(car{name: "Opel", age: 5})
 
 	qr, err := il.QueryStr(`name = "Opel"`)
 	assert.NoError(t, err)
@@ -598,3 +629,31 @@ func TestIndexList_QueryStr(t *testing.T) {
 		{name: "Opel", age: 5},
 	}, qr.Values())
 }
+
+func TestIndexList_QueryStrContains(t *testing.T) {
+	il := NewIndexList[car]()
+	err := il.CreateIndex("name", NewTrigramIndex((*car).Name))
+	assert.NoError(t, err)
+
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 5})
+	_, _ = il.Insert(car{name: "Ferrari", age: 22})
+
+	qr, err := il.QueryStr(`name CONTAINS "ar"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Ferrari", age: 22}}, qr.Values())
+
+	qr, err = il.QueryStr(`name contains "ede" or name contains "pel"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{
+		{name: "Opel", age: 22},
+		{name: "Mercedes", age: 5},
+	}, qr.Values())
+
+	qr, err = il.Query(Contains("name", "r"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, qr.Count())
+
+	_, err = il.QueryStr(`name = "Opel"`)
+	assert.ErrorIs(t, err, ErrInvalidOperation{TrigramIndexName, OpEq})
+}
@@ -1,6 +1,9 @@
-package main
+package fali
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Op uint16
 
@@ -22,6 +25,10 @@ const (
 	OpString
 	OpNumber
 	OpBool
+	OpLBracket
+	OpRBracket
+	OpPlaceholder
+	OpAsterisk
 
 	// Logical
 	OpAnd Op = opLogical | iota
@@ -38,6 +45,9 @@ const (
 	OpBetween       = opRelational | (1 << 6)
 	OpIn            = opRelational | (1 << 7)
 	OpStartsWith    = opRelational | (1 << 8)
+	OpContains      = opRelational | (1 << 9)
+	OpHasAny        = opRelational | (1 << 10)
+	OpHasAll        = opRelational | (1 << 11)
 )
 
 func (o Op) IsRelational() bool { return o&opCategoryMaskOp == opRelational }
@@ -77,6 +87,12 @@ func (o Op) String() string {
 		return "IN"
 	case OpStartsWith:
 		return "STARTSWITH"
+	case OpContains:
+		return "CONTAINS"
+	case OpHasAny:
+		return "HAS ANY"
+	case OpHasAll:
+		return "HAS ALL"
 	case OpAnd:
 		return "AND"
 	case OpOr:
@@ -87,6 +103,14 @@ func (o Op) String() string {
 		return "("
 	case OpRParen:
 		return ")"
+	case OpLBracket:
+		return "["
+	case OpRBracket:
+		return "]"
+	case OpPlaceholder:
+		return "PLACEHOLDER"
+	case OpAsterisk:
+		return "*"
 	default:
 		return fmt.Sprintf("UNKNOWN: %d", o)
 	}
@@ -129,6 +153,14 @@ func (l *lexer) nextToken() token {
 		start := l.pos
 		l.pos++
 		return token{Op: OpRParen, Start: start, End: l.pos}
+	case ch == '[':
+		start := l.pos
+		l.pos++
+		return token{Op: OpLBracket, Start: start, End: l.pos}
+	case ch == ']':
+		start := l.pos
+		l.pos++
+		return token{Op: OpRBracket, Start: start, End: l.pos}
 	case ch == '=':
 		start := l.pos
 		l.pos++
@@ -163,6 +195,16 @@ func (l *lexer) nextToken() token {
 		start := l.pos
 		l.pos++
 		return token{Op: OpComma, Start: start, End: l.pos}
+	case ch == '?':
+		start := l.pos
+		l.pos++
+		return token{Op: OpPlaceholder, Start: start, End: l.pos}
+	case ch == '*':
+		start := l.pos
+		l.pos++
+		return token{Op: OpAsterisk, Start: start, End: l.pos}
+	case ch == ':':
+		return l.readNamedPlaceholder()
 	case ch == '"', ch == '\'':
 		return l.readString(ch)
 	case (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_':
@@ -180,7 +222,7 @@ func (l *lexer) nextToken() token {
 // - bool: true, false
 // - Logical: or, and, not
 // - ident: fieldname
-// - operation: between
+// - operation: between, contains, has any, has all
 func (l *lexer) readKeyword() token {
 	start := l.pos
 	// read while are there letters, numbers or _
@@ -224,6 +266,14 @@ func (l *lexer) readKeyword() token {
 			(b[2] == 't' || b[2] == 'T') {
 			return token{Op: OpNot, Start: start, End: l.pos}
 		}
+		// HAS ANY, HAS ALL
+		if (b[0] == 'h' || b[0] == 'H') &&
+			(b[1] == 'a' || b[1] == 'A') &&
+			(b[2] == 's' || b[2] == 'S') {
+			if tok, ok := l.readHas(start); ok {
+				return tok
+			}
+		}
 	case 4:
 		// TRUE
 		if (b[0] == 't' || b[0] == 'T') &&
@@ -252,6 +302,18 @@ func (l *lexer) readKeyword() token {
 			(b[6] == 'n' || b[6] == 'N') {
 			return token{Op: OpBetween, Start: start, End: l.pos}
 		}
+	case 8:
+		// CONTAINS
+		if (b[0] == 'c' || b[0] == 'C') &&
+			(b[1] == 'o' || b[1] == 'O') &&
+			(b[2] == 'n' || b[2] == 'N') &&
+			(b[3] == 't' || b[3] == 'T') &&
+			(b[4] == 'a' || b[4] == 'A') &&
+			(b[5] == 'i' || b[5] == 'I') &&
+			(b[6] == 'n' || b[6] == 'N') &&
+			(b[7] == 's' || b[7] == 'S') {
+			return token{Op: OpContains, Start: start, End: l.pos}
+		}
 	}
 
 	// If it didn't match any of the keywords, it's just a normal identifier
@@ -259,6 +321,59 @@ func (l *lexer) readKeyword() token {
 	return token{Op: OpIdent, Start: start, End: l.pos}
 }
 
+// readNamedPlaceholder reads: ':name', the name starts with a letter or _
+func (l *lexer) readNamedPlaceholder() token {
+	start := l.pos
+	l.pos++ // consume ':'
+
+	for l.pos < len(l.input) {
+		ch := l.input[l.pos]
+		if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_' || (l.pos > start+1 && ch >= '0' && ch <= '9') {
+			l.pos++
+		} else {
+			break
+		}
+	}
+
+	if l.pos == start+1 {
+		return token{Op: OpUndefined, Start: start, End: l.pos}
+	}
+	return token{Op: OpPlaceholder, Start: start, End: l.pos}
+}
+
+// readHas reads the second word after HAS: ANY or ALL.
+// If there is no ANY or ALL, HAS is a normal identifier.
+func (l *lexer) readHas(start int) (token, bool) {
+	pos := l.pos
+	for pos < len(l.input) && (l.input[pos] == ' ' || l.input[pos] == '\t' || l.input[pos] == '\n' || l.input[pos] == '\r') {
+		pos++
+	}
+
+	end := pos + 3
+	if pos == l.pos || end > len(l.input) {
+		return token{}, false
+	}
+	if end < len(l.input) {
+		ch := l.input[end]
+		if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '_' {
+			return token{}, false
+		}
+	}
+
+	var op Op
+	switch w := l.input[pos:end]; {
+	case strings.EqualFold(w, "any"):
+		op = OpHasAny
+	case strings.EqualFold(w, "all"):
+		op = OpHasAll
+	default:
+		return token{}, false
+	}
+
+	l.pos = end
+	return token{Op: op, Start: start, End: end}, true
+}
+
 func (l *lexer) readNumber() token {
 	start := l.pos
 	hasDot := false
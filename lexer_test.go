@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"fmt"
@@ -36,8 +36,14 @@ func TestLexer_OneOpen(t *testing.T) {
 		{query: ` , `, expected: OpComma},
 		{query: `betWeen`, expected: OpBetween},
 		{query: `In`, expected: OpIn},
+		{query: `conTAINS`, expected: OpContains},
 
 		{query: `startswith`, expected: OpIdent},
+		{query: `has any`, expected: OpHasAny},
+		{query: `HAS  All`, expected: OpHasAll},
+		{query: `has`, expected: OpIdent},
+		{query: `has anything`, expected: OpIdent},
+		{query: `hasany`, expected: OpIdent},
 	}
 
 	for _, tt := range tests {
@@ -127,6 +133,53 @@ func TestLexer_ManyOpen(t *testing.T) {
 			OpString,
 			OpRParen,
 		}},
+		{query: `name between ]"a", "x"[`, expected: []Op{
+			OpIdent,
+			OpBetween,
+			OpRBracket,
+			OpString,
+			OpComma,
+			OpString,
+			OpLBracket,
+		}},
+		{query: `age > ? and name = :name_1`, expected: []Op{
+			OpIdent,
+			OpGt,
+			OpPlaceholder,
+			OpAnd,
+			OpIdent,
+			OpEq,
+			OpPlaceholder,
+			OpEOF,
+		}},
+		{query: `select count(*) where`, expected: []Op{
+			OpIdent,
+			OpIdent,
+			OpLParen,
+			OpAsterisk,
+			OpRParen,
+			OpIdent,
+			OpEOF,
+		}},
+		{query: `name contains "ar"`, expected: []Op{
+			OpIdent,
+			OpContains,
+			OpString,
+		}},
+		{query: `tags has any("a", "x")`, expected: []Op{
+			OpIdent,
+			OpHasAny,
+			OpLParen,
+			OpString,
+			OpComma,
+			OpString,
+			OpRParen,
+		}},
+		{query: `has = 1`, expected: []Op{
+			OpIdent,
+			OpEq,
+			OpNumber,
+		}},
 		{query: `name IN("a", "x")`, expected: []Op{
 			OpIdent,
 			OpIn,
@@ -0,0 +1,236 @@
+package fali
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+const MultiValueIndexName = "MultiValueIndex"
+
+// MultiValueIndex is a mapping of every element of a slice to the Index in the List,
+// so an Item is found by each of his elements (e.g. tags).
+// For a map field, the FromField function can returns the keys: slices.Collect(maps.Keys(m))
+//
+// This index supported Queries with the Relations: Equal (contains the value), IN, HAS ANY and HAS ALL.
+// The List-Indices are saved in a compressed Roaring BitSet, like the MapIndex.
+type MultiValueIndex[OBJ any, V comparable, LI Value] struct {
+	data       map[V]*Roaring[LI]
+	fieldGetFn FromField[OBJ, []V]
+	cow        cow[V]
+}
+
+func NewMultiValueIndex[OBJ any, V comparable](fieldGetFn FromField[OBJ, []V]) Index32[OBJ] {
+	return newMultiValueIndex[OBJ, V, uint32](fieldGetFn)
+}
+
+func newMultiValueIndex[OBJ any, V comparable, LI Value](fieldGetFn FromField[OBJ, []V]) *MultiValueIndex[OBJ, V, LI] {
+	return &MultiValueIndex[OBJ, V, LI]{
+		data:       make(map[V]*Roaring[LI]),
+		fieldGetFn: fieldGetFn,
+	}
+}
+
+func (mi *MultiValueIndex[OBJ, V, LI]) Set(obj *OBJ, lidx LI) {
+	for _, value := range mi.fieldGetFn(obj) {
+		r, found := mi.roaring(value)
+		if !found {
+			r = NewRoaring[LI]()
+			mi.data[value] = r
+		}
+		r.Set(lidx)
+	}
+}
+
+func (mi *MultiValueIndex[OBJ, V, LI]) UnSet(obj *OBJ, lidx LI) {
+	for _, value := range mi.fieldGetFn(obj) {
+		if r, found := mi.roaring(value); found {
+			r.UnSet(lidx)
+			if r.IsEmpty() {
+				delete(mi.data, value)
+			}
+		}
+	}
+}
+
+func (mi *MultiValueIndex[OBJ, V, LI]) Match(op Op, value any) (*BitSet[LI], error) {
+	v, ok := value.(V)
+	if !ok {
+		return nil, ErrInvalidIndexValue[V]{value}
+	}
+
+	if op != OpEq {
+		return nil, ErrInvalidOperation{MultiValueIndexName, op}
+	}
+
+	r, found := mi.data[v]
+	if !found {
+		return NewBitSet[LI](), nil
+	}
+
+	return r.ToBitSet(), nil
+}
+
+// matchPosting returns the saved Roaring BitSet of the value, without creating a BitSet
+func (mi *MultiValueIndex[OBJ, V, LI]) matchPosting(value any) (PostingList[LI], error) {
+	v, ok := value.(V)
+	if !ok {
+		return nil, ErrInvalidIndexValue[V]{value}
+	}
+
+	if r, found := mi.data[v]; found {
+		return r, nil
+	}
+	return NewRoaring[LI](), nil
+}
+
+func (mi *MultiValueIndex[OBJ, V, LI]) MatchMany(op Op, values ...any) (*BitSet[LI], error) {
+	keys, err := mi.keys(op, values)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewBitSet[LI]()
+	switch op {
+	case OpIn, OpHasAny:
+		for _, key := range keys {
+			if r, found := mi.data[key]; found {
+				r.orInto(result)
+			}
+		}
+	case OpHasAll:
+		for i, key := range keys {
+			r, found := mi.data[key]
+			if !found {
+				return NewBitSet[LI](), nil
+			}
+
+			if i == 0 {
+				r.orInto(result)
+			} else {
+				r.andInto(result)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// ValueType returns the type of the single values of the field
+func (mi *MultiValueIndex[OBJ, V, LI]) ValueType() reflect.Type { return reflect.TypeFor[V]() }
+
+// buckets calls visit for every value with the List-Indices
+func (mi *MultiValueIndex[OBJ, V, LI]) buckets(visit func(any, PostingList[LI]) bool) {
+	for value, r := range mi.data {
+		if !visit(value, r) {
+			return
+		}
+	}
+}
+
+// Estimate returns the number of Items for Eq, the sum for HAS ANY and the smallest number for HAS ALL
+func (mi *MultiValueIndex[OBJ, V, LI]) Estimate(op Op, values ...any) (int, error) {
+	if op == OpEq {
+		if len(values) != 1 {
+			return 0, ErrInvalidArgsLen{defined: "1", got: len(values)}
+		}
+		r, err := mi.matchPosting(values[0])
+		if err != nil {
+			return 0, err
+		}
+		return r.Count(), nil
+	}
+
+	keys, err := mi.keys(op, values)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i, key := range keys {
+		c := 0
+		if r, found := mi.data[key]; found {
+			c = r.Count()
+		}
+
+		switch {
+		case op != OpHasAll:
+			count += c
+		case i == 0 || c < count:
+			count = c
+		}
+	}
+	return count, nil
+}
+
+// Snapshot returns an immutable MultiValueIndex, which shares the data until this MultiValueIndex is changed.
+func (mi *MultiValueIndex[OBJ, V, LI]) Snapshot() Filter[LI] {
+	mi.cow.snapshot()
+	return &MultiValueIndex[OBJ, V, LI]{data: mi.data, fieldGetFn: mi.fieldGetFn}
+}
+
+func (mi *MultiValueIndex[OBJ, V, LI]) matchItem(obj *OBJ, op Op, values ...any) (bool, error) {
+	if op == OpEq {
+		if len(values) != 1 {
+			return false, ErrInvalidArgsLen{defined: "1", got: len(values)}
+		}
+		v, ok := values[0].(V)
+		if !ok {
+			return false, ErrInvalidIndexValue[V]{values[0]}
+		}
+		return slices.Contains(mi.fieldGetFn(obj), v), nil
+	}
+
+	keys, err := mi.keys(op, values)
+	if err != nil {
+		return false, err
+	}
+
+	itemValues := mi.fieldGetFn(obj)
+	if op == OpHasAll {
+		for _, key := range keys {
+			if !slices.Contains(itemValues, key) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	return slices.ContainsFunc(itemValues, func(v V) bool { return slices.Contains(keys, v) }), nil
+}
+
+// keys checks the operation and converts the values to the value type of the Index
+func (mi *MultiValueIndex[OBJ, V, LI]) keys(op Op, values []any) ([]V, error) {
+	switch op {
+	case OpIn, OpHasAny:
+	case OpHasAll:
+		if len(values) == 0 {
+			return nil, ErrInvalidArgsLen{defined: "1 or more", got: 0}
+		}
+	default:
+		return nil, ErrInvalidOperation{MultiValueIndexName, op}
+	}
+
+	keys := make([]V, len(values))
+	var ok bool
+	for i, val := range values {
+		if keys[i], ok = val.(V); !ok {
+			return nil, ErrInvalidIndexValue[V]{val}
+		}
+	}
+	return keys, nil
+}
+
+// roaring returns the Roaring BitSet for changing, copy the Roaring BitSet if it is shared with a snapshot
+func (mi *MultiValueIndex[OBJ, V, LI]) roaring(value V) (*Roaring[LI], bool) {
+	if mi.cow.ownRoot() {
+		mi.data = maps.Clone(mi.data)
+	}
+
+	r, found := mi.data[value]
+	if found && mi.cow.own(value) {
+		r = r.Copy()
+		mi.data[value] = r
+	}
+	return r, found
+}
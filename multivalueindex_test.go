@@ -0,0 +1,122 @@
+package fali
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type article struct {
+	title string
+	tags  []string
+}
+
+func (a *article) Tags() []string { return a.tags }
+
+func newArticleIndex() *MultiValueIndex[article, string, uint32] {
+	mi := newMultiValueIndex[article, string, uint32]((*article).Tags)
+	mi.Set(&article{tags: []string{"go", "db"}}, 1)
+	mi.Set(&article{tags: []string{"go"}}, 3)
+	mi.Set(&article{tags: []string{"rust", "db", "db"}}, 5)
+	mi.Set(&article{}, 7)
+	return mi
+}
+
+func TestMultiValueIndex_Match(t *testing.T) {
+	mi := newArticleIndex()
+
+	tests := []struct {
+		name     string
+		op       Op
+		values   []any
+		expected []uint32
+	}{
+		{name: "eq", op: OpEq, values: []any{"go"}, expected: []uint32{1, 3}},
+		{name: "eq not found", op: OpEq, values: []any{"java"}, expected: []uint32{}},
+		{name: "in", op: OpIn, values: []any{"go", "rust"}, expected: []uint32{1, 3, 5}},
+		{name: "has any", op: OpHasAny, values: []any{"db", "java"}, expected: []uint32{1, 5}},
+		{name: "has any empty", op: OpHasAny, values: []any{}, expected: []uint32{}},
+		{name: "has all", op: OpHasAll, values: []any{"go", "db"}, expected: []uint32{1}},
+		{name: "has all one", op: OpHasAll, values: []any{"db"}, expected: []uint32{1, 5}},
+		{name: "has all not found", op: OpHasAll, values: []any{"go", "java"}, expected: []uint32{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bs *BitSet[uint32]
+			var err error
+			if tt.op == OpEq {
+				bs, err = mi.Match(tt.op, tt.values[0])
+			} else {
+				bs, err = mi.MatchMany(tt.op, tt.values...)
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, bs.ToSlice())
+
+			item := article{tags: []string{"rust", "db"}}
+			matched, err := mi.matchItem(&item, tt.op, tt.values...)
+			assert.NoError(t, err)
+			assert.Equal(t, bs.Contains(5), matched)
+		})
+	}
+}
+
+func TestMultiValueIndex_UnSet(t *testing.T) {
+	mi := newArticleIndex()
+	mi.UnSet(&article{tags: []string{"rust", "db", "db"}}, 5)
+	mi.UnSet(&article{tags: []string{"go"}}, 3)
+
+	bs, err := mi.MatchMany(OpHasAny, "go", "db", "rust")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1}, bs.ToSlice())
+	assert.Equal(t, 2, len(mi.data))
+}
+
+func TestMultiValueIndex_Error(t *testing.T) {
+	mi := newArticleIndex()
+
+	_, err := mi.Match(OpEq, 5)
+	assert.ErrorIs(t, err, ErrInvalidIndexValue[string]{5})
+	_, err = mi.Match(OpLt, "go")
+	assert.ErrorIs(t, err, ErrInvalidOperation{MultiValueIndexName, OpLt})
+	_, err = mi.MatchMany(OpHasAny, "go", 5)
+	assert.ErrorIs(t, err, ErrInvalidIndexValue[string]{5})
+	_, err = mi.MatchMany(OpHasAll)
+	assert.ErrorIs(t, err, ErrInvalidArgsLen{defined: "1 or more", got: 0})
+	_, err = mi.MatchMany(OpBetween, "a", "b")
+	assert.ErrorIs(t, err, ErrInvalidOperation{MultiValueIndexName, OpBetween})
+}
+
+func TestMultiValueIndex_IndexList(t *testing.T) {
+	il := NewIndexList[article]()
+	assert.NoError(t, il.CreateIndex("tags", NewMultiValueIndex((*article).Tags)))
+
+	_, _ = il.Insert(article{title: "fali", tags: []string{"go", "db"}})
+	_, _ = il.Insert(article{title: "hello", tags: []string{"go"}})
+	_, _ = il.Insert(article{title: "sled", tags: []string{"rust", "db"}})
+
+	titles := func(query string) []string {
+		qr, err := il.QueryStr(query)
+		assert.NoError(t, err)
+
+		titles := make([]string, 0)
+		for _, a := range qr.Values() {
+			titles = append(titles, a.title)
+		}
+		return titles
+	}
+
+	assert.Equal(t, []string{"fali", "hello"}, titles(`tags = "go"`))
+	assert.Equal(t, []string{"fali", "hello", "sled"}, titles(`tags in ("go", "rust")`))
+	assert.Equal(t, []string{"fali", "sled"}, titles(`tags HAS ANY ("db")`))
+	assert.Equal(t, []string{"fali"}, titles(`tags has all ("db", "go")`))
+	assert.Equal(t, []string{"hello", "sled"}, titles(`not tags has all ("db", "go")`))
+
+	qr, err := il.Query(HasAll("tags", "rust", "db"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, qr.Count())
+
+	qr, err = il.Query(HasAny("tags", "java"))
+	assert.NoError(t, err)
+	assert.True(t, qr.IsEmpty())
+}
@@ -0,0 +1,282 @@
+package fali
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+)
+
+// OrderBy sorts the result by the values of the field: ORDER BY field [ASC|DESC]
+type OrderBy struct {
+	Field string
+	Desc  bool
+}
+
+// Statement is a Query with the Aggregations of SELECT and the clauses: ORDER BY, LIMIT and OFFSET
+//
+//	stmt, err := ParseStatement(`isnew = true ORDER BY age DESC, name LIMIT 10 OFFSET 20`)
+//	stmt, err := ParseStatement(`SELECT COUNT(*), MAX(age) WHERE isnew = true`)
+//	stmt, err := ParseStatement(`SELECT isnew, AVG(age) WHERE age > 5 GROUP BY isnew`)
+//	result, err := il.QueryStatement(stmt)
+type Statement struct {
+	Select  []Aggregation // the Aggregations are calculated before ORDER BY, LIMIT and OFFSET
+	GroupBy string        // calculates the Aggregations for every value of the field, the clauses are applied to the Groups
+	Query   Query32
+	OrderBy []OrderBy
+	Limit   int // 0 means: no limit
+	Offset  int
+}
+
+// ParseStatement parses the input with the optional SELECT and the optional clauses:
+// [SELECT aggregation, ... [WHERE]] condition GROUP BY field ORDER BY field [ASC|DESC], ... LIMIT n OFFSET m
+func ParseStatement(input string) (Statement, error) {
+	prepared, err := Prepare(input)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	return prepared.BindStatement()
+}
+
+// sortedWalker is implemented by Filters, which can walk the List-Indices in the order of the values (SortedIndex)
+type sortedWalker[LI Value] interface {
+	// from is the first value (nil: all values)
+	walkSorted(desc bool, from any, visit func(PostingList[LI]) bool)
+}
+
+// itemValuer is implemented by Filters, which can return the indexed value of an Item
+type itemValuer[OBJ any] interface {
+	itemValue(*OBJ) any
+}
+
+// applyClauses calculates the Aggregations (or the Groups), orders the result and cuts the page with the offset and the limit.
+// The Items are read from the FreeList without lock.
+func (q *QueryResult[T, ID]) applyClauses(stmt Statement, l FilterByName32, items *FreeList[T]) error {
+	switch {
+	case stmt.GroupBy != "":
+		return q.applyGroupClauses(stmt, l, items)
+	case len(stmt.Select) > 0:
+		aggregates, err := aggregate(q.bitSet, stmt.Select, l, items)
+		if err != nil {
+			return err
+		}
+		q.aggregates = aggregates
+	}
+
+	if len(stmt.OrderBy) == 0 {
+		if stmt.Limit == 0 && stmt.Offset == 0 {
+			return nil
+		}
+
+		page := NewBitSet[uint32]()
+		skip, count := stmt.Offset, 0
+		q.bitSet.Values(func(lidx uint32) bool {
+			if skip > 0 {
+				skip--
+				return true
+			}
+			page.Set(lidx)
+			count++
+			return stmt.Limit == 0 || count < stmt.Limit
+		})
+		q.bitSet = page
+		return nil
+	}
+
+	need := 0
+	if stmt.Limit > 0 {
+		need = stmt.Offset + stmt.Limit
+	}
+	ordered, err := orderLidxs(q.bitSet, stmt.OrderBy, need, l, items)
+	if err != nil {
+		return err
+	}
+
+	ordered = ordered[min(stmt.Offset, len(ordered)):]
+	if stmt.Limit > 0 && len(ordered) > stmt.Limit {
+		ordered = ordered[:stmt.Limit]
+	}
+
+	q.bitSet = NewBitSetFrom(ordered...)
+	q.ordered = ordered
+	q.orderBy = stmt.OrderBy
+	return nil
+}
+
+// applyGroupClauses calculates the Groups, ORDER BY, LIMIT and OFFSET are applied to the Groups (not to the Items).
+// The Groups can only be ordered by the GROUP BY field, the result contains the Items of the remaining Groups.
+func (q *QueryResult[T, ID]) applyGroupClauses(stmt Statement, l FilterByName32, items *FreeList[T]) error {
+	if err := checkGroupOrderBy(stmt.GroupBy, stmt.OrderBy); err != nil {
+		return err
+	}
+
+	buckets, err := groupBuckets(q.bitSet, stmt.GroupBy, l, items)
+	if err != nil {
+		return err
+	}
+
+	if len(stmt.OrderBy) > 0 && stmt.OrderBy[0].Desc {
+		slices.Reverse(buckets)
+	}
+	if stmt.Limit > 0 || stmt.Offset > 0 {
+		buckets = buckets[min(stmt.Offset, len(buckets)):]
+		if stmt.Limit > 0 && len(buckets) > stmt.Limit {
+			buckets = buckets[:stmt.Limit]
+		}
+
+		page := NewBitSet[uint32]()
+		for _, b := range buckets {
+			page.Or(b.lidxs)
+		}
+		q.bitSet = page
+	}
+
+	q.groups, err = aggregateGroups(buckets, stmt.Select, l, items)
+	return err
+}
+
+// checkGroupOrderBy checks, that ORDER BY contains only the GROUP BY field
+func checkGroupOrderBy(groupBy string, orderBy []OrderBy) error {
+	for _, o := range orderBy {
+		if o.Field != groupBy {
+			return ErrGroupOrderBy{field: o.Field, groupBy: groupBy}
+		}
+	}
+	return nil
+}
+
+// orderLidxs returns the List-Indices of the result in the order of the fields, need > 0 stops after need List-Indices.
+// If the first field has a SortedIndex, the sorted values are walked and intersected with the result
+// (Items, which are not in the SortedIndex, are at the end), otherwise the values of the Items are sorted.
+// Items with equal values keep the order of the List-Indices.
+func orderLidxs[T any](result *BitSet[uint32], orderBy []OrderBy, need int, l FilterByName32, items *FreeList[T]) ([]uint32, error) {
+	valuers, err := sortableFilters[T](l, orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	first, _ := l(orderBy[0].Field)
+	walker, ok := first.(sortedWalker[uint32])
+	if !ok {
+		ordered := result.ToSlice()
+		sortByValues(ordered, orderBy, valuers, items)
+		return ordered, nil
+	}
+
+	capacity := result.Count()
+	if need > 0 {
+		capacity = min(need, capacity)
+	}
+	ordered := make([]uint32, 0, capacity)
+	walker.walkSorted(orderBy[0].Desc, nil, func(p PostingList[uint32]) bool {
+		start := len(ordered)
+		p.Values(func(lidx uint32) bool {
+			if result.Contains(lidx) {
+				ordered = append(ordered, lidx)
+			}
+			return true
+		})
+
+		// the Items with the same value are sorted by the other fields
+		if len(orderBy) > 1 && len(ordered)-start > 1 {
+			sortByValues(ordered[start:], orderBy[1:], valuers[1:], items)
+		}
+		return need == 0 || len(ordered) < need
+	})
+
+	// the Items, which are not in the SortedIndex (without a value), are at the end
+	if len(ordered) < result.Count() && (need == 0 || len(ordered) < need) {
+		rest := result.Copy()
+		for _, lidx := range ordered {
+			rest.UnSet(lidx)
+		}
+
+		start := len(ordered)
+		rest.Values(func(lidx uint32) bool {
+			ordered = append(ordered, lidx)
+			return true
+		})
+		if len(orderBy) > 1 {
+			sortByValues(ordered[start:], orderBy[1:], valuers[1:], items)
+		}
+		if need > 0 && len(ordered) > need {
+			ordered = ordered[:need]
+		}
+	}
+
+	return ordered, nil
+}
+
+// sortableFilter returns the Filter of the field, if the indexed values are ordered
+func sortableFilter[T any](l FilterByName32, fieldName string) (itemValuer[T], error) {
+	filter, err := l(fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	valuer, ok := filter.(itemValuer[T])
+	vt, okType := filter.(ValueTyper)
+	if !ok || !okType || !isOrdered(vt.ValueType().Kind()) {
+		return nil, ErrNotSortable{fieldName}
+	}
+	return valuer, nil
+}
+
+// sortByValues sorts the List-Indices stable by the values of the Items
+func sortByValues[T any](lidxs []uint32, orderBy []OrderBy, valuers []itemValuer[T], items *FreeList[T]) {
+	type row struct {
+		lidx   uint32
+		values []any
+	}
+
+	rows := make([]row, len(lidxs))
+	for i, lidx := range lidxs {
+		item, _ := items.Get(int(lidx))
+		values := make([]any, len(valuers))
+		for f, valuer := range valuers {
+			values[f] = valuer.itemValue(&item)
+		}
+		rows[i] = row{lidx: lidx, values: values}
+	}
+
+	slices.SortStableFunc(rows, func(a, b row) int {
+		for f, o := range orderBy {
+			c := compareValues(a.values[f], b.values[f])
+			if o.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+
+	for i, r := range rows {
+		lidxs[i] = r.lidx
+	}
+}
+
+//go:inline
+func isOrdered(k reflect.Kind) bool { return isNumber(k) || k == reflect.String || k == reflect.Bool }
+
+// compareValues compares two values with the same ordered kind (see: isOrdered), false is less than true
+func compareValues(a, b any) int {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case va.CanInt():
+		return cmp.Compare(va.Int(), vb.Int())
+	case va.CanUint():
+		return cmp.Compare(va.Uint(), vb.Uint())
+	case va.CanFloat():
+		return cmp.Compare(va.Float(), vb.Float())
+	case va.Kind() == reflect.String:
+		return cmp.Compare(va.String(), vb.String())
+	case va.Kind() == reflect.Bool && va.Bool() != vb.Bool():
+		if vb.Bool() {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,181 @@
+package fali
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func names(cars []car) []string {
+	result := make([]string, len(cars))
+	for i, c := range cars {
+		result[i] = c.name
+	}
+	return result
+}
+
+func TestOrderBy_Parse(t *testing.T) {
+	stmt, err := ParseStatement(`age > 1 ORDER BY age DESC, name asc, isnew LIMIT 10 OFFSET 20`)
+	assert.NoError(t, err)
+	assert.Equal(t, []OrderBy{{Field: "age", Desc: true}, {Field: "name"}, {Field: "isnew"}}, stmt.OrderBy)
+	assert.Equal(t, 10, stmt.Limit)
+	assert.Equal(t, 20, stmt.Offset)
+
+	stmt, err = ParseStatement(`age > 1 offset 5`)
+	assert.NoError(t, err)
+	assert.Nil(t, stmt.OrderBy)
+	assert.Equal(t, 0, stmt.Limit)
+	assert.Equal(t, 5, stmt.Offset)
+
+	// the keywords are no reserved words
+	stmt, err = ParseStatement(`limit = 1 order by order`)
+	assert.NoError(t, err)
+	assert.Equal(t, []OrderBy{{Field: "order"}}, stmt.OrderBy)
+
+	// a Query can not express the clauses
+	_, err = Parse(`age > 1 order by age`)
+	assert.ErrorIs(t, err, ErrStatementClause{"ORDER BY"})
+	_, err = Parse(`age > 1 limit 3`)
+	assert.ErrorIs(t, err, ErrStatementClause{"LIMIT"})
+}
+
+func TestOrderBy_ParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   error
+	}{
+		{`age > 1 order age`, ErrUnexpectedToken{token: token{Op: OpIdent, Start: 14, End: 17}, expected: OpIdent}},
+		{`age > 1 order by`, ErrUnexpectedToken{token: token{Op: OpEOF, Start: 16, End: 16}, expected: OpIdent}},
+		{`age > 1 order by age limit`, ErrUnexpectedToken{token: token{Op: OpEOF, Start: 26, End: 26}, expected: OpNumber}},
+		{`age > 1 limit 0`, ErrInvalidClauseValue{clause: "LIMIT", value: int64(0)}},
+		{`age > 1 limit 1.5`, ErrInvalidClauseValue{clause: "LIMIT", value: 1.5}},
+		{`age > 1 offset -1`, ErrInvalidClauseValue{clause: "OFFSET", value: int64(-1)}},
+		{`age > 1 offset 1 limit 1`, ErrUnexpectedToken{token: token{Op: OpIdent, Start: 17, End: 22}}},
+		{`age > 1 order by age desc asc`, ErrUnexpectedToken{token: token{Op: OpIdent, Start: 26, End: 29}}},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseStatement(tt.query)
+		assert.ErrorIs(t, err, tt.err, tt.query)
+	}
+}
+
+func TestOrderBy_SortedIndex(t *testing.T) {
+	il := newPlannerList()
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{`isnew = true order by age desc limit 3`, []string{"T", "S", "R"}},
+		{`isnew = true order by age limit 3 offset 2`, []string{"C", "E", "F"}},
+		{`age < uint8(4) order by age desc`, []string{"D", "C", "B", "A"}},
+		{`age < uint8(4) order by age desc offset 3`, []string{"A"}},
+		{`age < uint8(4) order by age offset 4`, []string{}},
+		// without an ORDER BY, the order of the List-Indices
+		{`isnew = true limit 2 offset 2`, []string{"C", "E"}},
+		{`isnew = false limit 5`, []string{"D"}},
+		// a TrigramIndex sorts the values of the Items
+		{`age < uint8(6) order by name desc limit 2 offset 1`, []string{"E", "D"}},
+		{`age < uint8(3) order by id desc`, []string{"C", "B", "A"}},
+	}
+
+	for _, tt := range tests {
+		qr, err := il.QueryStr(tt.query)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.expected, names(qr.Values()), tt.query)
+		assert.Equal(t, len(tt.expected), qr.Count(), tt.query)
+	}
+}
+
+func TestOrderBy_MoreFields(t *testing.T) {
+	il := NewIndexListWithID((*car).Name)
+	_ = il.CreateIndex("isnew", NewMapIndex((*car).IsNew))
+	_ = il.CreateIndex("age", NewSortedIndex((*car).Age))
+
+	for i := range 8 {
+		_, _ = il.Insert(car{name: string(rune('A' + i)), age: uint8(i % 3), isNew: i%2 == 0})
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		// the equal ages keep the order of the List-Indices
+		{`age < uint8(9) order by age`, []string{"A", "D", "G", "B", "E", "H", "C", "F"}},
+		{`age < uint8(9) order by age desc`, []string{"C", "F", "B", "E", "H", "A", "D", "G"}},
+		{`age < uint8(9) order by age, id desc limit 4`, []string{"G", "D", "A", "H"}},
+		{`age < uint8(9) order by age desc, isnew desc limit 5`, []string{"C", "F", "E", "B", "H"}},
+		// without SortedIndex for the first field
+		{`age < uint8(9) order by isnew, age desc, id`, []string{"F", "B", "H", "D", "C", "E", "A", "G"}},
+	}
+
+	for _, tt := range tests {
+		qr, err := il.QueryStr(tt.query)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.expected, names(qr.Values()), tt.query)
+	}
+}
+
+func TestOrderBy_Errors(t *testing.T) {
+	il := NewIndexList[article]()
+	assert.NoError(t, il.CreateIndex("tags", NewMultiValueIndex((*article).Tags)))
+	_, _ = il.Insert(article{title: "fali", tags: []string{"go", "db"}})
+
+	_, err := il.QueryStr(`tags = "go" order by tags`)
+	assert.ErrorIs(t, err, ErrNotSortable{"tags"})
+
+	_, err = il.QueryStr(`tags = "go" order by title`)
+	assert.ErrorIs(t, err, ErrInvalidIndexdName{"title"})
+}
+
+func TestOrderBy_Snapshot(t *testing.T) {
+	il := newPlannerList()
+	snap, err := il.Snapshot()
+	assert.NoError(t, err)
+
+	_, _ = il.Insert(car{name: "Z", age: 99, isNew: true})
+
+	qr, err := snap.QueryStr(`isnew = true order by age desc limit 2`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"T", "S"}, names(qr.Values()))
+}
+
+func TestQueryResult_SortedBy(t *testing.T) {
+	il := newPlannerList()
+
+	qr, err := il.QueryStr(`isnew = true and age < uint8(6)`)
+	assert.NoError(t, err)
+
+	sorted := func(fieldName string, desc bool, max int) []string {
+		seq, err := qr.SortedBy(fieldName, desc)
+		assert.NoError(t, err)
+
+		result := make([]string, 0)
+		for c := range seq {
+			if len(result) == max {
+				break
+			}
+			result = append(result, c.name)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"A", "B", "C", "E", "F"}, sorted("age", false, 10))
+	assert.Equal(t, []string{"F", "E"}, sorted("age", true, 2))
+	assert.Equal(t, []string{"A"}, sorted("age", false, 1))
+	// the TrigramIndex sorts the values of the Items
+	assert.Equal(t, []string{"F", "E", "C"}, sorted("name", true, 3))
+
+	// the list is not locked after the iteration
+	_, _ = il.Insert(car{name: "Z", age: 1, isNew: true})
+
+	_, err = qr.SortedBy("unknown", false)
+	assert.ErrorIs(t, err, ErrInvalidIndexdName{"unknown"})
+
+	snap, err := il.Snapshot()
+	assert.NoError(t, err)
+	qr, err = snap.QueryStr(`age < uint8(2)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"B", "Z", "A"}, sorted("age", true, 10))
+}
@@ -0,0 +1,289 @@
+package fali
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// Page is a page of Items with an opaque cursor for the next page.
+// The cursor saves the position after the last Item (the List-Index and the values of the sorted fields),
+// so the next page is stable, also if Items are inserted or removed between the calls.
+type Page[T any] struct {
+	Items []T
+	Next  string // the cursor for the next page, empty on the last page
+	Total int    // the number of Items in the result
+}
+
+// Page returns max limit Items after the cursor, an empty cursor starts with the first Item.
+// The Items are in the order of the result (ORDER BY or the List-Indices).
+//
+//	page, err := qr.Page("", 10)
+//	// the next request, with a new QueryResult
+//	page, err = qr.Page(page.Next, 10)
+func (q *QueryResult[T, ID]) Page(cursor string, limit int) (Page[T], error) {
+	if limit <= 0 {
+		return Page[T]{}, ErrInvalidClauseValue{clause: "LIMIT", value: limit}
+	}
+
+	c, err := decodeCursor(cursor)
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	fields := make([]string, len(q.orderBy))
+	for i, o := range q.orderBy {
+		fields[i] = o.Field
+	}
+	if cursor != "" && !slices.Equal(c.fields, fields) {
+		return Page[T]{}, ErrInvalidCursor{cursor}
+	}
+
+	page := Page[T]{Total: q.Count()}
+	q.readItems(func(items *FreeList[T]) {
+		var valuers []itemValuer[T]
+		if valuers, err = sortableFilters[T](q.filterByName(), q.orderBy); err != nil {
+			return
+		}
+
+		lidxs := make([]uint32, 0, limit+1)
+		if q.ordered != nil {
+			start := 0
+			if cursor != "" {
+				if start, err = q.orderedPosition(items, c, valuers); err != nil {
+					err = ErrInvalidCursor{cursor}
+					return
+				}
+			}
+			lidxs = append(lidxs, q.ordered[start:min(start+limit+1, len(q.ordered))]...)
+		} else if cursor == "" || c.lidx < math.MaxUint32 {
+			var from uint32
+			if cursor != "" {
+				from = c.lidx + 1
+			}
+			q.bitSet.Range(from, math.MaxUint32, func(lidx uint32) bool {
+				lidxs = append(lidxs, lidx)
+				return len(lidxs) <= limit
+			})
+		}
+
+		page.Items = make([]T, 0, min(limit, len(lidxs)))
+		for i, lidx := range lidxs {
+			if i == limit {
+				page.Next = newPageCursor(lidxs[limit-1], &page.Items[limit-1], fields, valuers).encode()
+				break
+			}
+			item, _ := items.Get(int(lidx))
+			page.Items = append(page.Items, item)
+		}
+	})
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	return page, nil
+}
+
+// orderedPosition returns the position of the first Item of the ordered result after the cursor (binary search)
+func (q *QueryResult[T, ID]) orderedPosition(items *FreeList[T], c pageCursor, valuers []itemValuer[T]) (int, error) {
+	keys, err := parseCursorKeys(c, valuers)
+	if err != nil {
+		return 0, err
+	}
+
+	values := make([]any, len(valuers))
+	return sort.Search(len(q.ordered), func(i int) bool {
+		item, _ := items.Get(int(q.ordered[i]))
+		for f, valuer := range valuers {
+			values[f] = valuer.itemValue(&item)
+		}
+		return c.before(q.ordered[i], values, keys, q.orderBy)
+	}), nil
+}
+
+// PageBy returns max limit Items after the cursor, sorted by the values of the field (see: SortedBy).
+// An empty cursor starts with the first Item.
+// With a SortedIndex, the sorted values are walked from the value of the cursor.
+func (q *QueryResult[T, ID]) PageBy(fieldName string, desc bool, cursor string, limit int) (Page[T], error) {
+	if limit <= 0 {
+		return Page[T]{}, ErrInvalidClauseValue{clause: "LIMIT", value: limit}
+	}
+
+	c, err := decodeCursor(cursor)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	fields := []string{fieldName}
+	if cursor != "" && !slices.Equal(c.fields, fields) {
+		return Page[T]{}, ErrInvalidCursor{cursor}
+	}
+
+	page := Page[T]{Items: make([]T, 0, limit), Total: q.Count()}
+	q.readItems(func(items *FreeList[T]) {
+		orderBy := []OrderBy{{Field: fieldName, Desc: desc}}
+		var valuers []itemValuer[T]
+		if valuers, err = sortableFilters[T](q.filterByName(), orderBy); err != nil {
+			return
+		}
+
+		var keys []any
+		if cursor != "" {
+			if keys, err = parseCursorKeys(c, valuers); err != nil {
+				err = ErrInvalidCursor{cursor}
+				return
+			}
+		}
+
+		// seek to the value of the cursor
+		var from any
+		if cursor != "" {
+			from = keys[0]
+		}
+
+		var lastLidx uint32
+		var last T
+		q.walkSortedBy(items, fieldName, desc, from, func(lidx uint32, item T) bool {
+			if cursor != "" && !c.before(lidx, []any{valuers[0].itemValue(&item)}, keys, orderBy) {
+				return true
+			}
+
+			if len(page.Items) == limit {
+				page.Next = newPageCursor(lastLidx, &last, fields, valuers).encode()
+				return false
+			}
+			page.Items = append(page.Items, item)
+			lastLidx, last = lidx, item
+			return true
+		})
+	})
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	return page, nil
+}
+
+// sortableFilters returns the itemValuers for the fields (see: sortableFilter)
+func sortableFilters[T any](l FilterByName32, orderBy []OrderBy) ([]itemValuer[T], error) {
+	valuers := make([]itemValuer[T], len(orderBy))
+	for i, o := range orderBy {
+		valuer, err := sortableFilter[T](l, o.Field)
+		if err != nil {
+			return nil, err
+		}
+		valuers[i] = valuer
+	}
+	return valuers, nil
+}
+
+// pageCursor is the position of the last Item of a page: the List-Index and the values of the sorted fields
+type pageCursor struct {
+	lidx   uint32
+	fields []string
+	keys   []string // the values of the fields, formatted with fmt.Sprint
+}
+
+func newPageCursor[T any](lidx uint32, item *T, fields []string, valuers []itemValuer[T]) pageCursor {
+	c := pageCursor{lidx: lidx, fields: fields, keys: make([]string, len(valuers))}
+	for i, valuer := range valuers {
+		c.keys[i] = fmt.Sprint(valuer.itemValue(item))
+	}
+	return c
+}
+
+// encode the cursor as base64 string: lidx and per field: field, key
+func (c pageCursor) encode() string {
+	e := &encoder{}
+	e.uvarint(uint64(c.lidx))
+	for i, field := range c.fields {
+		e.string(field)
+		e.string(c.keys[i])
+	}
+	return base64.RawURLEncoding.EncodeToString(e.buf.Bytes())
+}
+
+// decodeCursor decodes the cursor, an empty cursor is the start
+func decodeCursor(cursor string) (pageCursor, error) {
+	if cursor == "" {
+		return pageCursor{}, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return pageCursor{}, ErrInvalidCursor{cursor}
+	}
+
+	d := &decoder{data: b}
+	lidx := d.uvarint()
+	if lidx > math.MaxUint32 {
+		d.fail("invalid List-Index")
+	}
+
+	c := pageCursor{lidx: uint32(lidx)}
+	for d.err == nil && len(d.data) > 0 {
+		c.fields = append(c.fields, d.string())
+		c.keys = append(c.keys, d.string())
+	}
+	if d.err != nil {
+		return pageCursor{}, ErrInvalidCursor{cursor}
+	}
+	return c, nil
+}
+
+// parseCursorKeys parses the keys of the cursor to the value types of the fields
+func parseCursorKeys[T any](c pageCursor, valuers []itemValuer[T]) ([]any, error) {
+	keys := make([]any, len(c.keys))
+	for i, key := range c.keys {
+		value, err := parseCursorKey(key, valuers[i].(ValueTyper).ValueType())
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = value
+	}
+	return keys, nil
+}
+
+// before checks, is the cursor before the Item with the List-Index and the values.
+// Items with equal values are in the order of the List-Indices.
+func (c pageCursor) before(lidx uint32, values, keys []any, orderBy []OrderBy) bool {
+	for i, o := range orderBy {
+		order := compareValues(values[i], keys[i])
+		if o.Desc {
+			order = -order
+		}
+		if order != 0 {
+			return order > 0
+		}
+	}
+	return lidx > c.lidx
+}
+
+// parseCursorKey parses the value of the cursor (formatted with fmt.Sprint) to the type
+func parseCursorKey(key string, typ reflect.Type) (any, error) {
+	var value any
+	var err error
+
+	switch k := typ.Kind(); {
+	case k >= reflect.Int && k <= reflect.Int64:
+		value, err = strconv.ParseInt(key, 10, typ.Bits())
+	case k >= reflect.Uint && k <= reflect.Uintptr:
+		value, err = strconv.ParseUint(key, 10, typ.Bits())
+	case k == reflect.Float32 || k == reflect.Float64:
+		value, err = strconv.ParseFloat(key, typ.Bits())
+	case k == reflect.Bool:
+		value, err = strconv.ParseBool(key)
+	case k == reflect.String:
+		value = key
+	default:
+		return nil, ErrNotSortable{typ.String()}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return reflect.ValueOf(value).Convert(typ).Interface(), nil
+}
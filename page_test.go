@@ -0,0 +1,168 @@
+package fali
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPage_Cursor(t *testing.T) {
+	il := newPlannerList()
+
+	query := func() QueryResult[car, string] {
+		qr, err := il.QueryStr(`isnew = true and age < uint8(8)`)
+		assert.NoError(t, err)
+		return qr
+	}
+
+	qr := query()
+	page, err := qr.Page("", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A", "B", "C"}, names(page.Items))
+	assert.Equal(t, 7, page.Total)
+	assert.NotEmpty(t, page.Next)
+
+	// insert a new Item in the first page
+	_, _ = il.Insert(car{name: "Z", age: 0, isNew: true})
+
+	qr = query()
+	page, err = qr.Page(page.Next, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"E", "F", "G"}, names(page.Items))
+	assert.Equal(t, 8, page.Total)
+
+	page, err = qr.Page(page.Next, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"H", "Z"}, names(page.Items))
+	assert.Empty(t, page.Next)
+
+	// an exact page has no next page
+	page, err = qr.Page("", 8)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 8)
+	assert.Empty(t, page.Next)
+}
+
+func TestPage_CursorOrdered(t *testing.T) {
+	il := newPlannerList()
+
+	qr, err := il.QueryStr(`age < uint8(5) order by age desc`)
+	assert.NoError(t, err)
+
+	page, err := qr.Page("", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"E", "D"}, names(page.Items))
+
+	page, err = qr.Page(page.Next, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"C", "B"}, names(page.Items))
+
+	page, err = qr.Page(page.Next, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A"}, names(page.Items))
+	assert.Empty(t, page.Next)
+}
+
+func TestPage_CursorOrderedRemoved(t *testing.T) {
+	il := newPlannerList()
+	query := func() QueryResult[car, string] {
+		qr, err := il.QueryStr(`age < uint8(6) order by isnew, age desc`)
+		assert.NoError(t, err)
+		return qr
+	}
+
+	qr := query()
+	page, err := qr.Page("", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"D", "F", "E"}, names(page.Items))
+
+	// the last Item of the page is removed, the next page starts after the values of the cursor
+	_, _ = il.Remove("E")
+	_, _ = il.Insert(car{name: "Z", age: 3, isNew: true})
+
+	qr = query()
+	page, err = qr.Page(page.Next, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Z", "C", "B"}, names(page.Items))
+	cursor := page.Next
+
+	page, err = qr.Page(page.Next, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A"}, names(page.Items))
+	assert.Empty(t, page.Next)
+
+	// the cursor of an other ORDER BY
+	other, err := il.QueryStr(`age < uint8(6) order by age`)
+	assert.NoError(t, err)
+	_, err = other.Page(cursor, 3)
+	assert.ErrorIs(t, err, ErrInvalidCursor{cursor})
+}
+
+func TestPage_PageBy(t *testing.T) {
+	il := NewIndexListWithID((*car).Name)
+	_ = il.CreateIndex("age", NewSortedIndex((*car).Age))
+	_ = il.CreateIndex("name", NewTrigramIndex((*car).Name))
+	for i := range 8 {
+		_, _ = il.Insert(car{name: string(rune('A' + i)), age: uint8(i % 3)})
+	}
+	// ages: 0: A, D, G; 1: B, E, H; 2: C, F
+
+	query := func() QueryResult[car, string] {
+		qr, err := il.QueryStr(`age < uint8(9)`)
+		assert.NoError(t, err)
+		return qr
+	}
+
+	qr := query()
+	page, err := qr.PageBy("age", true, "", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"C", "F", "B"}, names(page.Items))
+
+	// the cursor is on the equal age 1 of: B
+	_, _ = il.Insert(car{name: "X", age: 2})
+	_, _ = il.Insert(car{name: "Y", age: 1})
+
+	qr = query()
+	page, err = qr.PageBy("age", true, page.Next, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"E", "H", "Y"}, names(page.Items))
+
+	page, err = qr.PageBy("age", true, page.Next, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A", "D", "G"}, names(page.Items))
+	assert.Empty(t, page.Next)
+
+	// without SortedIndex
+	page, err = qr.PageBy("name", false, "", 9)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A", "B", "C", "D", "E", "F", "G", "H", "X"}, names(page.Items))
+	page, err = qr.PageBy("name", false, page.Next, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Y"}, names(page.Items))
+}
+
+func TestPage_Errors(t *testing.T) {
+	il := newPlannerList()
+	qr, err := il.QueryStr(`age < uint8(5)`)
+	assert.NoError(t, err)
+
+	_, err = qr.Page("", 0)
+	assert.ErrorIs(t, err, ErrInvalidClauseValue{clause: "LIMIT", value: 0})
+	_, err = qr.Page("%%%", 2)
+	assert.ErrorIs(t, err, ErrInvalidCursor{"%%%"})
+
+	page, err := qr.PageBy("age", false, "", 2)
+	assert.NoError(t, err)
+	// the cursor of PageBy can not used for Page or for an other field
+	_, err = qr.Page(page.Next, 2)
+	assert.ErrorIs(t, err, ErrInvalidCursor{page.Next})
+	_, err = qr.PageBy("name", false, page.Next, 2)
+	assert.ErrorIs(t, err, ErrInvalidCursor{page.Next})
+
+	_, err = qr.PageBy("unknown", false, "", 2)
+	assert.ErrorIs(t, err, ErrInvalidIndexdName{"unknown"})
+
+	cursor := pageCursor{lidx: 1, fields: []string{"age"}, keys: []string{"300"}}.encode()
+	_, err = qr.PageBy("age", false, cursor, 2)
+	assert.ErrorIs(t, err, ErrInvalidCursor{cursor})
+}
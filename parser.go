@@ -1,9 +1,10 @@
-package main
+package fali
 
 import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 )
 
 type ExprKind uint8
@@ -47,11 +48,92 @@ type TermManyExpr struct {
 
 func (e TermManyExpr) kind() ExprKind { return ExprTerm }
 
+// TermCompositeExpr is an AND of equality terms on different fields: Fields[0] = Values[0] AND ...
+type TermCompositeExpr struct {
+	Fields []string
+	Values []any
+}
+
+func (e TermCompositeExpr) kind() ExprKind { return ExprTerm }
+
+func (k ExprKind) String() string {
+	switch k {
+	case ExprTerm:
+		return "TERM"
+	case ExprOr:
+		return "OR"
+	case ExprAnd:
+		return "AND"
+	case ExprAndNot:
+		return "AND NOT"
+	case ExprNot:
+		return "NOT"
+	default:
+		return fmt.Sprintf("UNKNOWN: %d", k)
+	}
+}
+
+func (e BinaryExpr) String() string { return fmt.Sprintf("(%s %s %s)", e.Left, e.Op, e.Right) }
+func (e NotExpr) String() string    { return fmt.Sprintf("NOT %s", e.Child) }
+func (e TermExpr) String() string {
+	return fmt.Sprintf("%s %s %s", e.Field, e.Op, formatValue(e.Value))
+}
+
+func (e TermManyExpr) String() string {
+	values := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		values[i] = formatValue(v)
+	}
+
+	if e.Op == OpBetween && len(values) == 2 {
+		// the interval notation: ]min, max[ excludes the bounds
+		open, closed := "]", "["
+		if e.MinIncl {
+			open = "["
+		}
+		if e.MaxIncl {
+			closed = "]"
+		}
+		return fmt.Sprintf("%s %s %s%s, %s%s", e.Field, e.Op, open, values[0], values[1], closed)
+	}
+	return fmt.Sprintf("%s %s (%s)", e.Field, e.Op, strings.Join(values, ", "))
+}
+
+func (e TermCompositeExpr) String() string {
+	values := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		values[i] = formatValue(v)
+	}
+	return fmt.Sprintf("(%s) = (%s)", strings.Join(e.Fields, ", "), strings.Join(values, ", "))
+}
+
+// formatValue formats the value in the query syntax: "text", 42, 4.2, true or with cast: uint8(7)
+func formatValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		return strconv.Quote(val)
+	case int64, bool:
+		return fmt.Sprint(val)
+	case placeholder:
+		return val.String()
+	case float64:
+		f := strconv.FormatFloat(val, 'f', -1, 64)
+		if !strings.Contains(f, ".") {
+			f += ".0"
+		}
+		return f
+	default:
+		return fmt.Sprintf("%T(%v)", val, val)
+	}
+}
+
 // Parser impl starts
 type parser struct {
 	input string
 	lex   lexer
 	cur   token
+	// the number of positional placeholders: ?
+	positional int
 }
 
 func optimize(e Expr) Expr {
@@ -153,54 +235,251 @@ func optimize(e Expr) Expr {
 	}
 }
 
-func compile(e Expr) Query32 {
-	switch n := e.(type) {
-	case TermExpr:
-		return match[uint32](n.Field, n.Op, n.Value)
+// compile the Expression to a Query, with the query planner (see: planExpr)
+func compile(e Expr) Query32 { return planExpr(e, false).query }
 
-	case NotExpr:
-		return Not(compile(n.Child))
+// Parse parses, optimizes and compiles the input to a Query.
+// Only a parsed query is planned: the AND terms are executed ordered by the estimate (see: planExpr),
+// a Query which is combined with And or Or is executed in the written order.
+// For queries with placeholders, use: Prepare and Bind.
+func Parse(input string) (Query32, error) {
+	prepared, err := Prepare(input)
+	if err != nil {
+		return nil, err
+	}
 
-	case BinaryExpr:
-		left := compile(n.Left)
-		right := compile(n.Right)
+	return prepared.Bind()
+}
 
-		switch n.Op {
-		case ExprAnd:
-			return And(left, right)
-		case ExprOr:
-			return Or(left, right)
-		case ExprAndNot:
-			// This calls the new high-performance AndNot we discussed!
-			return AndNot(left, right)
-		}
-	case TermManyExpr:
-		return matchMany[uint32](n.Field, n.Op, n.Values...)
-	}
+// parsed is the result of the parser: the AST of the condition and the clauses ORDER BY, LIMIT and OFFSET
+type parsed struct {
+	selects []Aggregation
+	// the fields in the SELECT list, which are not Aggregations, must be the GROUP BY field
+	selectFields []token
+	groupBy      string
+	// nil for a SELECT without a condition
+	ast Expr
+	// the number of positional placeholders
+	positional int
+	orderBy    []OrderBy
+	limit      int
+	offset     int
+}
 
-	panic(fmt.Sprintf("NOT supported Expression in compile: %T", e))
+// clause returns the name of the first clause or an empty string, if there is no clause
+func (p parsed) clause() string {
+	switch {
+	case len(p.selects) > 0 || len(p.selectFields) > 0:
+		return "SELECT"
+	case p.groupBy != "":
+		return "GROUP BY"
+	case len(p.orderBy) > 0:
+		return "ORDER BY"
+	case p.limit > 0:
+		return "LIMIT"
+	case p.offset > 0:
+		return "OFFSET"
+	}
+	return ""
 }
 
-func Parse(input string) (Query32, error) {
+// parse returns the AST of the input (without optimizing), the number of positional placeholders and the clauses
+func parse(input string) (parsed, error) {
 	p := parser{input: input, lex: lexer{input: input, pos: 0}}
 	p.next()
-	ast, err := p.parseOr()
-	if err != nil {
-		return nil, err
+
+	var result parsed
+	// SELECT is an identifier, if it is followed by an operator, it is a field name
+	if p.isKeyword("select") && p.peek().Op == OpIdent {
+		if err := p.parseSelect(&result); err != nil {
+			return parsed{}, err
+		}
+
+		if p.isKeyword("where") {
+			p.next()
+			ast, err := p.parseOr()
+			if err != nil {
+				return parsed{}, err
+			}
+			result.ast = ast
+		}
+	} else {
+		ast, err := p.parseOr()
+		if err != nil {
+			return parsed{}, err
+		}
+		result.ast = ast
+	}
+
+	if err := p.parseClauses(&result); err != nil {
+		return parsed{}, err
 	}
 	if p.cur.Op != OpEOF {
-		return nil, ErrUnexpectedToken{token: p.cur}
+		return parsed{}, ErrUnexpectedToken{token: p.cur}
+	}
+	for _, field := range result.selectFields {
+		if name := input[field.Start:field.End]; name != result.groupBy {
+			return parsed{}, ErrInvalidAggregation{name}
+		}
+	}
+	result.positional = p.positional
+	return result, nil
+}
+
+// parseSelect parses the Aggregations: SELECT COUNT(*), SUM(field), MIN(field), MAX(field), AVG(field)
+// and the GROUP BY field: SELECT field, COUNT(*) ... GROUP BY field
+func (p *parser) parseSelect(result *parsed) error {
+	p.next() // consume SELECT
+
+	for {
+		if p.cur.Op != OpIdent {
+			return ErrUnexpectedToken{token: p.cur, expected: OpIdent}
+		}
+		nameToken := p.cur
+		name := p.input[p.cur.Start:p.cur.End]
+		p.next()
+
+		if p.cur.Op != OpLParen {
+			result.selectFields = append(result.selectFields, nameToken)
+		} else {
+			fn, ok := aggFuncByName(name)
+			if !ok {
+				return ErrInvalidAggregation{name}
+			}
+			p.next()
+
+			agg := Aggregation{Func: fn}
+			switch {
+			case p.cur.Op == OpAsterisk && fn == AggCount:
+			case p.cur.Op == OpIdent:
+				agg.Field = p.input[p.cur.Start:p.cur.End]
+			default:
+				return ErrUnexpectedToken{token: p.cur, expected: OpIdent}
+			}
+			p.next()
+
+			if p.cur.Op != OpRParen {
+				return ErrUnexpectedToken{token: p.cur, expected: OpRParen}
+			}
+			p.next()
+			result.selects = append(result.selects, agg)
+		}
+
+		if p.cur.Op != OpComma {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// parseClauses parses the optional clauses after the condition:
+// GROUP BY field ORDER BY field [ASC|DESC], ... LIMIT n OFFSET m
+// The keywords are identifiers for the lexer, so they can be used as field names too.
+func (p *parser) parseClauses(result *parsed) error {
+	if p.isKeyword("group") {
+		p.next()
+		if !p.isKeyword("by") {
+			return ErrUnexpectedToken{token: p.cur, expected: OpIdent}
+		}
+		p.next()
+
+		if p.cur.Op != OpIdent {
+			return ErrUnexpectedToken{token: p.cur, expected: OpIdent}
+		}
+		result.groupBy = p.input[p.cur.Start:p.cur.End]
+		p.next()
+	}
+
+	if p.isKeyword("order") {
+		p.next()
+		if !p.isKeyword("by") {
+			return ErrUnexpectedToken{token: p.cur, expected: OpIdent}
+		}
+		p.next()
+
+		for {
+			if p.cur.Op != OpIdent {
+				return ErrUnexpectedToken{token: p.cur, expected: OpIdent}
+			}
+			order := OrderBy{Field: p.input[p.cur.Start:p.cur.End]}
+			p.next()
+
+			switch {
+			case p.isKeyword("asc"):
+				p.next()
+			case p.isKeyword("desc"):
+				order.Desc = true
+				p.next()
+			}
+			result.orderBy = append(result.orderBy, order)
+
+			if p.cur.Op != OpComma {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if result.groupBy != "" {
+		if err := checkGroupOrderBy(result.groupBy, result.orderBy); err != nil {
+			return err
+		}
+	}
+
+	if p.isKeyword("limit") {
+		p.next()
+		limit, err := p.parseClauseNumber("LIMIT", 1)
+		if err != nil {
+			return err
+		}
+		result.limit = limit
 	}
 
-	optAst := optimize(ast)
-	query := compile(optAst)
+	if p.isKeyword("offset") {
+		p.next()
+		offset, err := p.parseClauseNumber("OFFSET", 0)
+		if err != nil {
+			return err
+		}
+		result.offset = offset
+	}
 
-	return query, nil
+	return nil
+}
+
+// isKeyword checks, is the current token the identifier: keyword (case insensitive)
+//
+//go:inline
+func (p *parser) isKeyword(keyword string) bool {
+	return p.cur.Op == OpIdent && strings.EqualFold(p.input[p.cur.Start:p.cur.End], keyword)
+}
+
+// parseClauseNumber parses the integer of LIMIT or OFFSET, which must be >= min
+func (p *parser) parseClauseNumber(clause string, min int64) (int, error) {
+	if p.cur.Op != OpNumber {
+		return 0, ErrUnexpectedToken{token: p.cur, expected: OpNumber}
+	}
+	num, err := p.parseNumber()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := num.(int64)
+	if !ok || n < min || n > math.MaxInt32 {
+		return 0, ErrInvalidClauseValue{clause: clause, value: num}
+	}
+	p.next()
+	return int(n), nil
 }
 
 //go:inline
 func (p *parser) next() { p.cur = p.lex.nextToken() }
 
+// peek returns the next token, without consuming it
+func (p *parser) peek() token {
+	lex := p.lex
+	return lex.nextToken()
+}
+
 func (p *parser) parseOr() (Expr, error) {
 	// the rule: AND before OR
 	left, err := p.parseAnd()
@@ -275,16 +554,26 @@ func (p *parser) parseCondition() (Expr, error) {
 			return nil, err
 		}
 		return NotExpr{Child: TermExpr{Field: field, Op: OpEq, Value: val}}, nil
-	case OpLt, OpLe, OpGt, OpGe, OpEq:
+	case OpLt, OpLe, OpGt, OpGe, OpEq, OpContains:
 		val, err := p.parseValue()
 		if err != nil {
 			return nil, err
 		}
 		return TermExpr{Field: field, Op: tokenOp, Value: val}, nil
 	case OpBetween:
-		if p.cur.Op != OpLParen {
+		// between(min, max) includes the bounds
+		// the interval notation: [ or ] defines, is the bound included or excluded:
+		// between [min, max] or ]min, max[ or [min, max[ or ]min, max]
+		var minIncl, maxIncl bool
+		switch p.cur.Op {
+		case OpLParen, OpLBracket:
+			minIncl = true
+		case OpRBracket:
+			minIncl = false
+		default:
 			return nil, ErrUnexpectedToken{token: p.cur, expected: OpLParen}
 		}
+		legacy := p.cur.Op == OpLParen
 		p.next()
 		min, err := p.parseValue()
 		if err != nil {
@@ -298,17 +587,26 @@ func (p *parser) parseCondition() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		if p.cur.Op != OpRParen {
+		switch {
+		case legacy && p.cur.Op == OpRParen:
+			maxIncl = true
+		case legacy:
 			return nil, ErrUnexpectedToken{token: p.cur, expected: OpRParen}
+		case p.cur.Op == OpRBracket:
+			maxIncl = true
+		case p.cur.Op == OpLBracket:
+			maxIncl = false
+		default:
+			return nil, ErrUnexpectedToken{token: p.cur, expected: OpRBracket}
 		}
 		p.next()
-		return TermManyExpr{Field: field, Op: OpBetween, Values: []any{min, max}}, nil
-	case OpIn:
+		return TermManyExpr{Field: field, Op: OpBetween, Values: []any{min, max}, MinIncl: minIncl, MaxIncl: maxIncl}, nil
+	case OpIn, OpHasAny, OpHasAll:
 		values, err := p.parseValueList()
 		if err != nil {
 			return nil, err
 		}
-		return TermManyExpr{Field: field, Op: OpIn, Values: values}, nil
+		return TermManyExpr{Field: field, Op: tokenOp, Values: values}, nil
 	// case tokIdent:
 	// maybe relations like startswith
 	default:
@@ -360,6 +658,13 @@ func (p *parser) parseValue() (any, error) {
 			return nil, err
 		}
 		val = boolean
+	case OpPlaceholder:
+		if name := p.input[p.cur.Start+1 : p.cur.End]; name != "" {
+			val = placeholder{name: name}
+		} else {
+			val = placeholder{index: p.positional}
+			p.positional++
+		}
 	case OpIdent: // Type casting logic: uint8(10)
 		typeName := p.input[p.cur.Start:p.cur.End]
 		p.next()
@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"errors"
@@ -84,6 +84,16 @@ func TestParser_Base(t *testing.T) {
 
 		{query: `price between(1.2, 3.0)`, expected: []uint32{0, 1}},
 		{query: `price between(3.0, 1.2)`, expected: []uint32{}},
+		{query: `price between [1.2, 3.0]`, expected: []uint32{0, 1}},
+		{query: `price between ]1.2, 3.0]`, expected: []uint32{0}},
+		{query: `price between [1.2, 3.0[`, expected: []uint32{1}},
+		{query: `price between ]1.2, 3.0[`, expected: []uint32{}},
+		{query: `price between ]1.2, 1.2]`, expected: []uint32{}},
+		// RULE: A > X AND A < Y --> BETWEEN ]X, Y[
+		{query: `price > 1.2 and price < 3.0`, expected: []uint32{}},
+		{query: `price >= 1.2 and price < 3.0`, expected: []uint32{1}},
+		{query: `price > 1.2 and price <= 3.0`, expected: []uint32{0}},
+		{query: `price >= 1.2 and price <= 3.0`, expected: []uint32{0, 1}},
 
 		{query: `price in(1.2, 3.0)`, expected: []uint32{0, 1}},
 		{query: `price in(3.0, 1.2)`, expected: []uint32{0, 1}},
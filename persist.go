@@ -0,0 +1,577 @@
+package fali
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"reflect"
+)
+
+// The file format of a saved IndexList (all numbers are little-endian or varints):
+//
+//	magic    [4]byte "FALI"
+//	version  uint16
+//	body     FreeList slots, ID-Index, Indices
+//	checksum uint32 (CRC32 IEEE over magic, version and body)
+const (
+	persistMagic   = "FALI"
+	persistVersion = uint16(1)
+
+	headerSize   = len(persistMagic) + 2
+	checksumSize = 4
+)
+
+// saved kinds of an Index
+const (
+	// the Index content is saved
+	indexKindPersisted byte = 1
+	// the Index is rebuild from the Items, by loading
+	indexKindRebuild byte = 2
+)
+
+// errNotPersistable is returned by an Index, which can not save the content
+var errNotPersistable = errors.New("index is not persistable")
+
+// Codec encodes and decodes the Items for persisting.
+type Codec[T any] interface {
+	Marshal(T) ([]byte, error)
+	Unmarshal([]byte) (T, error)
+}
+
+// GobCodec is the default Codec, which use the encoding/gob package.
+// Hint: gob supports only exported fields!
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Marshal(item T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&item); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Unmarshal(data []byte) (T, error) {
+	var item T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&item)
+	return item, err
+}
+
+// persister is implemented by Indices, which can save and restore their content.
+type persister interface {
+	persist(e *encoder) error
+	restore(d *decoder) error
+}
+
+// SetCodec sets the Codec for encoding and decoding the Items, the default is the GobCodec.
+func (l *IndexList[T, ID]) SetCodec(codec Codec[T]) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.codec = codec
+}
+
+//go:inline
+func (l *IndexList[T, ID]) itemCodec() Codec[T] {
+	if l.codec == nil {
+		return GobCodec[T]{}
+	}
+	return l.codec
+}
+
+// Save writes the Items (with the free slots, so the List-Indices are stable)
+// and the content of the Indices to the given Writer.
+func (l *IndexList[T, ID]) Save(w io.Writer) error {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return l.saveNoLock(w)
+}
+
+func (l *IndexList[T, ID]) saveNoLock(w io.Writer) error {
+	e := &encoder{}
+	e.buf.WriteString(persistMagic)
+	e.uint16(persistVersion)
+
+	if err := writeFreeList(e, &l.list, l.itemCodec()); err != nil {
+		return err
+	}
+
+	if l.indexMap.idIndex == nil {
+		e.uvarint(0)
+	} else {
+		e.uvarint(1)
+		writeIndex(e, IDIndexFieldName, l.indexMap.idIndex)
+	}
+
+	e.uvarint(uint64(len(l.indexMap.index)))
+	for name, index := range l.indexMap.index {
+		writeIndex(e, name, index)
+	}
+
+	e.uint32(crc32.ChecksumIEEE(e.buf.Bytes()))
+
+	_, err := w.Write(e.buf.Bytes())
+	return err
+}
+
+// Load replaces the Items and the content of the Indices with the data from the given Reader.
+// The Indices must be created before, with the same field-names and Index types as by saving.
+// Indices, which are not found in the saved data, are rebuild from the Items.
+func (l *IndexList[T, ID]) Load(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	defer l.indexMap.subs.deliver()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.loadNoLock(data)
+}
+
+func (l *IndexList[T, ID]) loadNoLock(data []byte) error {
+	if len(data) < headerSize+checksumSize || string(data[:len(persistMagic)]) != persistMagic {
+		return ErrInvalidFormat{"missing header"}
+	}
+
+	body, sum := data[:len(data)-checksumSize], binary.LittleEndian.Uint32(data[len(data)-checksumSize:])
+	if got := crc32.ChecksumIEEE(body); got != sum {
+		return ErrChecksumMismatch{expected: sum, got: got}
+	}
+
+	if version := binary.LittleEndian.Uint16(data[len(persistMagic):]); version != persistVersion {
+		return ErrUnsupportedVersion{version}
+	}
+
+	d := &decoder{data: body[headerSize:]}
+	list, err := readFreeList(d, l.itemCodec())
+	if err != nil {
+		return err
+	}
+
+	var idPayload *savedIndex
+	if d.uvarint() == 1 {
+		idx := readIndex(d)
+		idPayload = &idx
+	}
+
+	saved := make(map[string]savedIndex)
+	for range d.uvarint() {
+		idx := readIndex(d)
+		saved[idx.name] = idx
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	l.clearNoLock()
+	l.list = list
+
+	for idx := range l.list.Iter() {
+		l.indexMap.ids().Set(uint32(idx))
+	}
+
+	if l.indexMap.idIndex != nil {
+		if !restoreIndex(idPayload, l.indexMap.idIndex) {
+			for idx, item := range l.list.Iter() {
+				l.indexMap.idIndex.Set(&item, idx)
+			}
+		}
+	}
+
+	for name, index := range l.indexMap.index {
+		var payload *savedIndex
+		if s, found := saved[name]; found {
+			payload = &s
+		}
+
+		if !restoreIndex(payload, index) {
+			for idx, item := range l.list.Iter() {
+				index.Set(&item, uint32(idx))
+			}
+		}
+	}
+
+	l.refreshSubscriptionsNoLock()
+	return nil
+}
+
+type savedIndex struct {
+	name     string
+	typeName string
+	kind     byte
+	payload  []byte
+}
+
+func writeIndex(e *encoder, name string, index any) {
+	e.string(name)
+	e.string(fmt.Sprintf("%T", index))
+
+	if p, ok := index.(persister); ok {
+		ie := &encoder{}
+		if err := p.persist(ie); err == nil {
+			e.buf.WriteByte(indexKindPersisted)
+			e.bytes(ie.buf.Bytes())
+			return
+		}
+	}
+
+	e.buf.WriteByte(indexKindRebuild)
+}
+
+func readIndex(d *decoder) savedIndex {
+	idx := savedIndex{name: d.string(), typeName: d.string(), kind: d.byte()}
+	if idx.kind == indexKindPersisted {
+		idx.payload = d.bytes()
+	}
+	return idx
+}
+
+// restoreIndex restores the Index content from the saved payload.
+// If this is not possible, returns false and the Index must be rebuild.
+func restoreIndex(saved *savedIndex, index any) bool {
+	if saved == nil || saved.kind != indexKindPersisted || saved.typeName != fmt.Sprintf("%T", index) {
+		return false
+	}
+
+	p, ok := index.(persister)
+	if !ok {
+		return false
+	}
+
+	d := &decoder{data: saved.payload}
+	return p.restore(d) == nil && d.err == nil
+}
+
+func writeFreeList[T any](e *encoder, l *FreeList[T], codec Codec[T]) error {
+	e.uvarint(uint64(l.length))
+	e.varint(int64(l.freeHead))
+	e.uvarint(uint64(l.count))
+
+	for _, page := range l.pages {
+		for _, s := range page {
+			if !s.occupied {
+				e.buf.WriteByte(0)
+				e.varint(int64(s.nextFree))
+				continue
+			}
+
+			b, err := codec.Marshal(s.value)
+			if err != nil {
+				return err
+			}
+			e.buf.WriteByte(1)
+			e.bytes(b)
+		}
+	}
+
+	return nil
+}
+
+func readFreeList[T any](d *decoder, codec Codec[T]) (FreeList[T], error) {
+	n := d.uvarint()
+	if d.err == nil && n > uint64(len(d.data)) {
+		// every slot needs at least one byte
+		return FreeList[T]{}, ErrInvalidFormat{"slot count is too big"}
+	}
+
+	l := FreeList[T]{
+		freeHead: int(d.varint()),
+		count:    int(d.uvarint()),
+	}
+
+	validIndex := func(i int) bool { return i >= -1 && i < int(n) }
+	if !validIndex(l.freeHead) {
+		return FreeList[T]{}, ErrInvalidFormat{"invalid free head"}
+	}
+
+	occupied := 0
+	for range n {
+		if d.byte() == 0 {
+			next := int(d.varint())
+			if !validIndex(next) {
+				return FreeList[T]{}, ErrInvalidFormat{"invalid next free slot"}
+			}
+			l.push(slot[T]{nextFree: next})
+			continue
+		}
+
+		b := d.bytes()
+		if d.err != nil {
+			return FreeList[T]{}, d.err
+		}
+		item, err := codec.Unmarshal(b)
+		if err != nil {
+			return FreeList[T]{}, err
+		}
+		l.push(slot[T]{value: item, occupied: true, nextFree: -1})
+		occupied++
+	}
+
+	if d.err != nil {
+		return FreeList[T]{}, d.err
+	}
+	if occupied != l.count {
+		return FreeList[T]{}, ErrInvalidFormat{fmt.Sprintf("expected %d items, got: %d", l.count, occupied)}
+	}
+
+	return l, nil
+}
+
+// ------------------------------------------
+// persisting the Index impls
+// ------------------------------------------
+
+// the values are encoded with gob, followed by the List-Indices (BitSets) in the same order
+
+func (mi *idMapIndex[OBJ, ID]) persist(e *encoder) error {
+	ids := make([]ID, 0, len(mi.data))
+	for id := range mi.data {
+		ids = append(ids, id)
+	}
+	if err := e.gob(ids); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		e.uvarint(uint64(mi.data[id]))
+	}
+	return nil
+}
+
+func (mi *idMapIndex[OBJ, ID]) restore(d *decoder) error {
+	var ids []ID
+	if err := d.gob(&ids); err != nil {
+		return err
+	}
+
+	data := make(map[ID]int, len(ids))
+	for _, id := range ids {
+		data[id] = int(d.uvarint())
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	mi.data = data
+	return nil
+}
+
+func (mi *MapIndex[OBJ, V, LI]) persist(e *encoder) error {
+	if reflect.TypeFor[V]().Kind() == reflect.Pointer {
+		// the restored pointers are not the same, so it must be rebuild
+		return errNotPersistable
+	}
+
+	values := make([]V, 0, len(mi.data))
+	for value := range mi.data {
+		values = append(values, value.(V))
+	}
+	if err := e.gob(values); err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		writePostings(e, mi.data[value])
+	}
+	return nil
+}
+
+func (mi *MapIndex[OBJ, V, LI]) restore(d *decoder) error {
+	var values []V
+	if err := d.gob(&values); err != nil {
+		return err
+	}
+
+	data := make(map[any]*Posting[LI], len(values))
+	for _, value := range values {
+		p := NewPosting[LI]()
+		readPostings(d, p.Set)
+		data[value] = p
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	mi.data = data
+	return nil
+}
+
+func (si *SortedIndex[OBJ, V, LI]) persist(e *encoder) error {
+	values := make([]V, 0)
+	postings := make([]*Posting[LI], 0)
+	si.sorted.Traverse(func(value V, p *Posting[LI]) bool {
+		values = append(values, value)
+		postings = append(postings, p)
+		return true
+	})
+	if err := e.gob(values); err != nil {
+		return err
+	}
+
+	for _, p := range postings {
+		writePostings(e, p)
+	}
+	return nil
+}
+
+func (si *SortedIndex[OBJ, V, LI]) restore(d *decoder) error {
+	var values []V
+	if err := d.gob(&values); err != nil {
+		return err
+	}
+
+	var sorted sortedPages[V, *Posting[LI]]
+	for _, value := range values {
+		p := NewPosting[LI]()
+		readPostings(d, p.Set)
+		sorted.Put(value, p)
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	si.sorted = sorted
+	return nil
+}
+
+// ------------------------------------------
+// encoder and decoder for the binary format
+// ------------------------------------------
+
+type encoder struct {
+	buf bytes.Buffer
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) uvarint(v uint64) { e.buf.Write(binary.AppendUvarint(e.tmp[:0], v)) }
+func (e *encoder) varint(v int64)   { e.buf.Write(binary.AppendVarint(e.tmp[:0], v)) }
+func (e *encoder) uint16(v uint16)  { e.buf.Write(binary.LittleEndian.AppendUint16(e.tmp[:0], v)) }
+func (e *encoder) uint32(v uint32)  { e.buf.Write(binary.LittleEndian.AppendUint32(e.tmp[:0], v)) }
+func (e *encoder) uint64(v uint64)  { e.buf.Write(binary.LittleEndian.AppendUint64(e.tmp[:0], v)) }
+func (e *encoder) string(s string)  { e.uvarint(uint64(len(s))); e.buf.WriteString(s) }
+func (e *encoder) bytes(b []byte)   { e.uvarint(uint64(len(b))); e.buf.Write(b) }
+
+func (e *encoder) gob(v any) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	e.bytes(buf.Bytes())
+	return nil
+}
+
+// decoder reads from the data, the first error is saved and all following reads returns zero values
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail(msg string) {
+	if d.err == nil {
+		d.err = ErrInvalidFormat{msg}
+	}
+	d.data = nil
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail("invalid uvarint")
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.fail("invalid varint")
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if len(d.data) < 1 {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *decoder) uint32() uint32 {
+	if len(d.data) < 4 {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(d.data)
+	d.data = d.data[4:]
+	return v
+}
+
+func (d *decoder) uint64() uint64 {
+	if len(d.data) < 8 {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(d.data)
+	d.data = d.data[8:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail("unexpected end of data")
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) string() string { return string(d.bytes()) }
+
+func (d *decoder) gob(v any) error {
+	b := d.bytes()
+	if d.err != nil {
+		return d.err
+	}
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+// writePostings writes the number of List-Indices and the List-Indices as deltas to the previous List-Index
+func writePostings[LI Value](e *encoder, p PostingList[LI]) {
+	e.uvarint(uint64(p.Count()))
+	prev := uint64(0)
+	p.Values(func(lidx LI) bool {
+		e.uvarint(uint64(lidx) - prev)
+		prev = uint64(lidx)
+		return true
+	})
+}
+
+// readPostings reads the List-Indices, written with writePostings and calls set for every List-Index
+func readPostings[LI Value](d *decoder, set func(LI)) {
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail("postings are too big")
+		return
+	}
+
+	lidx := uint64(0)
+	for range n {
+		lidx += d.uvarint()
+		set(LI(lidx))
+	}
+}
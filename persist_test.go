@@ -0,0 +1,184 @@
+package fali
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pcar struct {
+	Name  string
+	Color string
+	Age   uint8
+}
+
+func newPersistList() *IndexList[pcar, string] {
+	il := NewIndexListWithID(FromName[pcar, string]("Name"))
+	_ = il.CreateIndex("color", NewMapIndex(FromName[pcar, string]("Color")))
+	_ = il.CreateIndex("age", NewSortedIndex(FromName[pcar, uint8]("Age")))
+	_ = il.CreateIndex("name", NewTrigramIndex(FromName[pcar, string]("Name")))
+	return il
+}
+
+func TestPersist_SaveLoad(t *testing.T) {
+	il := newPersistList()
+	_, _ = il.Insert(pcar{Name: "Opel", Color: "red", Age: 22})
+	_, _ = il.Insert(pcar{Name: "Mercedes", Color: "black", Age: 5})
+	_, _ = il.Insert(pcar{Name: "Dacia", Color: "red", Age: 12})
+	_, _ = il.Insert(pcar{Name: "Audi", Color: "blue", Age: 2})
+	_, err := il.Remove("Mercedes")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, il.Save(&buf))
+
+	loaded := newPersistList()
+	// the existing items are replaced
+	_, _ = loaded.Insert(pcar{Name: "VW", Color: "red", Age: 1})
+	assert.NoError(t, loaded.Load(bytes.NewReader(buf.Bytes())))
+	assert.Equal(t, 3, loaded.Count())
+	assert.False(t, loaded.Contains("VW"))
+
+	dacia, err := loaded.Get("Dacia")
+	assert.NoError(t, err)
+	assert.Equal(t, pcar{Name: "Dacia", Color: "red", Age: 12}, dacia)
+
+	qr, err := loaded.QueryStr(`color = "red" and age > uint8(20)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []pcar{{Name: "Opel", Color: "red", Age: 22}}, qr.Values())
+
+	qr, err = loaded.QueryStr(`name contains "ud" or color = "black"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []pcar{{Name: "Audi", Color: "blue", Age: 2}}, qr.Values())
+
+	qr, err = loaded.Query(All())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, qr.Count())
+
+	// the free slot of Mercedes is reused, the List-Indices are stable
+	idx, err := loaded.Insert(pcar{Name: "BMW", Color: "white", Age: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, idx)
+	idx, err = loaded.Insert(pcar{Name: "Fiat", Color: "white", Age: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, idx)
+}
+
+func TestPersist_RebuildMissingIndex(t *testing.T) {
+	il := NewIndexList[pcar]()
+	_, _ = il.Insert(pcar{Name: "Opel", Color: "red", Age: 22})
+
+	var buf bytes.Buffer
+	assert.NoError(t, il.Save(&buf))
+
+	// the age index was not saved
+	loaded := NewIndexList[pcar]()
+	assert.NoError(t, loaded.CreateIndex("age", NewMapIndex(FromName[pcar, uint8]("Age"))))
+	assert.NoError(t, loaded.Load(&buf))
+
+	qr, err := loaded.Query(Eq("age", uint8(22)))
+	assert.NoError(t, err)
+	assert.Equal(t, []pcar{{Name: "Opel", Color: "red", Age: 22}}, qr.Values())
+}
+
+type jsonCodec[T any] struct{}
+
+func (jsonCodec[T]) Marshal(item T) ([]byte, error) { return json.Marshal(item) }
+func (jsonCodec[T]) Unmarshal(data []byte) (T, error) {
+	var item T
+	err := json.Unmarshal(data, &item)
+	return item, err
+}
+
+func TestPersist_Codec(t *testing.T) {
+	il := NewIndexList[pcar]()
+	il.SetCodec(jsonCodec[pcar]{})
+	_, _ = il.Insert(pcar{Name: "Opel", Color: "red", Age: 22})
+
+	var buf bytes.Buffer
+	assert.NoError(t, il.Save(&buf))
+	assert.Contains(t, buf.String(), `"Name":"Opel"`)
+
+	loaded := NewIndexList[pcar]()
+	loaded.SetCodec(jsonCodec[pcar]{})
+	assert.NoError(t, loaded.Load(&buf))
+	assert.Equal(t, 1, loaded.Count())
+}
+
+func TestPersist_Corrupted(t *testing.T) {
+	il := newPersistList()
+	_, _ = il.Insert(pcar{Name: "Opel", Color: "red", Age: 22})
+
+	var buf bytes.Buffer
+	assert.NoError(t, il.Save(&buf))
+	data := buf.Bytes()
+
+	// changed byte
+	corrupted := bytes.Clone(data)
+	corrupted[10] ^= 0xFF
+	err := newPersistList().Load(bytes.NewReader(corrupted))
+	assert.ErrorAs(t, err, &ErrChecksumMismatch{})
+
+	// truncated
+	err = newPersistList().Load(bytes.NewReader(data[:len(data)-3]))
+	assert.ErrorAs(t, err, &ErrChecksumMismatch{})
+
+	// no header
+	err = newPersistList().Load(bytes.NewReader([]byte("FAL")))
+	assert.ErrorIs(t, err, ErrInvalidFormat{"missing header"})
+
+	// unknown version
+	changed := bytes.Clone(data)
+	binary.LittleEndian.PutUint16(changed[4:], 99)
+	binary.LittleEndian.PutUint32(changed[len(changed)-4:], crc32.ChecksumIEEE(changed[:len(changed)-4]))
+	err = newPersistList().Load(bytes.NewReader(changed))
+	assert.ErrorIs(t, err, ErrUnsupportedVersion{99})
+
+	// the list is not changed by an error
+	loaded := newPersistList()
+	_, _ = loaded.Insert(pcar{Name: "VW"})
+	assert.Error(t, loaded.Load(bytes.NewReader(corrupted)))
+	assert.True(t, loaded.Contains("VW"))
+}
+
+func TestPersist_IndexContent(t *testing.T) {
+	mi := NewMapIndex(FromValue[string]())
+	set(mi, "a", 1)
+	set(mi, "a", 3)
+	set(mi, "b", 2)
+
+	si := NewSortedIndex(FromValue[int]())
+	set(si, 5, 1)
+	set(si, 1, 2)
+
+	id := newIDMapIndex(FromValue[string]())
+	x := "x"
+	id.Set(&x, 7)
+
+	for _, index := range []any{mi, si, id} {
+		e := &encoder{}
+		writeIndex(e, "val", index)
+
+		saved := readIndex(&decoder{data: e.buf.Bytes()})
+		assert.Equal(t, indexKindPersisted, saved.kind)
+		assert.True(t, restoreIndex(&saved, index))
+	}
+
+	bs, _ := mi.Match(OpEq, "a")
+	assert.Equal(t, []uint32{1, 3}, bs.ToSlice())
+	bs, _ = si.Match(OpGe, 1)
+	assert.Equal(t, []uint32{1, 2}, bs.ToSlice())
+	lidx, err := id.GetIndex("x")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, lidx)
+
+	// pointer values are rebuild
+	pi := NewMapIndex(FromValue[*string]())
+	e := &encoder{}
+	writeIndex(e, "val", pi)
+	assert.Equal(t, indexKindRebuild, readIndex(&decoder{data: e.buf.Bytes()}).kind)
+}
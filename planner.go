@@ -0,0 +1,417 @@
+package fali
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// unknownCost is the estimate for a Query, which can not be estimated (e.g. NOT or a Filter without Estimator).
+// These Queries are executed at last.
+const unknownCost = math.MaxInt
+
+// Estimator is implemented by Filters, which can estimate the number of matching Items, without creating the result.
+// The query planner uses the estimate, to execute the most selective term of an AND first.
+type Estimator interface {
+	Estimate(op Op, values ...any) (int, error)
+}
+
+// estimateFn estimates the number of Items for a Query
+type estimateFn[LI Value] func(l FilterByName[LI]) (int, error)
+
+// plan is a compiled Query with the estimate of the result size
+type plan[LI Value] struct {
+	query    Query[LI]
+	estimate estimateFn[LI]
+	node     *ExplainNode
+}
+
+// planExpr compiles the Expression to a Query, AND terms are executed sorted by the estimated cardinality.
+// With analyze, the count and the duration of every Query are saved in the ExplainNode.
+func planExpr(e Expr, analyze bool) plan[uint32] {
+	p := planNode(e, analyze)
+	p.node.Expr = e
+	p.node.estimate = p.estimate
+	if analyze {
+		p.query = p.node.analyze(p.query)
+	}
+	return p
+}
+
+func planNode(e Expr, analyze bool) plan[uint32] {
+	switch n := e.(type) {
+	case TermExpr:
+		return plan[uint32]{
+			query: coerced(n.Field, []any{n.Value}, func(values ...any) Query32 {
+				return match[uint32](n.Field, n.Op, values[0])
+			}),
+			estimate: estimateTerm[uint32](n.Field, n.Op, n.Value),
+			node:     &ExplainNode{},
+		}
+
+	case TermManyExpr:
+		return plan[uint32]{
+			query: coerced(n.Field, n.Values, func(values ...any) Query32 {
+				if n.Op == OpBetween {
+					return matchRange[uint32](n.Field, values[0], values[1], n.MinIncl, n.MaxIncl)
+				}
+				return matchMany[uint32](n.Field, n.Op, values...)
+			}),
+			estimate: estimateTerm[uint32](n.Field, n.Op, n.Values...),
+			node:     &ExplainNode{},
+		}
+
+	case TermCompositeExpr:
+		values := make(CompositeKey, len(n.Fields))
+		for i, field := range n.Fields {
+			values[field] = n.Values[i]
+		}
+		return plan[uint32]{
+			query: func(l FilterByName32, allIDs *BitSet[uint32]) (PostingList[uint32], bool, error) {
+				converted, err := coerceCompositeParts(l, values)
+				if err != nil {
+					return nil, false, err
+				}
+				return matchComposite[uint32](converted)(l, allIDs)
+			},
+			estimate: estimateComposite[uint32](values),
+			node:     &ExplainNode{},
+		}
+
+	case NotExpr:
+		child := planExpr(n.Child, analyze)
+		return plan[uint32]{
+			query:    Not(child.query),
+			estimate: estimateUnknown[uint32],
+			node:     &ExplainNode{Children: []*ExplainNode{child.node}},
+		}
+
+	case BinaryExpr:
+		switch n.Op {
+		case ExprAnd:
+			terms := andTerms(n, nil)
+			plans := make([]plan[uint32], 0, len(terms))
+			for _, term := range terms {
+				plans = append(plans, planExpr(term, analyze))
+			}
+			p := planAnd(plans...)
+			p.node = &ExplainNode{}
+			for _, c := range plans {
+				p.node.Children = append(p.node.Children, c.node)
+			}
+			return planComposites(p, n)
+		case ExprOr:
+			left, right := planExpr(n.Left, analyze), planExpr(n.Right, analyze)
+			return plan[uint32]{
+				query:    Or(left.query, right.query),
+				estimate: estimateSum(left.estimate, right.estimate),
+				node:     &ExplainNode{Children: []*ExplainNode{left.node, right.node}},
+			}
+		case ExprAndNot:
+			left, right := planExpr(n.Left, analyze), planExpr(n.Right, analyze)
+			return plan[uint32]{
+				query:    AndNot(left.query, right.query),
+				estimate: left.estimate,
+				node:     &ExplainNode{Children: []*ExplainNode{left.node, right.node}},
+			}
+		}
+	}
+
+	panic(fmt.Sprintf("NOT supported Expression in compile: %T", e))
+}
+
+// planComposites merges the equality terms of the AND at execution, if a CompositeIndex is registered for the fields.
+// Without registered CompositeIndices, the planned AND is executed.
+func planComposites(and plan[uint32], n BinaryExpr) plan[uint32] {
+	terms, nots := andNotTerms(n, nil, nil)
+	equals := equalTerms(terms)
+	if len(equals) < 2 {
+		return and
+	}
+
+	merged := func(l FilterByName32) plan[uint32] {
+		if merged, ok := mergeTerms(terms, equals, registeredComposites(l)); ok {
+			return planExpr(andNotExpr(merged, nots), false)
+		}
+		return and
+	}
+
+	return plan[uint32]{
+		query: func(l FilterByName32, allIDs *BitSet[uint32]) (PostingList[uint32], bool, error) {
+			return merged(l).query(l, allIDs)
+		},
+		estimate: func(l FilterByName32) (int, error) { return merged(l).estimate(l) },
+		node:     and.node,
+	}
+}
+
+// mergeComposites merges the equality terms of every AND to a TermCompositeExpr,
+// for which a CompositeIndex is registered (see: registeredComposites).
+// Expressions without a CompositeIndex are not changed.
+func mergeComposites(e Expr, composites [][]string) Expr {
+	if len(composites) == 0 {
+		return e
+	}
+
+	switch n := e.(type) {
+	case NotExpr:
+		return NotExpr{Child: mergeComposites(n.Child, composites)}
+	case BinaryExpr:
+		if n.Op != ExprOr {
+			terms, nots := andNotTerms(n, nil, nil)
+			if merged, ok := mergeTerms(terms, equalTerms(terms), composites); ok {
+				for i, term := range merged {
+					merged[i] = mergeComposites(term, composites)
+				}
+				for i, not := range nots {
+					nots[i] = mergeComposites(not, composites)
+				}
+				return andNotExpr(merged, nots)
+			}
+		}
+		return BinaryExpr{Op: n.Op, Left: mergeComposites(n.Left, composites), Right: mergeComposites(n.Right, composites)}
+	}
+	return e
+}
+
+// equalTerms maps the fields of the equality terms to the position in the terms (the first term for a field)
+func equalTerms(terms []Expr) map[string]int {
+	equals := make(map[string]int, len(terms))
+	for i, term := range terms {
+		if t, ok := term.(TermExpr); ok && t.Op == OpEq && t.Field != IDIndexFieldName {
+			if _, found := equals[t.Field]; !found {
+				equals[t.Field] = i
+			}
+		}
+	}
+	return equals
+}
+
+// mergeTerms replaces the equality terms with a TermCompositeExpr, for the largest registered CompositeIndex first.
+// Returns false, if no CompositeIndex is found for the equality terms.
+func mergeTerms(terms []Expr, equals map[string]int, composites [][]string) ([]Expr, bool) {
+	if len(composites) == 0 || len(equals) < 2 {
+		return terms, false
+	}
+
+	merged := make([]Expr, 0, len(terms))
+	used := make([]bool, len(terms))
+	for {
+		fields := largestComposite(composites, func(f string) bool {
+			i, found := equals[f]
+			return found && !used[i]
+		})
+		if fields == nil {
+			break
+		}
+
+		composite := TermCompositeExpr{Fields: fields, Values: make([]any, len(fields))}
+		for i, field := range fields {
+			pos := equals[field]
+			composite.Values[i] = terms[pos].(TermExpr).Value
+			used[pos] = true
+		}
+		merged = append(merged, composite)
+	}
+	if len(merged) == 0 {
+		return terms, false
+	}
+
+	for i, term := range terms {
+		if !used[i] {
+			merged = append(merged, term)
+		}
+	}
+	return merged, true
+}
+
+// andNotExpr combines the terms with AND and the nots with AND NOT: [a, b], [c] => (a AND b) AND NOT c
+func andNotExpr(terms, nots []Expr) Expr {
+	e := terms[0]
+	for _, term := range terms[1:] {
+		e = BinaryExpr{Op: ExprAnd, Left: e, Right: term}
+	}
+	for _, not := range nots {
+		e = BinaryExpr{Op: ExprAndNot, Left: e, Right: not}
+	}
+	return e
+}
+
+// andNotTerms flattens nested ANDs and the left side of AND NOTs: (a AND NOT b) AND c => [a, c], [b]
+func andNotTerms(e Expr, terms, nots []Expr) ([]Expr, []Expr) {
+	if n, ok := e.(BinaryExpr); ok {
+		switch n.Op {
+		case ExprAnd:
+			terms, nots = andNotTerms(n.Left, terms, nots)
+			return andNotTerms(n.Right, terms, nots)
+		case ExprAndNot:
+			terms, nots = andNotTerms(n.Left, terms, nots)
+			return terms, append(nots, n.Right)
+		}
+	}
+	return append(terms, e), nots
+}
+
+// andTerms flattens nested ANDs: (a AND b) AND c => [a, b, c]
+func andTerms(e Expr, terms []Expr) []Expr {
+	if and, ok := e.(BinaryExpr); ok && and.Op == ExprAnd {
+		terms = andTerms(and.Left, terms)
+		return andTerms(and.Right, terms)
+	}
+	return append(terms, e)
+}
+
+// planAnd executes the terms sorted by the estimate, the most selective term first.
+// If the intermediate result is empty, the remaining terms are not executed.
+func planAnd[LI Value](plans ...plan[LI]) plan[LI] {
+	query := func(l FilterByName[LI], allIDs *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
+		ordered, err := orderByEstimate(l, plans)
+		if err != nil {
+			return nil, false, err
+		}
+
+		result, err := ensureMutable(ordered[0].query(l, allIDs))
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, p := range ordered[1:] {
+			// early exit, the result can not be changed anymore
+			if result.IsEmpty() {
+				break
+			}
+
+			next, _, err := p.query(l, allIDs)
+			if err != nil {
+				return nil, false, err
+			}
+			andPosting(result, next)
+		}
+
+		return result, true, nil
+	}
+
+	estimate := func(l FilterByName[LI]) (int, error) {
+		cost := unknownCost
+		for _, p := range plans {
+			c, err := p.estimate(l)
+			if err != nil {
+				return 0, err
+			}
+			cost = min(cost, c)
+		}
+		return cost, nil
+	}
+
+	return plan[LI]{query: query, estimate: estimate}
+}
+
+// orderByEstimate returns the plans sorted by the estimate, the written order is kept for equal estimates
+func orderByEstimate[LI Value](l FilterByName[LI], plans []plan[LI]) ([]plan[LI], error) {
+	type estimated struct {
+		plan[LI]
+		cost int
+	}
+
+	costs := make([]estimated, len(plans))
+	for i, p := range plans {
+		cost, err := p.estimate(l)
+		if err != nil {
+			return nil, err
+		}
+		costs[i] = estimated{p, cost}
+	}
+	slices.SortStableFunc(costs, func(a, b estimated) int { return cmp.Compare(a.cost, b.cost) })
+
+	ordered := make([]plan[LI], len(costs))
+	for i, c := range costs {
+		ordered[i] = c.plan
+	}
+	return ordered, nil
+}
+
+//go:inline
+func estimateUnknown[LI Value](FilterByName[LI]) (int, error) { return unknownCost, nil }
+
+//go:inline
+func estimateTerm[LI Value](fieldName string, op Op, values ...any) estimateFn[LI] {
+	return func(l FilterByName[LI]) (int, error) {
+		filter, err := l(fieldName)
+		if err != nil {
+			return 0, err
+		}
+
+		e, ok := filter.(Estimator)
+		if !ok {
+			return unknownCost, nil
+		}
+
+		converted, err := coerceValues(filter, values)
+		if err != nil {
+			return 0, err
+		}
+		return e.Estimate(op, converted...)
+	}
+}
+
+// estimateComposite is the estimate of the most selective part (see: compositeParts)
+//
+//go:inline
+func estimateComposite[LI Value](values CompositeKey) estimateFn[LI] {
+	return func(l FilterByName[LI]) (int, error) {
+		converted, err := coerceCompositeParts(l, values)
+		if err != nil {
+			return 0, err
+		}
+
+		cost := unknownCost
+		for _, part := range compositeParts(l, converted) {
+			c, err := compositeEstimate[LI](part)(l)
+			if err != nil {
+				return 0, err
+			}
+			cost = min(cost, c)
+		}
+		return cost, nil
+	}
+}
+
+// estimateSum is the estimate for an OR
+//
+//go:inline
+func estimateSum[LI Value](a, b estimateFn[LI]) estimateFn[LI] {
+	return func(l FilterByName[LI]) (int, error) {
+		ca, err := a(l)
+		if err != nil {
+			return 0, err
+		}
+		cb, err := b(l)
+		if err != nil {
+			return 0, err
+		}
+
+		if ca > unknownCost-cb {
+			return unknownCost, nil
+		}
+		return ca + cb, nil
+	}
+}
+
+// estimateKeys estimates the number of List-Indices for the (estimated) number of keys of a SortedIndex:
+// the number of keys * the average count of the first (sampled) Postings, which are visited by walk.
+func estimateKeys[V cmp.Ordered, LI Value](keys int, walk func(VisitFn[V, *Posting[LI]])) int {
+	const samples = 8
+
+	sampled, sum := 0, 0
+	walk(func(_ V, p *Posting[LI]) bool {
+		sum += p.Count()
+		sampled++
+		return sampled < samples
+	})
+
+	if sampled == 0 {
+		return 0
+	}
+	return max(keys, sampled) * sum / sampled
+}
@@ -0,0 +1,188 @@
+package fali
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordFilter records the field-names of the executed Match calls
+type recordFilter struct {
+	Filter32
+	name  string
+	calls *[]string
+}
+
+func (r recordFilter) Match(op Op, value any) (*BitSet[uint32], error) {
+	*r.calls = append(*r.calls, r.name)
+	return r.Filter32.Match(op, value)
+}
+
+func (r recordFilter) Estimate(op Op, values ...any) (int, error) {
+	if e, ok := r.Filter32.(Estimator); ok {
+		return e.Estimate(op, values...)
+	}
+	return unknownCost, nil
+}
+
+func newPlannerList() *IndexList[car, string] {
+	il := NewIndexListWithID((*car).Name)
+	_ = il.CreateIndex("isnew", NewMapIndex((*car).IsNew))
+	_ = il.CreateIndex("age", NewSortedIndex((*car).Age))
+	_ = il.CreateIndex("name", NewTrigramIndex((*car).Name))
+
+	for i := range 20 {
+		_, _ = il.Insert(car{name: string(rune('A' + i)), age: uint8(i), isNew: i != 3})
+	}
+	return il
+}
+
+func recordQuery(t *testing.T, il *IndexList[car, string], queryStr string) ([]car, []string) {
+	query, err := Parse(queryStr)
+	assert.NoError(t, err)
+	return recordCalls(t, il, query)
+}
+
+func recordCalls(t *testing.T, il *IndexList[car, string], query Query32) ([]car, []string) {
+	calls := make([]string, 0)
+	l := func(fieldName string) (Filter32, error) {
+		filter, err := il.indexMap.FilterByName(fieldName)
+		if err != nil {
+			return nil, err
+		}
+		return recordFilter{Filter32: filter, name: fieldName, calls: &calls}, nil
+	}
+
+	bs, _, err := query(l, il.indexMap.allIDs)
+	assert.NoError(t, err)
+
+	result := make([]car, 0)
+	for lidx := range bs.Values {
+		item, _ := il.list.Get(int(lidx))
+		result = append(result, item)
+	}
+	return result, calls
+}
+
+func TestPlanner_SelectiveFirst(t *testing.T) {
+	il := newPlannerList()
+
+	// the broad condition is written first
+	result, calls := recordQuery(t, il, `isnew = true and age = uint8(5)`)
+	assert.Equal(t, []car{{name: "F", age: 5, isNew: true}}, result)
+	assert.Equal(t, []string{"age", "isnew"}, calls)
+
+	result, calls = recordQuery(t, il, `isnew = false and age > uint8(1)`)
+	assert.Equal(t, []car{{name: "D", age: 3}}, result)
+	assert.Equal(t, []string{"isnew", "age"}, calls)
+
+	// the TrigramIndex has no estimate, it is executed at last
+	result, calls = recordQuery(t, il, `name contains "B" and age = uint8(1)`)
+	assert.Equal(t, []car{{name: "B", age: 1, isNew: true}}, result)
+	assert.Equal(t, []string{"age", "name"}, calls)
+
+	// the written order for equal estimates
+	_, calls = recordQuery(t, il, `isnew = false and age >= uint8(19)`)
+	assert.Equal(t, []string{"isnew", "age"}, calls)
+}
+
+func TestPlanner_EarlyExit(t *testing.T) {
+	il := newPlannerList()
+
+	// age = 99 is empty, isnew is not executed
+	result, calls := recordQuery(t, il, `isnew = true and age > uint8(1) and age = uint8(99)`)
+	assert.Empty(t, result)
+	assert.Equal(t, []string{"age"}, calls)
+
+	// an empty intermediate result, the broad isnew = true is not executed
+	result, calls = recordQuery(t, il, `age = uint8(1) and isnew = true and isnew = false`)
+	assert.Empty(t, result)
+	assert.Equal(t, []string{"age", "isnew"}, calls)
+
+	result, calls = recordQuery(t, il, `age = uint8(3) and isnew = true and name contains "D"`)
+	assert.Empty(t, result)
+	assert.Equal(t, []string{"age", "isnew"}, calls)
+}
+
+func TestPlanner_BuilderNotPlanned(t *testing.T) {
+	il := newPlannerList()
+
+	// And and Or are executed in the written order
+	result, calls := recordCalls(t, il, And(Eq("isnew", true), Eq("age", uint8(5))))
+	assert.Equal(t, []car{{name: "F", age: 5, isNew: true}}, result)
+	assert.Equal(t, []string{"isnew", "age"}, calls)
+
+	_, calls = recordCalls(t, il, Or(Eq("isnew", false), Eq("age", uint8(5))))
+	assert.Equal(t, []string{"isnew", "age"}, calls)
+
+	// the same parsed query is planned
+	_, calls = recordQuery(t, il, `isnew = true and age = uint8(5)`)
+	assert.Equal(t, []string{"age", "isnew"}, calls)
+}
+
+func TestPlanner_Errors(t *testing.T) {
+	il := newPlannerList()
+
+	// the errors are reported, also if the term is not executed
+	_, err := il.QueryStr(`age = uint8(99) and isnew = "true"`)
+	assert.ErrorIs(t, err, ErrInvalidIndexValue[bool]{"true"})
+
+	_, err = il.QueryStr(`age = uint8(99) and unknown = 5`)
+	assert.ErrorIs(t, err, ErrInvalidIndexdName{"unknown"})
+}
+
+func TestPlanner_Estimate(t *testing.T) {
+	si := NewSortedIndex(FromValue[int]())
+	for i := range 10 {
+		set(si, i, uint32(i))
+		set(si, i, uint32(i+10))
+	}
+	e := si.(Estimator)
+
+	tests := []struct {
+		op       Op
+		values   []any
+		expected int
+	}{
+		{OpEq, []any{3}, 2},
+		{OpEq, []any{42}, 0},
+		{OpLt, []any{3}, 6},
+		{OpLe, []any{3}, 8},
+		{OpGt, []any{7}, 4},
+		{OpGe, []any{7}, 6},
+		{OpBetween, []any{2, 5}, 8},
+		{OpIn, []any{5, 1, 42}, 4},
+	}
+
+	for _, tt := range tests {
+		count, err := e.Estimate(tt.op, tt.values...)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, count, "%s %v", tt.op, tt.values)
+	}
+
+	_, err := e.Estimate(OpEq, "3")
+	assert.ErrorIs(t, err, ErrInvalidIndexValue[int]{"3"})
+	_, err = e.Estimate(OpBetween, 1)
+	assert.ErrorIs(t, err, ErrInvalidArgsLen{defined: "2", got: 1})
+	_, err = e.Estimate(OpContains, 1)
+	assert.ErrorIs(t, err, ErrInvalidOperation{SortedIndexName, OpContains})
+
+	mi := NewMapIndex(FromValue[string]())
+	set(mi, "a", 1)
+	set(mi, "a", 2)
+	count, err := mi.(Estimator).Estimate(OpEq, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	_, err = mi.(Estimator).Estimate(OpLt, "a")
+	assert.ErrorIs(t, err, ErrInvalidOperation{MapIndexName, OpLt})
+
+	mv := newMultiValueIndex[article, string, uint32]((*article).Tags)
+	mv.Set(&article{tags: []string{"go", "db"}}, 1)
+	mv.Set(&article{tags: []string{"go"}}, 2)
+	count, err = mv.Estimate(OpHasAny, "go", "db")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	count, err = mv.Estimate(OpHasAll, "go", "db")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
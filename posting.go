@@ -0,0 +1,418 @@
+package fali
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// PostingList are the List-Indices of one value of an Index or the result of a Query: BitSet, Roaring or Posting
+type PostingList[LI Value] interface {
+	Count() int
+	Values(yield func(LI) bool)
+	ToSlice() []LI
+}
+
+// postingMatcher is implemented by Filters, which save the List-Indices compressed (e.g. MapIndex).
+// The saved PostingList is combined with the result, without creating a BitSet for the value.
+type postingMatcher[LI Value] interface {
+	matchPosting(value any) (PostingList[LI], error)
+}
+
+// eqPosting returns the List-Indices for: field = value, the saved PostingList (must not be changed) or the result of Match
+func eqPosting[LI Value](filter Filter[LI], value any) (PostingList[LI], error) {
+	if pm, ok := filter.(postingMatcher[LI]); ok {
+		return pm.matchPosting(value)
+	}
+
+	bs, err := filter.Match(OpEq, value)
+	if err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+// Posting are the List-Indices of one value of an Index.
+// A Posting starts as SliceSet, while it is sparse and is promoted to a BitSet, if the BitSet needs less memory.
+// If the BitSet needs more than the double memory of the SliceSet, the Posting is demoted to a SliceSet.
+type Posting[LI Value] struct {
+	slice  *SliceSet[LI]
+	bitSet *BitSet[LI] // is not nil, if the Posting is dense
+	count  int         // the number of values in the BitSet
+}
+
+// NewPosting creates a new (sparse) Posting
+func NewPosting[LI Value]() *Posting[LI] {
+	return &Posting[LI]{slice: NewSliceSet[LI]()}
+}
+
+// NewPostingFrom creates a new Posting from given values
+func NewPostingFrom[LI Value](values ...LI) *Posting[LI] {
+	p := NewPosting[LI]()
+	for _, v := range values {
+		p.Set(v)
+	}
+	return p
+}
+
+// IsDense returns true, if the values are saved in a BitSet
+func (p *Posting[LI]) IsDense() bool { return p.bitSet != nil }
+
+// Set inserts the value in the Posting
+func (p *Posting[LI]) Set(value LI) {
+	if p.bitSet == nil {
+		p.slice.Set(value)
+	} else if !p.bitSet.Contains(value) {
+		p.bitSet.Set(value)
+		p.count++
+	}
+	p.adapt()
+}
+
+// UnSet removes the value from the Posting, returns false, if the value was not found
+func (p *Posting[LI]) UnSet(value LI) bool {
+	if p.bitSet == nil {
+		return p.slice.UnSet(value)
+	}
+
+	if !p.bitSet.Contains(value) {
+		return false
+	}
+	p.bitSet.UnSet(value)
+	p.count--
+	p.adapt()
+	return true
+}
+
+// Contains check, is the value saved in the Posting
+func (p *Posting[LI]) Contains(value LI) bool {
+	if p.bitSet == nil {
+		return p.slice.Contains(value)
+	}
+	return p.bitSet.Contains(value)
+}
+
+// Count counts how many values are in the Posting
+func (p *Posting[LI]) Count() int {
+	if p.bitSet == nil {
+		return p.slice.Count()
+	}
+	return p.count
+}
+
+// IsEmpty there are no values, means Count() == 0
+func (p *Posting[LI]) IsEmpty() bool { return p.Count() == 0 }
+
+// Min return the min value, if the Posting is empty, return -1
+func (p *Posting[LI]) Min() int {
+	if p.bitSet == nil {
+		return p.slice.Min()
+	}
+	return p.bitSet.Min()
+}
+
+// Max return the max value, if the Posting is empty, return -1
+func (p *Posting[LI]) Max() int {
+	if p.bitSet == nil {
+		return p.slice.Max()
+	}
+	return p.bitSet.Max()
+}
+
+// Values iterate over the sorted values and call the yield function, for every value
+func (p *Posting[LI]) Values(yield func(LI) bool) {
+	if p.bitSet == nil {
+		p.slice.Values(yield)
+		return
+	}
+	p.bitSet.Values(yield)
+}
+
+// ToSlice create a new slice which contains all saved values
+func (p *Posting[LI]) ToSlice() []LI {
+	if p.bitSet == nil {
+		return slices.Clone(p.slice.data)
+	}
+	return p.bitSet.ToSlice()
+}
+
+// ToBitSet creates a new BitSet with the same values
+func (p *Posting[LI]) ToBitSet() *BitSet[LI] {
+	if p.bitSet == nil {
+		return p.slice.ToBitSet()
+	}
+	return p.bitSet.Copy()
+}
+
+// Copy copy the complete Posting.
+func (p *Posting[LI]) Copy() *Posting[LI] {
+	if p.bitSet == nil {
+		return &Posting[LI]{slice: p.slice.Copy()}
+	}
+	return &Posting[LI]{bitSet: p.bitSet.Copy(), count: p.count}
+}
+
+// And is the logical AND of two Postings
+func (p *Posting[LI]) And(other *Posting[LI]) {
+	switch {
+	case p.bitSet == nil && other.bitSet == nil:
+		p.slice.And(other.slice)
+	case p.bitSet == nil:
+		p.slice.data = slices.DeleteFunc(p.slice.data, func(v LI) bool { return !other.bitSet.Contains(v) })
+	case other.bitSet == nil:
+		data := make([]LI, 0, other.slice.Len())
+		for _, v := range other.slice.data {
+			if p.bitSet.Contains(v) {
+				data = append(data, v)
+			}
+		}
+		p.slice, p.bitSet, p.count = &SliceSet[LI]{data: data}, nil, 0
+	default:
+		p.bitSet.And(other.bitSet)
+		p.count = p.bitSet.Count()
+	}
+	p.adapt()
+}
+
+// Or is the logical OR of two Postings
+func (p *Posting[LI]) Or(other *Posting[LI]) {
+	switch {
+	case p.bitSet == nil && other.bitSet == nil:
+		if p.slice.Len() == 0 {
+			// SliceSet.Or shares the data, if this SliceSet is empty
+			p.slice = other.slice.Copy()
+		} else {
+			p.slice.Or(other.slice)
+		}
+	case p.bitSet == nil:
+		bs := other.bitSet.Copy()
+		for _, v := range p.slice.data {
+			bs.Set(v)
+		}
+		p.slice, p.bitSet, p.count = nil, bs, bs.Count()
+	case other.bitSet == nil:
+		for _, v := range other.slice.data {
+			p.bitSet.Set(v)
+		}
+		p.count = p.bitSet.Count()
+	default:
+		p.bitSet.Or(other.bitSet)
+		p.count = p.bitSet.Count()
+	}
+	p.adapt()
+}
+
+// AndNot removes all values from the current Posting that exist in another Posting.
+func (p *Posting[LI]) AndNot(other *Posting[LI]) {
+	switch {
+	case p.bitSet == nil && other.bitSet == nil:
+		p.slice.AndNot(other.slice)
+	case p.bitSet == nil:
+		p.slice.data = slices.DeleteFunc(p.slice.data, other.bitSet.Contains)
+	case other.bitSet == nil:
+		for _, v := range other.slice.data {
+			p.bitSet.UnSet(v)
+		}
+		p.count = p.bitSet.Count()
+	default:
+		p.bitSet.AndNot(other.bitSet)
+		p.count = p.bitSet.Count()
+	}
+	p.adapt()
+}
+
+// CountAnd counts the values, which are in the Posting and in the BitSet
+func (p *Posting[LI]) CountAnd(bs *BitSet[LI]) int {
+	if p.bitSet != nil {
+		return p.bitSet.CountAnd(bs)
+	}
+
+	count := 0
+	for _, v := range p.slice.data {
+		if bs.Contains(v) {
+			count++
+		}
+	}
+	return count
+}
+
+// orInto adds the values of the Posting to the BitSet
+func (p *Posting[LI]) orInto(bs *BitSet[LI]) {
+	if p.bitSet != nil {
+		bs.Or(p.bitSet)
+		return
+	}
+	for _, v := range p.slice.data {
+		bs.Set(v)
+	}
+}
+
+// andInto removes all values from the BitSet, which are not in the Posting
+func (p *Posting[LI]) andInto(bs *BitSet[LI]) {
+	if p.bitSet != nil {
+		bs.And(p.bitSet)
+		return
+	}
+	andValues(bs, p.slice)
+}
+
+// andNotInto removes the values of the Posting from the BitSet
+func (p *Posting[LI]) andNotInto(bs *BitSet[LI]) {
+	if p.bitSet != nil {
+		bs.AndNot(p.bitSet)
+		return
+	}
+	for _, v := range p.slice.data {
+		bs.UnSet(v)
+	}
+}
+
+// asBitSet returns the BitSet of a dense Posting (not a copy, so it must not be changed), otherwise a new BitSet
+func (p *Posting[LI]) asBitSet() *BitSet[LI] {
+	if p.bitSet != nil {
+		return p.bitSet
+	}
+	return p.slice.ToBitSet()
+}
+
+// adapt promotes the SliceSet to a BitSet, if the BitSet needs less memory
+// and demotes the BitSet, if the BitSet needs more than the double memory of the SliceSet
+func (p *Posting[LI]) adapt() {
+	size := bits.Len64(uint64(^LI(0))) / 8
+
+	if p.bitSet == nil {
+		if n := p.slice.Len(); n > 0 && n*size > (p.slice.Max()>>6+1)*8 {
+			p.bitSet, p.count, p.slice = NewBitSetFrom(p.slice.data...), n, nil
+		}
+		return
+	}
+
+	if 2*p.count*size < p.bitSet.Len()*8 {
+		p.bitSet.Shrink()
+		if 2*p.count*size < p.bitSet.Len()*8 {
+			p.slice, p.bitSet, p.count = &SliceSet[LI]{data: p.bitSet.ToSlice()}, nil, 0
+		}
+	}
+}
+
+// how many bytes is using
+func (p *Posting[LI]) usedBytes() int {
+	if p.bitSet == nil {
+		return 24 + p.slice.Len()*(bits.Len64(uint64(^LI(0)))/8)
+	}
+	return p.bitSet.usedBytes()
+}
+
+// countAnd counts the List-Indices of the PostingList, which are in the result
+func countAnd[LI Value](p PostingList[LI], result *BitSet[LI]) int {
+	switch p := p.(type) {
+	case *BitSet[LI]:
+		return p.CountAnd(result)
+	case *Posting[LI]:
+		return p.CountAnd(result)
+	}
+
+	count := 0
+	p.Values(func(lidx LI) bool {
+		if result.Contains(lidx) {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// toBitSet returns a new BitSet with the List-Indices of the PostingList
+func toBitSet[LI Value](p PostingList[LI]) *BitSet[LI] {
+	bs := NewBitSet[LI]()
+	orPosting(bs, p)
+	return bs
+}
+
+// andBitSet returns a new BitSet with the List-Indices of the PostingList, which are in the result
+func andBitSet[LI Value](p PostingList[LI], result *BitSet[LI]) *BitSet[LI] {
+	if bs, ok := p.(*BitSet[LI]); ok {
+		bs = bs.Copy()
+		bs.And(result)
+		return bs
+	}
+
+	bs := NewBitSet[LI]()
+	p.Values(func(lidx LI) bool {
+		if result.Contains(lidx) {
+			bs.Set(lidx)
+		}
+		return true
+	})
+	return bs
+}
+
+// andPosting is the logical AND of the result and the PostingList, without creating a BitSet for the PostingList
+func andPosting[LI Value](result *BitSet[LI], p PostingList[LI]) {
+	switch p := p.(type) {
+	case *BitSet[LI]:
+		result.And(p)
+	case *Posting[LI]:
+		p.andInto(result)
+	case *Roaring[LI]:
+		p.andInto(result)
+	default:
+		andValues(result, p)
+	}
+}
+
+// orPosting is the logical OR of the result and the PostingList, without creating a BitSet for the PostingList
+func orPosting[LI Value](result *BitSet[LI], p PostingList[LI]) {
+	switch p := p.(type) {
+	case *BitSet[LI]:
+		result.Or(p)
+	case *Posting[LI]:
+		p.orInto(result)
+	case *Roaring[LI]:
+		p.orInto(result)
+	default:
+		p.Values(func(lidx LI) bool {
+			result.Set(lidx)
+			return true
+		})
+	}
+}
+
+// andNotPosting removes the List-Indices of the PostingList from the result
+func andNotPosting[LI Value](result *BitSet[LI], p PostingList[LI]) {
+	switch p := p.(type) {
+	case *BitSet[LI]:
+		result.AndNot(p)
+	case *Posting[LI]:
+		p.andNotInto(result)
+	case *Roaring[LI]:
+		p.andNotInto(result)
+	default:
+		p.Values(func(lidx LI) bool {
+			result.UnSet(lidx)
+			return true
+		})
+	}
+}
+
+// andValues keeps only the words of the result, which contains the (sorted) values of the PostingList
+func andValues[LI Value](result *BitSet[LI], p PostingList[LI]) {
+	word, mask := 0, uint64(0)
+	p.Values(func(lidx LI) bool {
+		w := int(lidx >> 6)
+		if w >= len(result.data) {
+			return false
+		}
+		if w != word {
+			result.data[word] &= mask
+			clear(result.data[word+1 : w])
+			word, mask = w, 0
+		}
+		mask |= 1 << (lidx & 63)
+		return true
+	})
+
+	if word < len(result.data) {
+		result.data[word] &= mask
+		clear(result.data[word+1:])
+	}
+}
@@ -0,0 +1,219 @@
+package fali
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPosting_PromoteDemote(t *testing.T) {
+	p := NewPosting[uint32]()
+	p.Set(4_000_000)
+	assert.False(t, p.IsDense())
+	assert.Less(t, p.usedBytes(), 64)
+
+	// 64 values in 2 words => BitSet
+	for i := range uint32(64) {
+		p.UnSet(4_000_000)
+		p.Set(i)
+	}
+	assert.True(t, p.IsDense())
+	assert.Equal(t, 64, p.Count())
+	assert.Equal(t, 0, p.Min())
+	assert.Equal(t, 63, p.Max())
+
+	// a big value => SliceSet
+	p.Set(4_000_000)
+	assert.False(t, p.IsDense())
+	assert.Equal(t, 65, p.Count())
+	assert.True(t, p.Contains(4_000_000))
+
+	// remove the big value => BitSet
+	assert.True(t, p.UnSet(4_000_000))
+	assert.False(t, p.UnSet(4_000_000))
+	for i := range uint32(10) {
+		p.Set(i + 64)
+	}
+	assert.True(t, p.IsDense())
+
+	// remove most of the values => SliceSet
+	for i := range uint32(1004) {
+		p.Set(i)
+	}
+	for i := range uint32(1000) {
+		assert.True(t, p.UnSet(i))
+	}
+	assert.False(t, p.IsDense())
+	assert.Equal(t, []uint32{1000, 1001, 1002, 1003}, p.ToSlice())
+
+	for i := range uint32(1004) {
+		p.UnSet(i)
+	}
+	assert.True(t, p.IsEmpty())
+	assert.Equal(t, -1, p.Max())
+}
+
+// TestPosting_MixedOps compares the operations of sparse and dense Postings with the BitSet
+func TestPosting_MixedOps(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(7, 1))
+	random := func(n, maxValue int) (*Posting[uint32], *BitSet[uint32]) {
+		p, bs := NewPosting[uint32](), NewBitSet[uint32]()
+		for range n {
+			v := uint32(rnd.IntN(maxValue))
+			p.Set(v)
+			bs.Set(v)
+		}
+		return p, bs
+	}
+
+	type op struct {
+		name    string
+		posting func(a, b *Posting[uint32])
+		bitSet  func(a, b *BitSet[uint32])
+	}
+	ops := []op{
+		{"And", (*Posting[uint32]).And, (*BitSet[uint32]).And},
+		{"Or", (*Posting[uint32]).Or, (*BitSet[uint32]).Or},
+		{"AndNot", (*Posting[uint32]).AndNot, (*BitSet[uint32]).AndNot},
+	}
+
+	// sparse, dense and empty Postings
+	sizes := []struct{ n, maxValue int }{{20, 100_000}, {2_000, 4_000}, {0, 1}}
+
+	for _, sa := range sizes {
+		for _, sb := range sizes {
+			pa, bsa := random(sa.n, sa.maxValue)
+			pb, bsb := random(sb.n, sb.maxValue)
+			assert.Equal(t, sa.n == 2_000, pa.IsDense())
+			assert.Equal(t, bsa.ToSlice(), pa.ToSlice())
+			assert.Equal(t, bsa.CountAnd(bsb), pa.CountAnd(bsb))
+
+			for _, op := range ops {
+				p, bs := pa.Copy(), bsa.Copy()
+				op.posting(p, pb)
+				op.bitSet(bs, bsb)
+				assert.Equal(t, bs.Count(), p.Count(), op.name)
+				assert.Equal(t, bs.ToSlice(), p.ToSlice(), op.name)
+				assert.Equal(t, bs.ToSlice(), p.ToBitSet().ToSlice(), op.name)
+
+				// the other Posting is not changed
+				p.Set(99_999)
+				assert.Equal(t, bsb.ToSlice(), pb.ToSlice(), op.name)
+			}
+		}
+	}
+}
+
+func TestPosting_SortedIndex(t *testing.T) {
+	si := NewSortedIndex(func(c *car) string { return c.name }).(*SortedIndex[car, string, uint32])
+	si.Set(&car{name: "Opel"}, 4_000_000)
+	si.Set(&car{name: "Opel"}, 3)
+	for i := range uint32(100) {
+		si.Set(&car{name: "Mercedes"}, i)
+	}
+
+	opel, _ := si.sorted.Get("Opel")
+	assert.False(t, opel.IsDense())
+	mercedes, _ := si.sorted.Get("Mercedes")
+	assert.True(t, mercedes.IsDense())
+
+	bs, err := si.Match(OpEq, "Opel")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{3, 4_000_000}, bs.ToSlice())
+
+	bs, err = si.Match(OpGe, "N")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{3, 4_000_000}, bs.ToSlice())
+
+	bs, err = si.MatchMany(OpIn, "Opel", "Mercedes")
+	assert.NoError(t, err)
+	assert.Equal(t, 101, bs.Count())
+}
+
+// TestPosting_CombineInto compares And, Or and AndNot of a BitSet result with the postingLists and the BitSet
+func TestPosting_CombineInto(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(3, 1))
+	random := func(n, maxValue int) *BitSet[uint32] {
+		bs := NewBitSet[uint32]()
+		for range n {
+			bs.Set(uint32(rnd.IntN(maxValue)))
+		}
+		return bs
+	}
+
+	type op struct {
+		name    string
+		posting func(*BitSet[uint32], PostingList[uint32])
+		bitSet  func(a, b *BitSet[uint32])
+	}
+	ops := []op{
+		{"And", andPosting[uint32], (*BitSet[uint32]).And},
+		{"Or", orPosting[uint32], (*BitSet[uint32]).Or},
+		{"AndNot", andNotPosting[uint32], (*BitSet[uint32]).AndNot},
+	}
+
+	// sparse, dense, runs and empty values
+	values := []*BitSet[uint32]{random(20, 300_000), random(50_000, 100_000), random(0, 1)}
+	runs := random(10, 200_000)
+	for v := uint32(1_000); v < 70_000; v++ {
+		runs.Set(v)
+	}
+	values = append(values, runs)
+
+	for _, result := range values {
+		for _, other := range values {
+			roaring := NewRoaringFrom(other.ToSlice()...)
+			roaring.RunOptimize()
+			lists := map[string]PostingList[uint32]{
+				"BitSet":   other,
+				"Posting":  NewPostingFrom(other.ToSlice()...),
+				"Roaring":  roaring,
+				"SliceSet": NewSliceSetFrom(other.ToSlice()...),
+			}
+
+			for _, op := range ops {
+				expected := result.Copy()
+				op.bitSet(expected, other)
+
+				for name, list := range lists {
+					got := result.Copy()
+					op.posting(got, list)
+					assert.Equal(t, expected.ToSlice(), got.ToSlice(), op.name+" "+name)
+				}
+			}
+		}
+	}
+}
+
+func TestPosting_MapIndex(t *testing.T) {
+	mi := NewMapIndex(func(c *car) string { return c.name }).(*MapIndex[car, string, uint32])
+	mi.Set(&car{name: "Opel"}, 4_000_000)
+	mi.Set(&car{name: "Opel"}, 3)
+	for i := range uint32(100) {
+		mi.Set(&car{name: "Mercedes"}, i)
+	}
+
+	assert.False(t, mi.data["Opel"].IsDense())
+	assert.Less(t, mi.data["Opel"].usedBytes(), 64)
+	assert.True(t, mi.data["Mercedes"].IsDense())
+
+	bs, err := mi.Match(OpEq, "Opel")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{3, 4_000_000}, bs.ToSlice())
+
+	n, err := mi.Estimate(OpEq, "Opel")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// the query uses the saved Posting, without creating a BitSet
+	p, err := mi.matchPosting("Opel")
+	assert.NoError(t, err)
+	assert.Same(t, mi.data["Opel"], p)
+
+	for i := range uint32(100) {
+		mi.UnSet(&car{name: "Mercedes"}, i)
+	}
+	_, found := mi.data["Mercedes"]
+	assert.False(t, found)
+}
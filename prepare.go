@@ -0,0 +1,155 @@
+package fali
+
+import (
+	"slices"
+	"strconv"
+)
+
+// placeholder is a value in a query string, which is set with Bind: ? or :name
+type placeholder struct {
+	name  string // empty for: ?
+	index int    // the position of: ?
+}
+
+func (p placeholder) String() string {
+	if p.name != "" {
+		return ":" + p.name
+	}
+	return "?"
+}
+
+// NamedArg is the value for a named placeholder (:name), created with: Named
+type NamedArg struct {
+	Name  string
+	Value any
+}
+
+// Named creates the value for the placeholder :name
+func Named(name string, value any) NamedArg { return NamedArg{Name: name, Value: value} }
+
+// Prepared is a parsed and optimized query string with placeholders: ? or :name.
+// The query string is parsed once, the values for the placeholders are set with Bind.
+//
+//	p, err := Prepare(`name = ? and age > :age`)
+//	query, err := p.Bind("Opel", Named("age", uint8(22)))
+type Prepared struct {
+	parsed
+	names []string
+}
+
+// Prepare parses and optimizes the query string
+func Prepare(queryStr string) (*Prepared, error) {
+	parsed, err := parse(queryStr)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed.ast = optimize(parsed.ast)
+	p := &Prepared{parsed: parsed}
+	mapValues(p.ast, func(v any) any {
+		if ph, ok := v.(placeholder); ok && ph.name != "" && !slices.Contains(p.names, ph.name) {
+			p.names = append(p.names, ph.name)
+		}
+		return v
+	})
+
+	return p, nil
+}
+
+// Bind sets the values for the placeholders and returns the Query.
+// The args for ? are in the order of the placeholders, the args for :name are created with: Named.
+// The values are used with the given type, a cast like uint8(22) is not necessary.
+// A query string with ORDER BY, LIMIT or OFFSET returns the error: ErrStatementClause, use: BindStatement.
+func (p *Prepared) Bind(args ...any) (Query32, error) {
+	if clause := p.clause(); clause != "" {
+		return nil, ErrStatementClause{clause}
+	}
+	return p.bind(args...)
+}
+
+// BindStatement sets the values for the placeholders (see: Bind) and returns the Statement.
+func (p *Prepared) BindStatement(args ...any) (Statement, error) {
+	query, err := p.bind(args...)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	return Statement{
+		Select:  slices.Clone(p.selects),
+		GroupBy: p.groupBy,
+		Query:   query,
+		OrderBy: slices.Clone(p.orderBy),
+		Limit:   p.limit,
+		Offset:  p.offset,
+	}, nil
+}
+
+func (p *Prepared) bind(args ...any) (Query32, error) {
+	positional := make([]any, 0, len(args))
+	named := make(map[string]any)
+	for _, arg := range args {
+		if n, ok := arg.(NamedArg); ok {
+			if !slices.Contains(p.names, n.Name) {
+				return nil, ErrUnknownNamedArg{n.Name}
+			}
+			named[n.Name] = n.Value
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	if len(positional) != p.positional {
+		return nil, ErrInvalidArgsLen{defined: strconv.Itoa(p.positional), got: len(positional)}
+	}
+	for _, name := range p.names {
+		if _, found := named[name]; !found {
+			return nil, ErrMissingNamedArg{name}
+		}
+	}
+
+	ast := mapValues(p.ast, func(v any) any {
+		ph, ok := v.(placeholder)
+		switch {
+		case !ok:
+			return v
+		case ph.name != "":
+			return named[ph.name]
+		default:
+			return positional[ph.index]
+		}
+	})
+
+	if ast == nil {
+		// SELECT without a condition
+		return All(), nil
+	}
+	return compile(ast), nil
+}
+
+// mapValues returns a copy of the Expression, with the mapped values of all terms
+func mapValues(e Expr, fn func(any) any) Expr {
+	mapAll := func(values []any) []any {
+		mapped := make([]any, len(values))
+		for i, v := range values {
+			mapped[i] = fn(v)
+		}
+		return mapped
+	}
+
+	switch n := e.(type) {
+	case TermExpr:
+		n.Value = fn(n.Value)
+		return n
+	case TermManyExpr:
+		n.Values = mapAll(n.Values)
+		return n
+	case TermCompositeExpr:
+		n.Values = mapAll(n.Values)
+		return n
+	case NotExpr:
+		return NotExpr{Child: mapValues(n.Child, fn)}
+	case BinaryExpr:
+		return BinaryExpr{Op: n.Op, Left: mapValues(n.Left, fn), Right: mapValues(n.Right, fn)}
+	}
+	return e
+}
@@ -0,0 +1,99 @@
+package fali
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepare_Bind(t *testing.T) {
+	il := newPlannerList()
+
+	p, err := Prepare(`age >= ? and age < :max and isnew = :isnew`)
+	assert.NoError(t, err)
+
+	query, err := p.Bind(uint8(2), Named("max", uint8(5)), Named("isnew", true))
+	assert.NoError(t, err)
+	qr, err := il.Query(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "C", age: 2, isNew: true}, {name: "E", age: 4, isNew: true}}, qr.Values())
+
+	// the prepared query can bind other values
+	query, err = p.Bind(Named("isnew", false), uint8(0), Named("max", uint8(10)))
+	assert.NoError(t, err)
+	qr, err = il.Query(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "D", age: 3}}, qr.Values())
+}
+
+func TestPrepare_Values(t *testing.T) {
+	il := newPlannerList()
+
+	tests := []struct {
+		query    string
+		args     []any
+		expected []string
+	}{
+		{`id = ?`, []any{"B"}, []string{"B"}},
+		{`name contains ?`, []any{"C"}, []string{"C"}},
+		{`age in (?, ?, uint8(7))`, []any{uint8(1), uint8(3)}, []string{"B", "D", "H"}},
+		{`age between [?, ?[`, []any{uint8(1), uint8(3)}, []string{"B", "C"}},
+		{`not age > :v and age >= :v`, []any{Named("v", uint8(18))}, []string{"S"}},
+		{`isnew = ? or id = ?`, []any{false, "A"}, []string{"A", "D"}},
+		// the placeholder is a value, not a part of the query
+		{`id = ?`, []any{`"A" or id = "B"`}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			p, err := Prepare(tt.query)
+			assert.NoError(t, err)
+			query, err := p.Bind(tt.args...)
+			assert.NoError(t, err)
+
+			qr, err := il.Query(query)
+			if len(tt.expected) == 0 {
+				assert.ErrorIs(t, err, ErrValueNotFound{tt.args[0]})
+				return
+			}
+			assert.NoError(t, err)
+
+			names := make([]string, 0)
+			for _, c := range qr.Values() {
+				names = append(names, c.name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestPrepare_Errors(t *testing.T) {
+	p, err := Prepare(`age = ? and name = :name`)
+	assert.NoError(t, err)
+
+	_, err = p.Bind(Named("name", "x"))
+	assert.ErrorIs(t, err, ErrInvalidArgsLen{defined: "1", got: 0})
+	_, err = p.Bind(1, 2, Named("name", "x"))
+	assert.ErrorIs(t, err, ErrInvalidArgsLen{defined: "1", got: 2})
+	_, err = p.Bind(1)
+	assert.ErrorIs(t, err, ErrMissingNamedArg{"name"})
+	_, err = p.Bind(1, Named("name", "x"), Named("age", 1))
+	assert.ErrorIs(t, err, ErrUnknownNamedArg{"age"})
+
+	// Parse without values for the placeholders
+	_, err = Parse(`age = ?`)
+	assert.ErrorIs(t, err, ErrInvalidArgsLen{defined: "1", got: 0})
+	_, err = Parse(`age = :age`)
+	assert.ErrorIs(t, err, ErrMissingNamedArg{"age"})
+
+	_, err = Prepare(`age = :`)
+	assert.Error(t, err)
+	_, err = Prepare(`? = 5`)
+	assert.Error(t, err)
+}
+
+func TestPrepare_Optimize(t *testing.T) {
+	p, err := Prepare(`age > ? and age < :max`)
+	assert.NoError(t, err)
+	assert.Equal(t, "age BETWEEN ]?, :max[", p.ast.(TermManyExpr).String())
+}
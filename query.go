@@ -1,11 +1,17 @@
-package main
+package fali
+
+import (
+	"maps"
+	"slices"
+)
 
 // Query32 supports only uint32 List-Indices
 type Query32 = Query[uint32]
 
 // Query is a filter function, find the correct Index an execute the Index.Get method
-// and returns a BitSet pointer
-type Query[LI Value] func(l FilterByName[LI], allIDs *BitSet[LI]) (bs *BitSet[LI], canMutate bool, err error)
+// and returns the List-Indices: a BitSet or the saved PostingList of an Index (e.g. for Eq), so it is not copied.
+// Only a BitSet with canMutate = true can be changed (see: ensureMutable).
+type Query[LI Value] func(l FilterByName[LI], allIDs *BitSet[LI]) (result PostingList[LI], canMutate bool, err error)
 
 // FilterByName32 supports only uint32 List-Indices
 type FilterByName32 = FilterByName[uint32]
@@ -18,19 +24,24 @@ func All() Query32 { return all[uint32]() }
 
 //go:inline
 func all[LI Value]() Query[LI] {
-	return func(_ FilterByName[LI], allIDs *BitSet[LI]) (_ *BitSet[LI], canMutate bool, _ error) {
+	return func(_ FilterByName[LI], allIDs *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
 		return allIDs, false, nil
 	}
 }
 
 //go:inline
 func match[LI Value](fieldName string, op Op, value any) Query[LI] {
-	return func(l FilterByName[LI], _ *BitSet[LI]) (_ *BitSet[LI], canMutate bool, _ error) {
+	return func(l FilterByName[LI], _ *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
 		filter, err := l(fieldName)
 		if err != nil {
 			return nil, false, err
 		}
 
+		if op == OpEq {
+			p, err := eqPosting(filter, value)
+			return p, false, err
+		}
+
 		bs, err := filter.Match(op, value)
 		return bs, false, err
 	}
@@ -38,7 +49,7 @@ func match[LI Value](fieldName string, op Op, value any) Query[LI] {
 
 //go:inline
 func matchMany[LI Value](fieldName string, op Op, values ...any) Query[LI] {
-	return func(l FilterByName[LI], _ *BitSet[LI]) (_ *BitSet[LI], canMutate bool, _ error) {
+	return func(l FilterByName[LI], _ *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
 		filter, err := l(fieldName)
 		if err != nil {
 			return nil, false, err
@@ -49,6 +60,94 @@ func matchMany[LI Value](fieldName string, op Op, values ...any) Query[LI] {
 	}
 }
 
+// EqAll is equality on all given fields: fieldName1 = val1 AND fieldName2 = val2 ...
+// If a CompositeIndex for these fields (or a subset of these fields) exists, it is used.
+func EqAll(values CompositeKey) Query32 { return matchComposite[uint32](values) }
+
+// matchComposite uses the CompositeIndices with the most fields of the CompositeKey (see: compositeParts),
+// the parts are combined with And (the most selective part first).
+//
+//go:inline
+func matchComposite[LI Value](values CompositeKey) Query[LI] {
+	return func(l FilterByName[LI], allIDs *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
+		parts := compositeParts(l, values)
+		switch len(parts) {
+		case 0:
+			return allIDs, false, nil
+		case 1:
+			return compositeQuery[LI](parts[0])(l, allIDs)
+		}
+
+		plans := make([]plan[LI], len(parts))
+		for i, part := range parts {
+			plans[i] = plan[LI]{query: compositeQuery[LI](part), estimate: compositeEstimate[LI](part)}
+		}
+		return planAnd(plans...).query(l, allIDs)
+	}
+}
+
+// compositePart are equality terms, which are executed with one Filter:
+// a CompositeIndex (the name is the compositeName of the fields) or the Index of one field.
+type compositePart struct {
+	name   string
+	values CompositeKey
+}
+
+// compositeParts splits the CompositeKey in parts: the fields of the largest registered CompositeIndex first,
+// then the largest CompositeIndex of the remaining fields and so on.
+// Every field without a CompositeIndex is a part with one field.
+func compositeParts[LI Value](l FilterByName[LI], values CompositeKey) []compositePart {
+	fields := slices.Sorted(maps.Keys(values))
+	parts := make([]compositePart, 0, len(fields))
+
+	composites := registeredComposites(l)
+	for len(fields) >= 2 && len(composites) > 0 {
+		found := largestComposite(composites, func(f string) bool { return slices.Contains(fields, f) })
+		if found == nil {
+			break
+		}
+
+		part := compositePart{name: compositeName(found), values: make(CompositeKey, len(found))}
+		for _, field := range found {
+			part.values[field] = values[field]
+		}
+		parts = append(parts, part)
+		fields = slices.DeleteFunc(fields, func(f string) bool { return slices.Contains(found, f) })
+	}
+
+	for _, field := range fields {
+		parts = append(parts, compositePart{name: field, values: CompositeKey{field: values[field]}})
+	}
+	return parts
+}
+
+//go:inline
+func compositeQuery[LI Value](p compositePart) Query[LI] {
+	if len(p.values) == 1 {
+		return match[LI](p.name, OpEq, p.values[p.name])
+	}
+	return match[LI](p.name, OpEq, p.values)
+}
+
+//go:inline
+func compositeEstimate[LI Value](p compositePart) estimateFn[LI] {
+	if len(p.values) == 1 {
+		return estimateTerm[LI](p.name, OpEq, p.values[p.name])
+	}
+
+	return func(l FilterByName[LI]) (int, error) {
+		filter, err := l(p.name)
+		if err != nil {
+			return 0, err
+		}
+		e, ok := filter.(Estimator)
+		if !ok {
+			return unknownCost, nil
+		}
+		return e.Estimate(OpEq, p.values)
+	}
+}
+
 // ID id = val
 func ID(val any) Query32 { return match[uint32](IDIndexFieldName, OpEq, val) }
 
@@ -67,19 +166,66 @@ func Gt(fieldName string, val any) Query32 { return match[uint32](fieldName, OpG
 // Ge Greater Equal fieldName >= val
 func Ge(fieldName string, val any) Query32 { return match[uint32](fieldName, OpGe, val) }
 
+// Between fieldName BETWEEN [from, to], the bounds are included
+func Between(fieldName string, from, to any) Query32 {
+	return matchRange[uint32](fieldName, from, to, true, true)
+}
+
+// Range fieldName BETWEEN from and to, fromIncl and toIncl defines, are the bounds included or excluded:
+// Range("age", 10, 20, true, false) => 10 <= age < 20
+func Range(fieldName string, from, to any, fromIncl, toIncl bool) Query32 {
+	return matchRange[uint32](fieldName, from, to, fromIncl, toIncl)
+}
+
+//go:inline
+func matchRange[LI Value](fieldName string, from, to any, fromIncl, toIncl bool) Query[LI] {
+	return func(l FilterByName[LI], _ *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
+		filter, err := l(fieldName)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if rf, ok := filter.(RangeFilter[LI]); ok {
+			bs, err := rf.MatchRange(from, to, fromIncl, toIncl)
+			return bs, false, err
+		}
+
+		bs, err := filter.MatchMany(OpBetween, from, to)
+		if err != nil || (fromIncl && toIncl) {
+			return bs, false, err
+		}
+
+		// remove the excluded bounds
+		bs = bs.Copy()
+		for _, bound := range []struct {
+			value    any
+			excluded bool
+		}{{from, !fromIncl}, {to, !toIncl}} {
+			if bound.excluded {
+				eq, err := eqPosting(filter, bound.value)
+				if err != nil {
+					return nil, false, err
+				}
+				andNotPosting(bs, eq)
+			}
+		}
+		return bs, true, nil
+	}
+}
+
 // IsNil is a Query which checks for a given type the nil value
 func IsNil[V any](fieldName string) Query32 { return isNil[V, uint32](fieldName) }
 
 //go:inline
 func isNil[V any, LI Value](fieldName string) Query[LI] {
-	return func(l FilterByName[LI], _ *BitSet[LI]) (_ *BitSet[LI], canMutate bool, _ error) {
+	return func(l FilterByName[LI], _ *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
 		filter, err := l(fieldName)
 		if err != nil {
 			return nil, false, err
 		}
 
-		bs, err := filter.Match(OpEq, (*V)(nil))
-		return bs, false, err
+		p, err := eqPosting(filter, (*V)(nil))
+		return p, false, err
 	}
 }
 
@@ -89,7 +235,7 @@ func In(fieldName string, vals ...any) Query32 { return in[uint32](fieldName, va
 
 //go:inline
 func in[LI Value](fieldName string, vals ...any) Query[LI] {
-	return func(l FilterByName[LI], _ *BitSet[LI]) (_ *BitSet[LI], canMutate bool, _ error) {
+	return func(l FilterByName[LI], _ *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
 		if len(vals) == 0 {
 			return NewBitSet[LI](), true, nil
 		}
@@ -99,40 +245,46 @@ func in[LI Value](fieldName string, vals ...any) Query[LI] {
 			return nil, false, err
 		}
 
-		bs, err := filter.Match(OpEq, vals[0])
-		if err != nil {
-			return nil, false, err
-		}
-
 		if len(vals) == 1 {
-			return bs, false, nil
+			p, err := eqPosting(filter, vals[0])
+			return p, false, err
 		}
 
-		bs = bs.Copy()
-		for _, val := range vals[1:] {
-			bsGet, err := filter.Match(OpEq, val)
+		bs := NewBitSet[LI]()
+		for _, val := range vals {
+			p, err := eqPosting(filter, val)
 			if err != nil {
 				return nil, false, err
 			}
-			bs.Or(bsGet)
+			orPosting(bs, p)
 		}
 
 		return bs, true, nil
 	}
 }
 
+// HasAny fieldName HAS ANY (val1, val2, ...), the field contains one of the values (see: MultiValueIndex)
+func HasAny(fieldName string, vals ...any) Query32 {
+	return matchMany[uint32](fieldName, OpHasAny, vals...)
+}
+
+// HasAll fieldName HAS ALL (val1, val2, ...), the field contains all values (see: MultiValueIndex)
+func HasAll(fieldName string, vals ...any) Query32 {
+	return matchMany[uint32](fieldName, OpHasAll, vals...)
+}
+
 // NotEq is a shorcut for Not(Eq(...))
 func NotEq(fieldName string, val any) Query32 { return notEq[uint32](fieldName, val) }
 
 //go:inline
 func notEq[LI Value](fieldName string, val any) Query[LI] {
-	return func(l FilterByName[LI], allIDs *BitSet[LI]) (_ *BitSet[LI], canMutate bool, _ error) {
+	return func(l FilterByName[LI], allIDs *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
 		filter, err := l(fieldName)
 		if err != nil {
 			return nil, false, err
 		}
 
-		exclude, err := filter.Match(OpEq, val)
+		exclude, err := eqPosting(filter, val)
 		if err != nil {
 			return nil, false, err
 		}
@@ -143,14 +295,14 @@ func notEq[LI Value](fieldName string, val any) Query[LI] {
 		}
 
 		result := allIDs.Copy()
-		result.AndNot(exclude)
+		andNotPosting(result, exclude)
 		return result, true, nil
 	}
 }
 
 // Not Not(Query)
 func Not[LI Value](q Query[LI]) Query[LI] {
-	return func(l FilterByName[LI], allIDs *BitSet[LI]) (_ *BitSet[LI], canMutate bool, _ error) {
+	return func(l FilterByName[LI], allIDs *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
 		// can Mutate is not relevant, because allIDs are copied
 		qres, _, err := q(l, allIDs)
 		if err != nil {
@@ -159,62 +311,63 @@ func Not[LI Value](q Query[LI]) Query[LI] {
 
 		// maybe i can change the copy?
 		result := allIDs.Copy()
-		result.AndNot(qres)
+		andNotPosting(result, qres)
 		return result, true, nil
 	}
 }
 
-// Eq fieldName = val
+// Contains fieldName CONTAINS substr
+func Contains(fieldName string, substr string) Query32 {
+	return match[uint32](fieldName, OpContains, substr)
+}
+
+// WithPrefix fieldName STARTSWITH val
 func WithPrefix(fieldName string, val string) Query32 {
 	return match[uint32](fieldName, OpStartsWith, val)
 }
 
-// And combines 2 or more queries with an logical And
+// And combines 2 or more queries with an logical And.
+// The queries are executed in the given order, they are not ordered by the estimate like a parsed query (see: Parse).
+// If the intermediate result is empty, the remaining queries are not executed.
 func And[LI Value](a Query[LI], b Query[LI], other ...Query[LI]) Query[LI] {
-	return func(l FilterByName[LI], allIDs *BitSet[LI]) (_ *BitSet[LI], canMutate bool, _ error) {
+	return func(l FilterByName[LI], allIDs *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
 		result, err := ensureMutable(a(l, allIDs))
 		if err != nil {
 			return nil, false, err
 		}
-		right, _, err := b(l, allIDs)
-		if err != nil {
-			return nil, false, err
-		}
 
-		result.And(right)
-		// others, if there
-		for _, o := range other {
-			next, _, err := o(l, allIDs)
+		for _, q := range append([]Query[LI]{b}, other...) {
+			// early exit, the result can not be changed anymore
+			if result.IsEmpty() {
+				break
+			}
+
+			next, _, err := q(l, allIDs)
 			if err != nil {
 				return nil, false, err
 			}
-			result.And(next)
+			andPosting(result, next)
 		}
 
 		return result, true, nil
 	}
 }
 
-// Or combines 2 or more queries with an logical Or
+// Or combines 2 or more queries with an logical Or.
+// Like And, the queries are executed in the given order, only a parsed query is planned (see: Parse).
 func Or[LI Value](a Query[LI], b Query[LI], other ...Query[LI]) Query[LI] {
-	return func(l FilterByName[LI], allIDs *BitSet[LI]) (_ *BitSet[LI], canMutate bool, _ error) {
+	return func(l FilterByName[LI], allIDs *BitSet[LI]) (_ PostingList[LI], canMutate bool, _ error) {
 		result, err := ensureMutable(a(l, allIDs))
 		if err != nil {
 			return nil, false, err
 		}
-		right, _, err := b(l, allIDs)
-		if err != nil {
-			return nil, false, err
-		}
 
-		result.Or(right)
-		// others, if there
-		for _, o := range other {
-			next, _, err := o(l, allIDs)
+		for _, q := range append([]Query[LI]{b}, other...) {
+			next, _, err := q(l, allIDs)
 			if err != nil {
 				return nil, false, err
 			}
-			result.Or(next)
+			orPosting(result, next)
 		}
 
 		return result, true, nil
@@ -224,7 +377,7 @@ func Or[LI Value](a Query[LI], b Query[LI], other ...Query[LI]) Query[LI] {
 // AndNot performs: baseQuery AND NOT(subQuery)
 // example: status = 'active' AND type != 'guest'
 func AndNot[LI Value](base Query[LI], sub Query[LI]) Query[LI] {
-	return func(l FilterByName[LI], allIDs *BitSet[LI]) (*BitSet[LI], bool, error) {
+	return func(l FilterByName[LI], allIDs *BitSet[LI]) (PostingList[LI], bool, error) {
 		// base result (e.g., the 'active')
 		result, canMutate, err := base(l, allIDs)
 		if err != nil {
@@ -232,7 +385,7 @@ func AndNot[LI Value](base Query[LI], sub Query[LI]) Query[LI] {
 		}
 
 		// early return, if result is false (empty), stop immediately
-		if result.IsEmpty() {
+		if result.Count() == 0 {
 			return result, canMutate, nil
 		}
 
@@ -242,14 +395,14 @@ func AndNot[LI Value](base Query[LI], sub Query[LI]) Query[LI] {
 			return nil, false, err
 		}
 
-		result, err = ensureMutable(result, canMutate, nil)
+		mutable, err := ensureMutable(result, canMutate, nil)
 		if err != nil {
 			return nil, false, err
 		}
 
-		result.AndNot(exclude)
+		andNotPosting(mutable, exclude)
 
-		return result, true, nil
+		return mutable, true, nil
 	}
 }
 
@@ -257,14 +410,18 @@ func AndNot[LI Value](base Query[LI], sub Query[LI]) Query[LI] {
 // only copy, if not mutable
 //
 //go:inline
-func ensureMutable[LI Value](b *BitSet[LI], canMutate bool, err error) (*BitSet[LI], error) {
+func ensureMutable[LI Value](p PostingList[LI], canMutate bool, err error) (*BitSet[LI], error) {
 	if err != nil {
 		return nil, err
 	}
 
-	if canMutate {
-		return b, nil
+	bs, ok := p.(*BitSet[LI])
+	switch {
+	case !ok:
+		return toBitSet(p), nil
+	case canMutate:
+		return bs, nil
 	}
 
-	return b.Copy(), nil
+	return bs.Copy(), nil
 }
@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"testing"
@@ -266,3 +266,41 @@ func TestSortedIndex_StringStartsWith(t *testing.T) {
 	assert.False(t, canMutate)
 	assert.Equal(t, []uint32{1, 5, 42}, result.ToSlice())
 }
+
+// TestQuery_Postings the Eq Queries return the saved PostingList, only the And result is a BitSet
+func TestQuery_Postings(t *testing.T) {
+	names := NewMapIndex((*car).Name).(*MapIndex[car, string, uint32])
+	set(names, car{name: "Opel"}, 4_000_000)
+	set(names, car{name: "Opel"}, 3)
+	ages := NewSortedIndex((*car).Age).(*SortedIndex[car, uint8, uint32])
+	set(ages, car{age: 1}, 3)
+	set(ages, car{age: 1}, 5)
+
+	l := func(fieldName string) (Filter32, error) {
+		switch fieldName {
+		case "name":
+			return names, nil
+		case "age":
+			return ages, nil
+		}
+		return nil, ErrInvalidIndexdName{fieldName}
+	}
+
+	result, canMutate, err := Eq("name", "Opel")(l, nil)
+	assert.NoError(t, err)
+	assert.False(t, canMutate)
+	assert.Same(t, names.data["Opel"], result)
+
+	queries := []Query32{
+		And(Eq("age", uint8(1)), Eq("name", "Opel")),
+		compile(BinaryExpr{Op: ExprAnd, Left: TermExpr{Field: "age", Op: OpEq, Value: uint8(1)}, Right: TermExpr{Field: "name", Op: OpEq, Value: "Opel"}}),
+	}
+	for _, q := range queries {
+		result, canMutate, err = q(l, nil)
+		assert.NoError(t, err)
+		assert.True(t, canMutate)
+		assert.Equal(t, []uint32{3}, result.ToSlice())
+		// the Posting with 4_000_000 is not copied to a BitSet
+		assert.Less(t, result.(*BitSet[uint32]).usedBytes(), 64)
+	}
+}
@@ -0,0 +1,729 @@
+package fali
+
+import (
+	"cmp"
+	"math/bits"
+	"slices"
+)
+
+// Roaring is a compressed BitSet. The values are split in chunks of 2^16 values (the high bits are the key),
+// every chunk is a container with the low 16 bits of the values:
+//   - array: a sorted slice, for sparse chunks (max 4096 values)
+//   - bitmap: 1024 words, for dense chunks
+//   - run: a sorted slice of intervals, for consecutive values (see: RunOptimize)
+//
+// So a single value 4_000_000 needs some bytes, and not ~500KB like the BitSet.
+type Roaring[V Value] struct {
+	keys       []uint64
+	containers []*container
+}
+
+// NewRoaring creates a new Roaring BitSet
+func NewRoaring[V Value]() *Roaring[V] {
+	return &Roaring[V]{}
+}
+
+// NewRoaringFrom creates a new Roaring BitSet from given values
+func NewRoaringFrom[V Value](values ...V) *Roaring[V] {
+	r := NewRoaring[V]()
+	for _, v := range values {
+		r.Set(v)
+	}
+	return r
+}
+
+//go:inline
+func splitValue[V Value](value V) (uint64, uint16) {
+	return uint64(value) >> 16, uint16(value)
+}
+
+//go:inline
+func (r *Roaring[V]) container(key uint64) (int, bool) {
+	return slices.BinarySearch(r.keys, key)
+}
+
+// Set inserts the value in the Roaring BitSet
+func (r *Roaring[V]) Set(value V) {
+	key, low := splitValue(value)
+	i, found := r.container(key)
+	if !found {
+		r.keys = slices.Insert(r.keys, i, key)
+		r.containers = slices.Insert(r.containers, i, &container{array: []uint16{low}})
+		return
+	}
+	r.containers[i].add(low)
+}
+
+// UnSet removes the value, returns false, if the value was not found
+func (r *Roaring[V]) UnSet(value V) bool {
+	key, low := splitValue(value)
+	i, found := r.container(key)
+	if !found {
+		return false
+	}
+
+	c := r.containers[i]
+	removed := c.remove(low)
+	if c.count() == 0 {
+		r.keys = slices.Delete(r.keys, i, i+1)
+		r.containers = slices.Delete(r.containers, i, i+1)
+	}
+	return removed
+}
+
+// Contains check, is the value saved in the Roaring BitSet
+func (r *Roaring[V]) Contains(value V) bool {
+	key, low := splitValue(value)
+	i, found := r.container(key)
+	return found && r.containers[i].contains(low)
+}
+
+// Count counts how many values are in the Roaring BitSet
+func (r *Roaring[V]) Count() int {
+	count := 0
+	for _, c := range r.containers {
+		count += c.count()
+	}
+	return count
+}
+
+// IsEmpty there are no values, means Count() == 0
+func (r *Roaring[V]) IsEmpty() bool { return len(r.keys) == 0 }
+
+// Min return the min value, if no value found, return -1
+func (r *Roaring[V]) Min() int {
+	if len(r.keys) == 0 {
+		return -1
+	}
+	return int(r.keys[0]<<16) + int(r.containers[0].min())
+}
+
+// Max return the max value, if no value found, return -1
+func (r *Roaring[V]) Max() int {
+	l := len(r.keys)
+	if l == 0 {
+		return -1
+	}
+	return int(r.keys[l-1]<<16) + int(r.containers[l-1].max())
+}
+
+// Range iterates over the values between 'from' and 'to' (inclusive).
+// It calls 'visit' for each found value. If 'visit' returns false, iteration stops.
+func (r *Roaring[V]) Range(from, to V, visit func(v uint32) bool) {
+	if from > to {
+		return
+	}
+
+	fromKey, fromLow := splitValue(from)
+	i, _ := r.container(fromKey)
+	for ; i < len(r.keys); i++ {
+		key := r.keys[i]
+		start := uint16(0)
+		if key == fromKey {
+			start = fromLow
+		}
+
+		base := key << 16
+		proceed := r.containers[i].each(start, func(low uint16) bool {
+			v := base | uint64(low)
+			if v > uint64(to) {
+				return false
+			}
+			return visit(uint32(v))
+		})
+		if !proceed {
+			return
+		}
+	}
+}
+
+// Values iterate over the complete Roaring BitSet and call the yield function, for every value
+func (r *Roaring[V]) Values(yield func(V) bool) {
+	for i, c := range r.containers {
+		base := r.keys[i] << 16
+		if !c.each(0, func(low uint16) bool { return yield(V(base | uint64(low))) }) {
+			return
+		}
+	}
+}
+
+// ToSlice create a new slice which contains all saved values
+func (r *Roaring[V]) ToSlice() []V {
+	res := make([]V, 0, r.Count())
+	r.Values(func(v V) bool {
+		res = append(res, v)
+		return true
+	})
+	return res
+}
+
+// ToBitSet creates a BitSet with the same values
+func (r *Roaring[V]) ToBitSet() *BitSet[V] {
+	bs := NewBitSet[V]()
+	r.orInto(bs)
+	return bs
+}
+
+// orInto adds the values of the Roaring BitSet to the BitSet
+func (r *Roaring[V]) orInto(bs *BitSet[V]) {
+	if maxValue := r.Max(); maxValue >= 0 {
+		bs.grow(maxValue >> 6)
+	}
+
+	for i, c := range r.containers {
+		offset := int(r.keys[i] << 10)
+		words := bs.data[offset:min(offset+bitmapWords, len(bs.data))]
+		if c.kind == bitmapKind {
+			for j := range words {
+				words[j] |= c.bitmap[j]
+			}
+			continue
+		}
+		c.each(0, func(low uint16) bool {
+			words[low>>6] |= 1 << (low & 63)
+			return true
+		})
+	}
+}
+
+// andInto removes all values from the BitSet, which are not in the Roaring BitSet
+func (r *Roaring[V]) andInto(bs *BitSet[V]) {
+	next := 0 // the first word of the BitSet, which is not checked
+	for i, c := range r.containers {
+		offset := int(r.keys[i] << 10)
+		if offset >= len(bs.data) {
+			break
+		}
+		clear(bs.data[next:offset])
+
+		words := bs.data[offset:min(offset+bitmapWords, len(bs.data))]
+		next = offset + len(words)
+		if c.kind == bitmapKind {
+			for j := range words {
+				words[j] &= c.bitmap[j]
+			}
+			continue
+		}
+
+		var mask [bitmapWords]uint64
+		c.each(0, func(low uint16) bool {
+			mask[low>>6] |= 1 << (low & 63)
+			return true
+		})
+		for j := range words {
+			words[j] &= mask[j]
+		}
+	}
+	clear(bs.data[next:])
+}
+
+// andNotInto removes the values of the Roaring BitSet from the BitSet
+func (r *Roaring[V]) andNotInto(bs *BitSet[V]) {
+	for i, c := range r.containers {
+		offset := int(r.keys[i] << 10)
+		if offset >= len(bs.data) {
+			return
+		}
+
+		words := bs.data[offset:min(offset+bitmapWords, len(bs.data))]
+		if c.kind == bitmapKind {
+			for j := range words {
+				words[j] &^= c.bitmap[j]
+			}
+			continue
+		}
+		c.each(0, func(low uint16) bool {
+			if int(low>>6) < len(words) {
+				words[low>>6] &^= 1 << (low & 63)
+			}
+			return true
+		})
+	}
+}
+
+// Copy copy the complete Roaring BitSet.
+func (r *Roaring[V]) Copy() *Roaring[V] {
+	containers := make([]*container, len(r.containers))
+	for i, c := range r.containers {
+		containers[i] = c.clone()
+	}
+	return &Roaring[V]{keys: slices.Clone(r.keys), containers: containers}
+}
+
+// RunOptimize converts the containers to run containers, if they need less memory
+func (r *Roaring[V]) RunOptimize() {
+	for _, c := range r.containers {
+		c.runOptimize()
+	}
+}
+
+// And is the logical AND of two Roaring BitSets
+// In this Roaring BitSet is the result, this means the values will be overwritten!
+func (r *Roaring[V]) And(other *Roaring[V]) {
+	keys := r.keys[:0]
+	containers := r.containers[:0]
+
+	i, j := 0, 0
+	for i < len(r.keys) && j < len(other.keys) {
+		switch cmp.Compare(r.keys[i], other.keys[j]) {
+		case -1:
+			i++
+		case 1:
+			j++
+		default:
+			if c := r.containers[i].and(other.containers[j]); c != nil {
+				keys = append(keys, r.keys[i])
+				containers = append(containers, c)
+			}
+			i++
+			j++
+		}
+	}
+
+	clear(r.containers[len(containers):])
+	r.keys, r.containers = keys, containers
+}
+
+// Or is the logical OR of two Roaring BitSets
+func (r *Roaring[V]) Or(other *Roaring[V]) {
+	r.merge(other, true, (*container).or)
+}
+
+// Xor is the logical XOR of two Roaring BitSets
+func (r *Roaring[V]) Xor(other *Roaring[V]) {
+	r.merge(other, true, (*container).xor)
+}
+
+// AndNot removes all elements from the current set that exist in another set.
+func (r *Roaring[V]) AndNot(other *Roaring[V]) {
+	r.merge(other, false, (*container).andNot)
+}
+
+// merge combines the containers with the same key with the op,
+// the containers which are only in the other Roaring BitSet are copied, if addOther is true
+func (r *Roaring[V]) merge(other *Roaring[V], addOther bool, op func(c, o *container) *container) {
+	keys := make([]uint64, 0, len(r.keys)+len(other.keys))
+	containers := make([]*container, 0, cap(keys))
+
+	i, j := 0, 0
+	for i < len(r.keys) || j < len(other.keys) {
+		switch {
+		case j == len(other.keys) || (i < len(r.keys) && r.keys[i] < other.keys[j]):
+			keys = append(keys, r.keys[i])
+			containers = append(containers, r.containers[i])
+			i++
+		case i == len(r.keys) || other.keys[j] < r.keys[i]:
+			if addOther {
+				keys = append(keys, other.keys[j])
+				containers = append(containers, other.containers[j].clone())
+			}
+			j++
+		default:
+			if c := op(r.containers[i], other.containers[j]); c != nil {
+				keys = append(keys, r.keys[i])
+				containers = append(containers, c)
+			}
+			i++
+			j++
+		}
+	}
+
+	r.keys, r.containers = keys, containers
+}
+
+// how many bytes is using
+func (r *Roaring[V]) usedBytes() int {
+	size := 48 + len(r.keys)*8
+	for _, c := range r.containers {
+		size += 8 + c.usedBytes()
+	}
+	return size
+}
+
+const (
+	// arrayMaxSize is the max number of values in an array container, more values are saved in a bitmap container
+	arrayMaxSize = 4096
+	// bitmapWords are the number of words for 2^16 values
+	bitmapWords = 1 << 16 / 64
+)
+
+type containerKind uint8
+
+const (
+	arrayKind containerKind = iota
+	bitmapKind
+	runKind
+)
+
+// interval are the values: start, start+1, ... start+length
+type interval struct {
+	start  uint16
+	length uint16
+}
+
+//go:inline
+func (iv interval) last() uint16 { return iv.start + iv.length }
+
+// container contains the low 16 bits of the values of one chunk
+type container struct {
+	kind   containerKind
+	array  []uint16   // arrayKind: the sorted values
+	bitmap []uint64   // bitmapKind: bitmapWords words
+	card   int        // bitmapKind: the number of values
+	runs   []interval // runKind: the sorted intervals
+}
+
+// newContainer creates an array or a bitmap container from the bitmap, returns nil, if the bitmap is empty
+func newContainer(bitmap []uint64) *container {
+	card := 0
+	for _, w := range bitmap {
+		card += bits.OnesCount64(w)
+	}
+
+	switch {
+	case card == 0:
+		return nil
+	case card > arrayMaxSize:
+		return &container{kind: bitmapKind, bitmap: bitmap, card: card}
+	default:
+		c := &container{kind: bitmapKind, bitmap: bitmap, card: card}
+		c.toArray()
+		return c
+	}
+}
+
+func (c *container) count() int {
+	switch c.kind {
+	case bitmapKind:
+		return c.card
+	case runKind:
+		count := 0
+		for _, iv := range c.runs {
+			count += int(iv.length) + 1
+		}
+		return count
+	default:
+		return len(c.array)
+	}
+}
+
+func (c *container) contains(low uint16) bool {
+	switch c.kind {
+	case bitmapKind:
+		return c.bitmap[low>>6]&(1<<(low&63)) != 0
+	case runKind:
+		i, found := slices.BinarySearchFunc(c.runs, low, func(iv interval, v uint16) int { return cmp.Compare(iv.start, v) })
+		return found || (i > 0 && low <= c.runs[i-1].last())
+	default:
+		_, found := slices.BinarySearch(c.array, low)
+		return found
+	}
+}
+
+func (c *container) add(low uint16) {
+	if c.kind == runKind {
+		c.fromRuns()
+	}
+
+	if c.kind == bitmapKind {
+		bit := uint64(1) << (low & 63)
+		if c.bitmap[low>>6]&bit == 0 {
+			c.bitmap[low>>6] |= bit
+			c.card++
+		}
+		return
+	}
+
+	i, found := slices.BinarySearch(c.array, low)
+	if found {
+		return
+	}
+	if len(c.array) < arrayMaxSize {
+		c.array = slices.Insert(c.array, i, low)
+		return
+	}
+	c.toBitmap()
+	c.add(low)
+}
+
+func (c *container) remove(low uint16) bool {
+	if c.kind == runKind {
+		c.fromRuns()
+	}
+
+	if c.kind == bitmapKind {
+		bit := uint64(1) << (low & 63)
+		if c.bitmap[low>>6]&bit == 0 {
+			return false
+		}
+		c.bitmap[low>>6] &^= bit
+		c.card--
+		if c.card <= arrayMaxSize {
+			c.toArray()
+		}
+		return true
+	}
+
+	i, found := slices.BinarySearch(c.array, low)
+	if found {
+		c.array = slices.Delete(c.array, i, i+1)
+	}
+	return found
+}
+
+func (c *container) min() uint16 {
+	switch c.kind {
+	case bitmapKind:
+		for i, w := range c.bitmap {
+			if w != 0 {
+				return uint16(i<<6 + bits.TrailingZeros64(w))
+			}
+		}
+		return 0
+	case runKind:
+		return c.runs[0].start
+	default:
+		return c.array[0]
+	}
+}
+
+func (c *container) max() uint16 {
+	switch c.kind {
+	case bitmapKind:
+		for i := len(c.bitmap) - 1; i >= 0; i-- {
+			if w := c.bitmap[i]; w != 0 {
+				return uint16(i<<6 + bits.Len64(w) - 1)
+			}
+		}
+		return 0
+	case runKind:
+		return c.runs[len(c.runs)-1].last()
+	default:
+		return c.array[len(c.array)-1]
+	}
+}
+
+// each calls visit for every value, which is greater or equal than from, returns false, if visit returns false
+func (c *container) each(from uint16, visit func(uint16) bool) bool {
+	switch c.kind {
+	case bitmapKind:
+		for i := int(from >> 6); i < bitmapWords; i++ {
+			w := c.bitmap[i]
+			if i == int(from>>6) {
+				w &= ^uint64(0) << (from & 63)
+			}
+			for w != 0 {
+				if !visit(uint16(i<<6 + bits.TrailingZeros64(w))) {
+					return false
+				}
+				w &= w - 1
+			}
+		}
+	case runKind:
+		for _, iv := range c.runs {
+			if iv.last() < from {
+				continue
+			}
+			for v := int(max(iv.start, from)); v <= int(iv.last()); v++ {
+				if !visit(uint16(v)) {
+					return false
+				}
+			}
+		}
+	default:
+		i, _ := slices.BinarySearch(c.array, from)
+		for _, v := range c.array[i:] {
+			if !visit(v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// words returns the values as bitmap, the bitmap of a bitmap container is not copied, so it must not be changed
+func (c *container) words() []uint64 {
+	if c.kind == bitmapKind {
+		return c.bitmap
+	}
+	return c.copyWords()
+}
+
+// copyWords returns the values as new bitmap
+func (c *container) copyWords() []uint64 {
+	bitmap := make([]uint64, bitmapWords)
+	switch c.kind {
+	case bitmapKind:
+		copy(bitmap, c.bitmap)
+	case runKind:
+		for _, iv := range c.runs {
+			for v := int(iv.start); v <= int(iv.last()); v++ {
+				bitmap[v>>6] |= 1 << (v & 63)
+			}
+		}
+	default:
+		for _, v := range c.array {
+			bitmap[v>>6] |= 1 << (v & 63)
+		}
+	}
+	return bitmap
+}
+
+func (c *container) toBitmap() {
+	c.bitmap, c.card = c.copyWords(), c.count()
+	c.kind, c.array, c.runs = bitmapKind, nil, nil
+}
+
+func (c *container) toArray() {
+	array := make([]uint16, 0, c.count())
+	c.each(0, func(v uint16) bool {
+		array = append(array, v)
+		return true
+	})
+	c.kind, c.array, c.bitmap, c.card, c.runs = arrayKind, array, nil, 0, nil
+}
+
+// fromRuns converts a run container to an array or a bitmap container, which can be changed
+func (c *container) fromRuns() {
+	if c.count() <= arrayMaxSize {
+		c.toArray()
+	} else {
+		c.toBitmap()
+	}
+}
+
+// runOptimize converts the container to a run container, if the runs need less memory (and back)
+func (c *container) runOptimize() {
+	runs := make([]interval, 0, 8)
+	c.each(0, func(v uint16) bool {
+		if l := len(runs); l > 0 && int(runs[l-1].last())+1 == int(v) {
+			runs[l-1].length++
+		} else {
+			runs = append(runs, interval{start: v})
+		}
+		return true
+	})
+
+	// the bytes of an array or a bitmap container
+	size := 2 * c.count()
+	if size > 2*arrayMaxSize {
+		size = bitmapWords * 8
+	}
+
+	switch {
+	case 4*len(runs) < size:
+		c.kind, c.runs, c.array, c.bitmap, c.card = runKind, slices.Clip(runs), nil, nil, 0
+	case c.kind == runKind:
+		c.fromRuns()
+	}
+}
+
+func (c *container) clone() *container {
+	return &container{
+		kind:   c.kind,
+		array:  slices.Clone(c.array),
+		bitmap: slices.Clone(c.bitmap),
+		card:   c.card,
+		runs:   slices.Clone(c.runs),
+	}
+}
+
+func (c *container) usedBytes() int {
+	return 80 + len(c.array)*2 + len(c.bitmap)*8 + len(c.runs)*4
+}
+
+// and returns a new container with the values, which are in both containers, or nil, if there are no values
+func (c *container) and(o *container) *container {
+	switch {
+	case c.kind == arrayKind:
+		return filterArray(c.array, func(v uint16) bool { return o.contains(v) })
+	case o.kind == arrayKind:
+		return filterArray(o.array, func(v uint16) bool { return c.contains(v) })
+	}
+
+	bitmap, ow := c.copyWords(), o.words()
+	for i := range bitmap {
+		bitmap[i] &= ow[i]
+	}
+	return newContainer(bitmap)
+}
+
+// or returns a new container with the values, which are in one of the containers
+func (c *container) or(o *container) *container {
+	if c.kind == arrayKind && o.kind == arrayKind && len(c.array)+len(o.array) <= arrayMaxSize {
+		return &container{array: mergeArrays(c.array, o.array, true)}
+	}
+
+	bitmap, ow := c.copyWords(), o.words()
+	for i := range bitmap {
+		bitmap[i] |= ow[i]
+	}
+	return newContainer(bitmap)
+}
+
+// xor returns a new container with the values, which are only in one of the containers, or nil, if there are no values
+func (c *container) xor(o *container) *container {
+	if c.kind == arrayKind && o.kind == arrayKind && len(c.array)+len(o.array) <= arrayMaxSize {
+		if array := mergeArrays(c.array, o.array, false); len(array) > 0 {
+			return &container{array: array}
+		}
+		return nil
+	}
+
+	bitmap, ow := c.copyWords(), o.words()
+	for i := range bitmap {
+		bitmap[i] ^= ow[i]
+	}
+	return newContainer(bitmap)
+}
+
+// andNot returns a new container with the values, which are not in the other container, or nil, if there are no values
+func (c *container) andNot(o *container) *container {
+	if c.kind == arrayKind {
+		return filterArray(c.array, func(v uint16) bool { return !o.contains(v) })
+	}
+
+	bitmap, ow := c.copyWords(), o.words()
+	for i := range bitmap {
+		bitmap[i] &^= ow[i]
+	}
+	return newContainer(bitmap)
+}
+
+// filterArray creates an array container with the values, where keep returns true, or nil, if there are no values
+func filterArray(array []uint16, keep func(uint16) bool) *container {
+	var result []uint16
+	for _, v := range array {
+		if keep(v) {
+			result = append(result, v)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return &container{array: result}
+}
+
+// mergeArrays merges the sorted arrays, with all values (union is true) or with the values, which are only in one array (xor)
+func mergeArrays(a, b []uint16, union bool) []uint16 {
+	result := make([]uint16, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			result = append(result, a[i])
+			i++
+		case a[i] > b[j]:
+			result = append(result, b[j])
+			j++
+		default:
+			if union {
+				result = append(result, a[i])
+			}
+			i++
+			j++
+		}
+	}
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
+}
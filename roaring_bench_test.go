@@ -0,0 +1,142 @@
+package fali
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the benchmarks are the same as for the BitSet (bitset_bench_test.go)
+
+func newRoaringMod(mod int) *Roaring[uint32] {
+	r := NewRoaring[uint32]()
+	for i := 1; i <= count; i++ {
+		if i%mod == 0 {
+			r.Set(uint32(i))
+		}
+	}
+	return r
+}
+
+func BenchmarkRoaringContains(b *testing.B) {
+	r := newRoaringMod(1)
+	b.ResetTimer()
+
+	for b.Loop() {
+		assert.True(b, r.Contains(found_val))
+	}
+}
+
+func BenchmarkRoaringCount(b *testing.B) {
+	r := newRoaringMod(1)
+	b.ResetTimer()
+
+	for b.Loop() {
+		assert.Equal(b, count, r.Count())
+	}
+}
+
+func BenchmarkRoaringCountRuns(b *testing.B) {
+	r := newRoaringMod(1)
+	r.RunOptimize()
+	b.ResetTimer()
+
+	for b.Loop() {
+		assert.Equal(b, count, r.Count())
+	}
+}
+
+func BenchmarkRoaringAnd(b *testing.B) {
+	r1 := newRoaringMod(3)
+	r2 := newRoaringMod(6)
+	b.ResetTimer()
+
+	for b.Loop() {
+		r := r2.Copy()
+		r.And(r1)
+		assert.Equal(b, 500_000, r.Count())
+	}
+}
+
+func BenchmarkRoaringOr(b *testing.B) {
+	r1 := newRoaringMod(3)
+	r2 := newRoaringMod(6)
+	b.ResetTimer()
+
+	for b.Loop() {
+		r := r2.Copy()
+		r.Or(r1)
+		assert.Equal(b, count/3, r.Count())
+	}
+}
+
+func BenchmarkRoaringXor(b *testing.B) {
+	r1 := newRoaringMod(3)
+	r2 := newRoaringMod(6)
+	b.ResetTimer()
+
+	for b.Loop() {
+		r := r2.Copy()
+		r.Xor(r1)
+		assert.Equal(b, 500_000, r.Count())
+	}
+}
+
+func BenchmarkRoaringToSlice(b *testing.B) {
+	r := newRoaringMod(1)
+	b.ResetTimer()
+
+	for b.Loop() {
+		assert.Equal(b, count, len(r.ToSlice()))
+	}
+}
+
+func BenchmarkRoaringValuesIter(b *testing.B) {
+	r := newRoaringMod(1)
+	b.ResetTimer()
+
+	for b.Loop() {
+		c := 0
+		r.Values(func(v uint32) bool {
+			_ = v
+			c += 1
+			return true
+		})
+		assert.Equal(b, count, c)
+	}
+}
+
+func BenchmarkRoaringCopy(b *testing.B) {
+	r := newRoaringMod(1)
+	b.ResetTimer()
+
+	for b.Loop() {
+		rCopy := r.Copy()
+		assert.Equal(b, rCopy.Count(), r.Count())
+	}
+}
+
+func BenchmarkRoaringToBitSet(b *testing.B) {
+	r := newRoaringMod(1)
+	b.ResetTimer()
+
+	for b.Loop() {
+		assert.Equal(b, count, r.ToBitSet().Count())
+	}
+}
+
+func BenchmarkRoaringSparse(b *testing.B) {
+	for b.Loop() {
+		r := NewRoaring[uint32]()
+		r.Set(4_000_000)
+		assert.Equal(b, 1, r.Count())
+	}
+}
+
+func BenchmarkBitSetSparse(b *testing.B) {
+	for b.Loop() {
+		bs := NewBitSet[uint32]()
+		bs.Set(4_000_000)
+		assert.Equal(b, 1, bs.Count())
+	}
+}
@@ -0,0 +1,229 @@
+package fali
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoaring_Base(t *testing.T) {
+	r := NewRoaring[uint32]()
+	assert.False(t, r.Contains(0))
+	assert.True(t, r.IsEmpty())
+	assert.Equal(t, -1, r.Min())
+	assert.Equal(t, -1, r.Max())
+
+	r.Set(1)
+	r.Set(2)
+	r.Set(2)
+	r.Set(4_000_000)
+
+	assert.True(t, r.Contains(2))
+	assert.True(t, r.Contains(4_000_000))
+	assert.False(t, r.Contains(3))
+	assert.Equal(t, 3, r.Count())
+	assert.Equal(t, 1, r.Min())
+	assert.Equal(t, 4_000_000, r.Max())
+	assert.Equal(t, []uint32{1, 2, 4_000_000}, r.ToSlice())
+
+	// a single big value needs only some bytes
+	assert.Less(t, r.usedBytes(), 512)
+	assert.Greater(t, r.ToBitSet().usedBytes(), 500_000)
+
+	assert.True(t, r.UnSet(4_000_000))
+	assert.False(t, r.UnSet(4_000_000))
+	assert.False(t, r.UnSet(40_000_000))
+	assert.Equal(t, 2, r.Max())
+	assert.Equal(t, 1, len(r.keys))
+
+	assert.True(t, r.UnSet(1))
+	assert.True(t, r.UnSet(2))
+	assert.True(t, r.IsEmpty())
+}
+
+func TestRoaring_Containers(t *testing.T) {
+	r := NewRoaring[uint32]()
+	for i := range arrayMaxSize {
+		r.Set(uint32(i * 2))
+	}
+	assert.Equal(t, arrayKind, r.containers[0].kind)
+
+	// more than arrayMaxSize values => bitmap
+	r.Set(1)
+	assert.Equal(t, bitmapKind, r.containers[0].kind)
+	assert.Equal(t, arrayMaxSize+1, r.Count())
+	assert.True(t, r.Contains(1))
+
+	// back to array
+	r.UnSet(1)
+	assert.Equal(t, arrayKind, r.containers[0].kind)
+	assert.Equal(t, arrayMaxSize, r.Count())
+
+	// consecutive values => run
+	r = NewRoaring[uint32]()
+	for i := 100; i < 20_000; i++ {
+		r.Set(uint32(i))
+	}
+	r.RunOptimize()
+	assert.Equal(t, runKind, r.containers[0].kind)
+	assert.Equal(t, 19_900, r.Count())
+	assert.Equal(t, 100, r.Min())
+	assert.Equal(t, 19_999, r.Max())
+	assert.True(t, r.Contains(100))
+	assert.True(t, r.Contains(19_999))
+	assert.False(t, r.Contains(99))
+	assert.False(t, r.Contains(20_000))
+
+	// change a run container
+	assert.True(t, r.UnSet(500))
+	assert.Equal(t, bitmapKind, r.containers[0].kind)
+	assert.False(t, r.Contains(500))
+	assert.Equal(t, 19_899, r.Count())
+}
+
+func TestRoaring_Range(t *testing.T) {
+	r := NewRoaringFrom[uint32](0, 63, 64, 65, 130, 70_000, 70_001, 200_000)
+
+	var result []uint32
+	r.Range(63, 70_000, func(v uint32) bool {
+		result = append(result, v)
+		return true
+	})
+	assert.Equal(t, []uint32{63, 64, 65, 130, 70_000}, result)
+
+	result = nil
+	r.Range(66, 69_999, func(v uint32) bool {
+		result = append(result, v)
+		return true
+	})
+	assert.Equal(t, []uint32{130}, result)
+
+	result = nil
+	r.Range(64, 200_000, func(v uint32) bool {
+		result = append(result, v)
+		return len(result) < 2
+	})
+	assert.Equal(t, []uint32{64, 65}, result)
+
+	result = nil
+	r.Range(10, 1, func(v uint32) bool {
+		result = append(result, v)
+		return true
+	})
+	assert.Nil(t, result)
+}
+
+func TestRoaring_Ops(t *testing.T) {
+	a := NewRoaringFrom[uint32](1, 2, 110, 2345, 70_000, 200_000)
+	b := NewRoaringFrom[uint32](2, 110, 70_000, 300_000)
+
+	r := a.Copy()
+	r.And(b)
+	assert.Equal(t, []uint32{2, 110, 70_000}, r.ToSlice())
+
+	r = a.Copy()
+	r.Or(b)
+	assert.Equal(t, []uint32{1, 2, 110, 2345, 70_000, 200_000, 300_000}, r.ToSlice())
+
+	r = a.Copy()
+	r.Xor(b)
+	assert.Equal(t, []uint32{1, 2345, 200_000, 300_000}, r.ToSlice())
+
+	r = a.Copy()
+	r.AndNot(b)
+	assert.Equal(t, []uint32{1, 2345, 200_000}, r.ToSlice())
+
+	// the other Roaring BitSet is not changed
+	r.Set(300_001)
+	assert.Equal(t, []uint32{2, 110, 70_000, 300_000}, b.ToSlice())
+}
+
+// TestRoaring_CompareBitSet compares all operations with random values (sparse, dense and runs) with the BitSet
+func TestRoaring_CompareBitSet(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(42, 1))
+	random := func(n, maxValue int, runs bool) (*Roaring[uint32], *BitSet[uint32]) {
+		r, bs := NewRoaring[uint32](), NewBitSet[uint32]()
+		for range n {
+			v := uint32(rnd.IntN(maxValue))
+			r.Set(v)
+			bs.Set(v)
+		}
+		if runs {
+			for v := uint32(1_000); v < 30_000; v++ {
+				r.Set(v)
+				bs.Set(v)
+			}
+			r.RunOptimize()
+		}
+		return r, bs
+	}
+
+	type op struct {
+		name   string
+		roar   func(a, b *Roaring[uint32])
+		bitSet func(a, b *BitSet[uint32])
+	}
+	ops := []op{
+		{"And", (*Roaring[uint32]).And, (*BitSet[uint32]).And},
+		{"Or", (*Roaring[uint32]).Or, (*BitSet[uint32]).Or},
+		{"Xor", (*Roaring[uint32]).Xor, (*BitSet[uint32]).Xor},
+		{"AndNot", (*Roaring[uint32]).AndNot, (*BitSet[uint32]).AndNot},
+	}
+
+	sizes := []struct {
+		n, maxValue int
+		runs        bool
+	}{
+		{100, 1_000_000, false},
+		{10_000, 200_000, false},
+		{50_000, 100_000, false},
+		{10, 100_000, true},
+	}
+
+	for _, sa := range sizes {
+		for _, sb := range sizes {
+			ra, bsa := random(sa.n, sa.maxValue, sa.runs)
+			rb, bsb := random(sb.n, sb.maxValue, sb.runs)
+			assert.Equal(t, bsa.Count(), ra.Count())
+			assert.Equal(t, bsa.ToSlice(), ra.ToSlice())
+			assert.Equal(t, bsa.Max(), ra.Max())
+			assert.Equal(t, bsa.Min(), ra.Min())
+			assert.Equal(t, bsa.ToSlice(), ra.ToBitSet().ToSlice())
+
+			for _, op := range ops {
+				r, bs := ra.Copy(), bsa.Copy()
+				op.roar(r, rb)
+				op.bitSet(bs, bsb)
+				assert.Equal(t, bs.Count(), r.Count(), op.name)
+				assert.Equal(t, bs.ToSlice(), r.ToSlice(), op.name)
+			}
+		}
+	}
+}
+
+func TestRoaring_MultiValueIndex(t *testing.T) {
+	mi := newMultiValueIndex[article, string, uint32]((*article).Tags)
+	mi.Set(&article{tags: []string{"go", "db"}}, 4_000_000)
+	mi.Set(&article{tags: []string{"go"}}, 3)
+	mi.Set(&article{tags: []string{"rust"}}, 1)
+
+	assert.Less(t, mi.data["go"].usedBytes(), 512)
+
+	bs, err := mi.MatchMany(OpHasAll, "go", "db")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{4_000_000}, bs.ToSlice())
+
+	// the query uses the saved Roaring BitSet, without creating a BitSet
+	p, err := mi.matchPosting("go")
+	assert.NoError(t, err)
+	assert.Same(t, mi.data["go"], p)
+
+	result := NewBitSetFrom[uint32](1, 3, 5)
+	andPosting(result, p)
+	assert.Equal(t, []uint32{3}, result.ToSlice())
+
+	mi.UnSet(&article{tags: []string{"rust"}}, 1)
+	_, found := mi.data["rust"]
+	assert.False(t, found)
+}
@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"cmp"
@@ -27,10 +27,12 @@ type node[K cmp.Ordered, V any] struct {
 	value V
 	level byte
 	next  [maxLevel]*node[K, V]
+	prev  *node[K, V] // the previous node on level 0, nil for the first node
 }
 
 type SkipList[K cmp.Ordered, V any] struct {
 	head  *node[K, V]
+	tail  *node[K, V] // the last node, nil if the list is empty
 	level byte
 
 	rnd *rand.Rand
@@ -116,6 +118,16 @@ func (sl *SkipList[K, V]) Put(key K, value V) bool {
 		update[i].next[i] = n
 	}
 
+	// link the previous node on level 0
+	if update[0] != sl.head {
+		n.prev = update[0]
+	}
+	if next := n.next[0]; next != nil {
+		next.prev = n
+	} else {
+		sl.tail = n
+	}
+
 	// update global level
 	if lvl > sl.level {
 		sl.level = lvl
@@ -149,6 +161,12 @@ func (sl *SkipList[K, V]) Delete(key K) bool {
 		update[i].next[i] = x.next[i]
 	}
 
+	if next := x.next[0]; next != nil {
+		next.prev = x.prev
+	} else {
+		sl.tail = x.prev
+	}
+
 	for sl.level > 1 && sl.head.next[sl.level-1] == nil {
 		sl.level--
 	}
@@ -170,6 +188,18 @@ func (sl *SkipList[K, V]) Traverse(visit VisitFn[K, V]) bool {
 	return true
 }
 
+// ReverseTraverse over the complete Skiplist in descending order of the keys and calling the visitor
+// the return value false means, not to the start, otherwise true
+func (sl *SkipList[K, V]) ReverseTraverse(visit VisitFn[K, V]) bool {
+	for x := sl.tail; x != nil; x = x.prev {
+		if !visit(x.key, x.value) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // FindSortedKeys calls visit for all finding keys.
 // Important: they keys slice MUST be sorted!
 func (sl *SkipList[K, V]) FindSortedKeys(visit VisitFn[K, V], keys ...K) {
@@ -231,7 +261,23 @@ func (sl *SkipList[K, V]) FindMaybeSortedKeys(visit VisitFn[K, V], keys ...K) {
 
 // Range traverse 'from' until 'to' over Skiplist and calling the visitor
 func (sl *SkipList[K, V]) Range(from, to K, visit VisitFn[K, V]) {
-	if from > to {
+	sl.RangeBounds(from, to, true, true, visit)
+}
+
+// RangeOpen traverse the keys: from < key < to
+func (sl *SkipList[K, V]) RangeOpen(from, to K, visit VisitFn[K, V]) {
+	sl.RangeBounds(from, to, false, false, visit)
+}
+
+// RangeHalfOpen traverse the keys: from <= key < to
+func (sl *SkipList[K, V]) RangeHalfOpen(from, to K, visit VisitFn[K, V]) {
+	sl.RangeBounds(from, to, true, false, visit)
+}
+
+// RangeBounds traverse 'from' until 'to' over Skiplist and calling the visitor,
+// fromIncl and toIncl defines, are the bounds included or excluded.
+func (sl *SkipList[K, V]) RangeBounds(from, to K, fromIncl, toIncl bool, visit VisitFn[K, V]) {
+	if from > to || (from == to && (!fromIncl || !toIncl)) {
 		return
 	}
 
@@ -245,12 +291,12 @@ func (sl *SkipList[K, V]) Range(from, to K, visit VisitFn[K, V]) {
 
 	// move to the actual first node at level 0
 	x = x.next[0]
-	if x == nil || x.key > to {
-		return
+	if x != nil && !fromIncl && x.key == from {
+		x = x.next[0]
 	}
 
 	// collect all nodes until we exceed 'to'
-	for x != nil && x.key <= to {
+	for x != nil && (x.key < to || (toIncl && x.key == to)) {
 		if !visit(x.key, x.value) {
 			return
 		}
@@ -258,6 +304,35 @@ func (sl *SkipList[K, V]) Range(from, to K, visit VisitFn[K, V]) {
 	}
 }
 
+// ReverseRange traverse 'to' until 'from' over Skiplist (descending order of the keys) and calling the visitor
+func (sl *SkipList[K, V]) ReverseRange(from, to K, visit VisitFn[K, V]) {
+	if from > to {
+		return
+	}
+
+	for x := sl.lastBefore(to, true); x != nil && x.key >= from; x = x.prev {
+		if !visit(x.key, x.value) {
+			return
+		}
+	}
+}
+
+// lastBefore returns the last node with a key < the given key (incl: key <= the given key),
+// or nil, if there is no such node
+func (sl *SkipList[K, V]) lastBefore(key K, incl bool) *node[K, V] {
+	x := sl.head
+	for i := int(sl.level) - 1; i >= 0; i-- {
+		for next := x.next[i]; next != nil && (next.key < key || (incl && next.key == key)); next = x.next[i] {
+			x = next
+		}
+	}
+
+	if x == sl.head {
+		return nil
+	}
+	return x
+}
+
 // Less calls visit for all keys < the given key
 func (sl *SkipList[K, V]) Less(key K, visit VisitFn[K, V]) {
 	// start directly at the first element (Level 0)
@@ -279,6 +354,24 @@ func (sl *SkipList[K, V]) LessEqual(key K, visit VisitFn[K, V]) {
 	}
 }
 
+// ReverseLess calls visit for all keys < the given key, in descending order of the keys
+func (sl *SkipList[K, V]) ReverseLess(key K, visit VisitFn[K, V]) {
+	for x := sl.lastBefore(key, false); x != nil; x = x.prev {
+		if !visit(x.key, x.value) {
+			return
+		}
+	}
+}
+
+// ReverseLessEqual calls visit for all keys <= the given key, in descending order of the keys
+func (sl *SkipList[K, V]) ReverseLessEqual(key K, visit VisitFn[K, V]) {
+	for x := sl.lastBefore(key, true); x != nil; x = x.prev {
+		if !visit(x.key, x.value) {
+			return
+		}
+	}
+}
+
 // Greater calls visit for all keys > the given key
 func (sl *SkipList[K, V]) Greater(key K, visit VisitFn[K, V]) {
 	x := sl.head
@@ -365,21 +458,12 @@ func (sl *SkipList[K, V]) MinKey() (K, bool) {
 // MaxKey returns the last (biggest) Key
 // or the zero value and false, if the list is empty.
 func (sl *SkipList[K, V]) MaxKey() (K, bool) {
-	x := sl.head
-	// start at the highest lane and jump as far right as possible
-	for i := int(sl.level) - 1; i >= 0; i-- {
-		for x.next[i] != nil {
-			x = x.next[i]
-		}
-	}
-
-	// list is empty
-	if x == sl.head {
+	if sl.tail == nil {
 		var zero K
 		return zero, false
 	}
 
-	return x.key, true
+	return sl.tail.key, true
 }
 
 // FirstValue returns the value associated with the smallest key
@@ -398,19 +482,10 @@ func (sl *SkipList[K, V]) FirstValue() (V, bool) {
 // LastValue returns the value associated with the largest key
 // or the zero value and false, if the list is empty.
 func (sl *SkipList[K, V]) LastValue() (V, bool) {
-	x := sl.head
-	// start at the highest lane and jump as far right as possible
-	for i := int(sl.level) - 1; i >= 0; i-- {
-		for x.next[i] != nil {
-			x = x.next[i]
-		}
-	}
-
-	// list is empty
-	if x == sl.head {
+	if sl.tail == nil {
 		var zero V
 		return zero, false
 	}
 
-	return x.value, true
+	return sl.tail.value, true
 }
@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"testing"
@@ -90,3 +90,21 @@ func BenchmarkFromName(b *testing.B) {
 		assert.Equal(b, "ItsMe", fieldName(&p))
 	}
 }
+
+func BenchmarkReverseLess(b *testing.B) {
+	sl := NewSkipList[uint32, uint32]()
+	for i := 1; i <= count; i++ {
+		sl.Put(uint32(i), uint32(i))
+	}
+	b.ResetTimer()
+
+	for b.Loop() {
+		c := 0
+		// the latest 'to' keys < found_val
+		sl.ReverseLess(uint32(found_val), func(key, val uint32) bool {
+			c += 1
+			return c < to
+		})
+		assert.Equal(b, to, c)
+	}
+}
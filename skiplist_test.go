@@ -1,6 +1,7 @@
-package main
+package fali
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -140,6 +141,54 @@ func TestSplitList_RangeInclusiveFromTo(t *testing.T) {
 	assert.Equal(t, []uint32{2, 3, 4, 5}, result)
 }
 
+func TestSplitList_RangeBounds(t *testing.T) {
+	sl := NewSkipList[int, uint32]()
+	sl.Put(2, 2)
+	sl.Put(3, 3)
+	sl.Put(5, 5)
+	sl.Put(4, 4)
+
+	tests := []struct {
+		from, to         int
+		fromIncl, toIncl bool
+		expected         []uint32
+	}{
+		{2, 5, true, true, []uint32{2, 3, 4, 5}},
+		{2, 5, false, true, []uint32{3, 4, 5}},
+		{2, 5, true, false, []uint32{2, 3, 4}},
+		{2, 5, false, false, []uint32{3, 4}},
+		{1, 6, false, false, []uint32{2, 3, 4, 5}},
+		{3, 3, true, true, []uint32{3}},
+		{3, 3, false, true, []uint32{}},
+		{3, 4, false, false, []uint32{}},
+		{5, 2, true, true, []uint32{}},
+	}
+
+	for _, tt := range tests {
+		result := make([]uint32, 0)
+		sl.RangeBounds(tt.from, tt.to, tt.fromIncl, tt.toIncl,
+			func(key int, val uint32) bool {
+				result = append(result, val)
+				return true
+			})
+		assert.Equal(t, tt.expected, result, "%d %d %t %t", tt.from, tt.to, tt.fromIncl, tt.toIncl)
+	}
+
+	result := make([]uint32, 0)
+	sl.RangeOpen(2, 5, func(key int, val uint32) bool {
+		result = append(result, val)
+		return true
+	})
+	assert.Equal(t, []uint32{3, 4}, result)
+
+	result = make([]uint32, 0)
+	sl.RangeHalfOpen(2, 5, func(key int, val uint32) bool {
+		result = append(result, val)
+		return true
+	})
+	assert.Equal(t, []uint32{2, 3, 4}, result)
+}
+
 func TestSplitList_NotInRange(t *testing.T) {
 	sl := NewSkipList[uint32, uint32]()
 	sl.Put(1, 1)
@@ -429,3 +478,59 @@ func TestSplitList_FindMaybeSortedKeys(t *testing.T) {
 	assert.Equal(t, []uint32{1, 5, 3}, result)
 	assert.Equal(t, 3, counVisit)
 }
+
+func TestSkipList_Reverse(t *testing.T) {
+	sl := NewSkipList[int, string]()
+	assert.True(t, sl.ReverseTraverse(func(int, string) bool { return false }))
+	_, found := sl.MaxKey()
+	assert.False(t, found)
+
+	for _, k := range []int{5, 1, 9, 3, 7} {
+		sl.Put(k, fmt.Sprint(k))
+	}
+
+	keys := func(walk func(VisitFn[int, string])) []int {
+		var result []int
+		walk(func(key int, _ string) bool {
+			result = append(result, key)
+			return true
+		})
+		return result
+	}
+
+	assert.Equal(t, []int{9, 7, 5, 3, 1}, keys(func(v VisitFn[int, string]) { sl.ReverseTraverse(v) }))
+	assert.Equal(t, []int{7, 5, 3}, keys(func(v VisitFn[int, string]) { sl.ReverseRange(2, 7, v) }))
+	assert.Equal(t, []int{9, 7, 5, 3, 1}, keys(func(v VisitFn[int, string]) { sl.ReverseRange(0, 10, v) }))
+	assert.Equal(t, []int{5}, keys(func(v VisitFn[int, string]) { sl.ReverseRange(5, 5, v) }))
+	assert.Nil(t, keys(func(v VisitFn[int, string]) { sl.ReverseRange(7, 2, v) }))
+	assert.Equal(t, []int{5, 3, 1}, keys(func(v VisitFn[int, string]) { sl.ReverseLess(7, v) }))
+	assert.Equal(t, []int{5, 3, 1}, keys(func(v VisitFn[int, string]) { sl.ReverseLess(6, v) }))
+	assert.Nil(t, keys(func(v VisitFn[int, string]) { sl.ReverseLess(1, v) }))
+	assert.Equal(t, []int{7, 5, 3, 1}, keys(func(v VisitFn[int, string]) { sl.ReverseLessEqual(7, v) }))
+	assert.Equal(t, []int{5, 3, 1}, keys(func(v VisitFn[int, string]) { sl.ReverseLessEqual(6, v) }))
+	assert.Nil(t, keys(func(v VisitFn[int, string]) { sl.ReverseLessEqual(0, v) }))
+
+	// stop the traversal
+	var first []int
+	assert.False(t, sl.ReverseTraverse(func(key int, _ string) bool {
+		first = append(first, key)
+		return len(first) < 2
+	}))
+	assert.Equal(t, []int{9, 7}, first)
+
+	// the backward links after deleting the first and the last key
+	assert.True(t, sl.Delete(9))
+	assert.True(t, sl.Delete(1))
+	assert.Equal(t, []int{7, 5, 3}, keys(func(v VisitFn[int, string]) { sl.ReverseTraverse(v) }))
+	maxKey, _ := sl.MaxKey()
+	assert.Equal(t, 7, maxKey)
+	last, _ := sl.LastValue()
+	assert.Equal(t, "7", last)
+
+	for _, k := range []int{3, 5, 7} {
+		assert.True(t, sl.Delete(k))
+	}
+	assert.Nil(t, keys(func(v VisitFn[int, string]) { sl.ReverseTraverse(v) }))
+	_, found = sl.LastValue()
+	assert.False(t, found)
+}
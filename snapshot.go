@@ -0,0 +1,176 @@
+package fali
+
+import (
+	"iter"
+)
+
+// Snapshotter is implemented by Indices, which can create an immutable view of the current state.
+// The Index must not change the returned Filter by following Set and UnSet calls (e.g. with copy-on-write).
+type Snapshotter[LI Value] interface {
+	Snapshot() Filter[LI]
+}
+
+// cow tracks the copy-on-write state of an Index, which shares his data with snapshots.
+type cow[K comparable] struct {
+	// the root data structure (map, slice) is shared with a snapshot
+	shared bool
+	// the keys, whose values are copied after the last snapshot, nil means there is no snapshot
+	owned map[K]struct{}
+}
+
+// snapshot marks all data as shared
+func (c *cow[K]) snapshot() {
+	c.shared = true
+	c.owned = make(map[K]struct{})
+}
+
+// ownRoot returns true once after a snapshot, then the root data structure must be copied.
+//
+//go:inline
+func (c *cow[K]) ownRoot() bool {
+	if !c.shared {
+		return false
+	}
+	c.shared = false
+	return true
+}
+
+// own returns true, if the value of the given key is shared and must be copied.
+//
+//go:inline
+func (c *cow[K]) own(key K) bool {
+	if c.owned == nil {
+		return false
+	}
+	if _, found := c.owned[key]; found {
+		return false
+	}
+	c.owned[key] = struct{}{}
+	return true
+}
+
+// Snapshot is an immutable view of an IndexList, at the point in time, by calling IndexList.Snapshot.
+// Following changes of the IndexList are not visible in the Snapshot.
+type Snapshot[T any, ID comparable] struct {
+	list      FreeList[T]
+	idIndex   idIndex[T, ID]
+	filters   map[string]Filter32
+	composite composites
+	allIDs    *BitSet[uint32]
+}
+
+// Snapshot creates an immutable view of the current state of the list.
+// The list and the Indices share the data with the Snapshot, until they are changed (copy-on-write).
+// All Indices must implement the Snapshotter interface, otherwise returns an error.
+func (l *IndexList[T, ID]) Snapshot() (*Snapshot[T, ID], error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	filters := make(map[string]Filter32, len(l.indexMap.index))
+	for name, index := range l.indexMap.index {
+		s, ok := index.(Snapshotter[uint32])
+		if !ok {
+			return nil, ErrSnapshotNotSupported{name}
+		}
+		filters[name] = s.Snapshot()
+	}
+
+	snap := &Snapshot[T, ID]{
+		list:      l.list.snapshot(),
+		filters:   filters,
+		composite: l.indexMap.composite.clone(),
+		allIDs:    l.indexMap.allIDs,
+	}
+	if l.indexMap.idIndex != nil {
+		snap.idIndex = l.indexMap.idIndex.snapshot()
+	}
+	l.indexMap.sharedIDs = true
+
+	return snap, nil
+}
+
+// FilterByName finds the Filter by a given field-name
+func (s *Snapshot[T, ID]) FilterByName(fieldName string) (Filter32, error) {
+	if fieldName == IDIndexFieldName {
+		if s.idIndex == nil {
+			return nil, ErrNoIdIndexDefined{}
+		}
+		return s.idIndex, nil
+	}
+
+	if filter, found := s.filters[fieldName]; found {
+		return filter, nil
+	}
+
+	if name, found := s.composite.names[fieldName]; found {
+		return s.filters[name], nil
+	}
+	if fieldName == compositesName {
+		return s.composite.fields, nil
+	}
+
+	return nil, ErrInvalidIndexdName{fieldName}
+}
+
+// Get returns an item by the given ID, see IndexList.Get
+func (s *Snapshot[T, ID]) Get(id ID) (T, error) {
+	if s.idIndex == nil {
+		var null T
+		return null, ErrNoIdIndexDefined{}
+	}
+
+	idx, err := s.idIndex.GetIndex(id)
+	if err != nil {
+		var null T
+		return null, err
+	}
+
+	item, _ := s.list.Get(idx)
+	return item, nil
+}
+
+// Contains check, is this ID found in the Snapshot.
+func (s *Snapshot[T, ID]) Contains(id ID) bool {
+	if s.idIndex == nil {
+		return false
+	}
+
+	_, err := s.idIndex.GetIndex(id)
+	return err == nil
+}
+
+// Count the Items, which in this Snapshot exist
+func (s *Snapshot[T, ID]) Count() int { return s.list.Count() }
+
+// Iter create an Iterator, to iterate over all List-Indices and Items of the Snapshot
+func (s *Snapshot[T, ID]) Iter() iter.Seq2[int, T] { return s.list.Iter() }
+
+// QueryStr parses and executes the query string, with the optional clauses: ORDER BY, LIMIT and OFFSET
+func (s *Snapshot[T, ID]) QueryStr(queryStr string) (QueryResult[T, ID], error) {
+	stmt, err := ParseStatement(queryStr)
+	if err != nil {
+		return QueryResult[T, ID]{}, err
+	}
+
+	return s.QueryStatement(stmt)
+}
+
+// Query execute the given Query on the Snapshot.
+// The QueryResult reads the Items from the Snapshot and is read-only.
+func (s *Snapshot[T, ID]) Query(query Query32) (QueryResult[T, ID], error) {
+	return s.QueryStatement(Statement{Query: query})
+}
+
+// QueryStatement execute the Statement on the Snapshot (see: Query).
+func (s *Snapshot[T, ID]) QueryStatement(stmt Statement) (QueryResult[T, ID], error) {
+	bs, err := ensureMutable(stmt.Query(s.FilterByName, s.allIDs))
+	if err != nil {
+		return QueryResult[T, ID]{}, err
+	}
+
+	result := QueryResult[T, ID]{bitSet: bs, snapshot: s}
+	if err := result.applyClauses(stmt, s.FilterByName, &s.list); err != nil {
+		return QueryResult[T, ID]{}, err
+	}
+	return result, nil
+}
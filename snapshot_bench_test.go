@@ -0,0 +1,28 @@
+package fali
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// a write after a snapshot copies only the changed pages, not the complete list.
+// What remains depends on the size: the page pointers and the allIDs bits (size/64 words).
+func BenchmarkSnapshot_Write(b *testing.B) {
+	for _, size := range []int{10_000, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			il := NewIndexList[car]()
+			_ = il.CreateIndex("name", NewSortedIndex((*car).Name))
+			for i := range size {
+				_, _ = il.Insert(car{name: strconv.Itoa(i)})
+			}
+
+			i := size
+			for b.Loop() {
+				_, _ = il.Snapshot()
+				_, _ = il.Insert(car{name: strconv.Itoa(i)})
+				i++
+			}
+		})
+	}
+}
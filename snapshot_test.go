@@ -0,0 +1,123 @@
+package fali
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSnapshotList() *IndexList[car, string] {
+	il := NewIndexListWithID((*car).Name)
+	_ = il.CreateIndex("age", NewSortedIndex((*car).Age))
+	_ = il.CreateIndex("isnew", NewMapIndex((*car).IsNew))
+	_ = il.CreateIndex("name", NewTrigramIndex((*car).Name))
+
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
+
+	return il
+}
+
+func TestSnapshot_Isolation(t *testing.T) {
+	il := newSnapshotList()
+
+	snap, err := il.Snapshot()
+	assert.NoError(t, err)
+
+	// change the list after the snapshot
+	_, err = il.Remove("Opel")
+	assert.NoError(t, err)
+	// reuse the slot of Opel
+	idx, err := il.Insert(car{name: "Audi", age: 22, isNew: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, idx)
+	assert.NoError(t, il.Update(car{name: "Dacia", age: 1}))
+	_, _ = il.Insert(car{name: "BMW", age: 7})
+
+	// the snapshot is unchanged
+	assert.Equal(t, 3, snap.Count())
+	assert.True(t, snap.Contains("Opel"))
+	assert.False(t, snap.Contains("Audi"))
+	dacia, err := snap.Get("Dacia")
+	assert.NoError(t, err)
+	assert.Equal(t, car{name: "Dacia", age: 22}, dacia)
+
+	qr, err := snap.QueryStr(`age = uint8(22)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Opel", age: 22}, {name: "Dacia", age: 22}}, qr.Values())
+
+	qr, err = snap.Query(Eq("isnew", true))
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Mercedes", age: 5, isNew: true}}, qr.Values())
+
+	qr, err = snap.QueryStr(`name contains "pe"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Opel", age: 22}}, qr.Values())
+
+	qr, err = snap.Query(ID("Opel"))
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Opel", age: 22}}, qr.Values())
+
+	items := make([]car, 0)
+	for _, item := range snap.Iter() {
+		items = append(items, item)
+	}
+	assert.Equal(t, []car{
+		{name: "Opel", age: 22},
+		{name: "Mercedes", age: 5, isNew: true},
+		{name: "Dacia", age: 22},
+	}, items)
+
+	assert.ErrorIs(t, qr.RemoveAll(), ErrReadOnly{})
+
+	// the list is changed
+	qr, err = il.QueryStr(`age = uint8(22)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Audi", age: 22, isNew: true}}, qr.Values())
+
+	qr, err = il.Query(Eq("isnew", true))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, qr.Count())
+
+	qr, err = il.QueryStr(`name contains "pe"`)
+	assert.NoError(t, err)
+	assert.True(t, qr.IsEmpty())
+}
+
+func TestSnapshot_NotSupported(t *testing.T) {
+	il := NewIndexList[car]()
+	_ = il.CreateIndex("name", fieldIndexOnly[car]{})
+
+	_, err := il.Snapshot()
+	assert.ErrorIs(t, err, ErrSnapshotNotSupported{"name"})
+}
+
+// fieldIndexOnly is an Index without Snapshot support
+type fieldIndexOnly[T any] struct{ Index32[T] }
+
+func (fieldIndexOnly[T]) Set(*T, uint32)   {}
+func (fieldIndexOnly[T]) UnSet(*T, uint32) {}
+
+func TestSnapshot_ConcurrentWriter(t *testing.T) {
+	il := newSnapshotList()
+
+	snap, err := il.Snapshot()
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for i := range 100 {
+			_, _ = il.Insert(car{name: "Opel", age: uint8(i)})
+			_, _ = il.Remove("Dacia")
+		}
+	})
+
+	for range 100 {
+		qr, err := snap.QueryStr(`age = uint8(22)`)
+		assert.NoError(t, err)
+		assert.Equal(t, []car{{name: "Opel", age: 22}, {name: "Dacia", age: 22}}, qr.Values())
+	}
+	wg.Wait()
+}
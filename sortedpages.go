@@ -0,0 +1,274 @@
+package fali
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// maxPageEntries is the max number of entries of a page, a full page is split in two pages
+const maxPageEntries = 512
+
+type sortedPage[K cmp.Ordered, V any] struct {
+	keys   []K
+	values []V
+	// the generation of the sortedPages, in which the page is created (see: sortedPages.page)
+	gen uint64
+}
+
+// sortedPages is a sorted map, the entries are saved in sorted pages with at most maxPageEntries.
+// A snapshot shares the pages, which are copied by the next change (copy-on-write per page),
+// so a change after a snapshot copies one page and the page pointers, but not all entries.
+type sortedPages[K cmp.Ordered, V any] struct {
+	pages []*sortedPage[K, V]
+	// the pages are shared with a snapshot
+	shared bool
+	// is incremented by every snapshot, pages of an older generation are shared with a snapshot
+	gen uint64
+}
+
+// snapshot returns sortedPages, which shares the pages with this sortedPages.
+// This sortedPages copies the changed pages (copy-on-write).
+func (sp *sortedPages[K, V]) snapshot() sortedPages[K, V] {
+	sp.shared = true
+	sp.gen++
+	return sortedPages[K, V]{pages: sp.pages}
+}
+
+// ownRoot copies the page pointers, if they are shared with a snapshot
+//
+//go:inline
+func (sp *sortedPages[K, V]) ownRoot() {
+	if sp.shared {
+		sp.pages = slices.Clone(sp.pages)
+		sp.shared = false
+	}
+}
+
+// page returns the page for changing, copy the page if it is shared with a snapshot
+func (sp *sortedPages[K, V]) page(i int) *sortedPage[K, V] {
+	sp.ownRoot()
+
+	p := sp.pages[i]
+	if p.gen != sp.gen {
+		p = &sortedPage[K, V]{keys: slices.Clone(p.keys), values: slices.Clone(p.values), gen: sp.gen}
+		sp.pages[i] = p
+	}
+	return p
+}
+
+// search returns the position (page, entry) of the first key, for which f returns true.
+// f must be false for the first keys and true for the rest (like: sort.Search).
+func (sp *sortedPages[K, V]) search(f func(K) bool) (int, int) {
+	i := sort.Search(len(sp.pages), func(i int) bool {
+		keys := sp.pages[i].keys
+		return f(keys[len(keys)-1])
+	})
+	if i == len(sp.pages) {
+		return i, 0
+	}
+	return i, sort.Search(len(sp.pages[i].keys), func(j int) bool { return f(sp.pages[i].keys[j]) })
+}
+
+// first returns the position of the first key >= the given key (incl: > the given key, if not incl)
+//
+//go:inline
+func (sp *sortedPages[K, V]) first(key K, incl bool) (int, int) {
+	if incl {
+		return sp.search(func(k K) bool { return k >= key })
+	}
+	return sp.search(func(k K) bool { return k > key })
+}
+
+// walk calls visit for the entries from the given position to the end.
+// Returns false, if visit returns false.
+func (sp *sortedPages[K, V]) walk(i, j int, visit VisitFn[K, V]) bool {
+	for ; i < len(sp.pages); i, j = i+1, 0 {
+		p := sp.pages[i]
+		for ; j < len(p.keys); j++ {
+			if !visit(p.keys[j], p.values[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// walkReverse calls visit for the entries before the given position to the start (in descending order of the keys).
+// Returns false, if visit returns false.
+func (sp *sortedPages[K, V]) walkReverse(i, j int, visit VisitFn[K, V]) bool {
+	for {
+		if j == 0 {
+			if i == 0 {
+				return true
+			}
+			i--
+			j = len(sp.pages[i].keys)
+			continue
+		}
+
+		j--
+		p := sp.pages[i]
+		if !visit(p.keys[j], p.values[j]) {
+			return false
+		}
+	}
+}
+
+// Get returns value and whether it exists
+func (sp *sortedPages[K, V]) Get(key K) (V, bool) {
+	i, j := sp.first(key, true)
+	if i < len(sp.pages) && sp.pages[i].keys[j] == key {
+		return sp.pages[i].values[j], true
+	}
+
+	var zeroVal V
+	return zeroVal, false
+}
+
+// Put inserts or updates a key with the given value.
+// Returns true if a new entry was inserted, false if an existing key was updated.
+func (sp *sortedPages[K, V]) Put(key K, value V) bool {
+	if len(sp.pages) == 0 {
+		sp.ownRoot()
+		sp.pages = append(sp.pages, &sortedPage[K, V]{keys: []K{key}, values: []V{value}, gen: sp.gen})
+		return true
+	}
+
+	i, j := sp.first(key, true)
+	if i < len(sp.pages) && sp.pages[i].keys[j] == key {
+		sp.page(i).values[j] = value
+		return false
+	}
+	if i == len(sp.pages) {
+		// greater than all keys, append to the last page
+		i--
+		j = len(sp.pages[i].keys)
+	}
+
+	p := sp.page(i)
+	p.keys = slices.Insert(p.keys, j, key)
+	p.values = slices.Insert(p.values, j, value)
+
+	if len(p.keys) > maxPageEntries {
+		half := len(p.keys) / 2
+		next := &sortedPage[K, V]{keys: slices.Clone(p.keys[half:]), values: slices.Clone(p.values[half:]), gen: sp.gen}
+		clear(p.values[half:])
+		p.keys, p.values = p.keys[:half], p.values[:half]
+		sp.pages = slices.Insert(sp.pages, i+1, next)
+	}
+	return true
+}
+
+// Delete removes the value for a given key
+// If the key was not found: false, otherwise true, if the key was deleted.
+func (sp *sortedPages[K, V]) Delete(key K) bool {
+	i, j := sp.first(key, true)
+	if i == len(sp.pages) || sp.pages[i].keys[j] != key {
+		return false
+	}
+
+	if len(sp.pages[i].keys) == 1 {
+		sp.ownRoot()
+		sp.pages = slices.Delete(sp.pages, i, i+1)
+		return true
+	}
+
+	p := sp.page(i)
+	p.keys = slices.Delete(p.keys, j, j+1)
+	p.values = slices.Delete(p.values, j, j+1)
+	return true
+}
+
+// Traverse over all entries and calling the visitor
+// the return value false means, not to the end, otherwise true
+func (sp *sortedPages[K, V]) Traverse(visit VisitFn[K, V]) bool { return sp.walk(0, 0, visit) }
+
+// ReverseTraverse over all entries in descending order of the keys and calling the visitor
+// the return value false means, not to the start, otherwise true
+func (sp *sortedPages[K, V]) ReverseTraverse(visit VisitFn[K, V]) bool {
+	return sp.walkReverse(len(sp.pages), 0, visit)
+}
+
+// FindSortedKeys calls visit for all finding keys.
+func (sp *sortedPages[K, V]) FindSortedKeys(visit VisitFn[K, V], keys ...K) {
+	for _, key := range keys {
+		if v, found := sp.Get(key); found && !visit(key, v) {
+			return
+		}
+	}
+}
+
+// Range traverse 'from' until 'to' and calling the visitor
+func (sp *sortedPages[K, V]) Range(from, to K, visit VisitFn[K, V]) {
+	sp.RangeBounds(from, to, true, true, visit)
+}
+
+// RangeBounds traverse 'from' until 'to' and calling the visitor,
+// fromIncl and toIncl defines, are the bounds included or excluded.
+func (sp *sortedPages[K, V]) RangeBounds(from, to K, fromIncl, toIncl bool, visit VisitFn[K, V]) {
+	if from > to || (from == to && (!fromIncl || !toIncl)) {
+		return
+	}
+
+	i, j := sp.first(from, fromIncl)
+	sp.walk(i, j, func(k K, v V) bool { return (k < to || (toIncl && k == to)) && visit(k, v) })
+}
+
+// Less calls visit for all keys < the given key
+func (sp *sortedPages[K, V]) Less(key K, visit VisitFn[K, V]) {
+	sp.walk(0, 0, func(k K, v V) bool { return k < key && visit(k, v) })
+}
+
+// LessEqual calls visit for all keys <= the given key
+func (sp *sortedPages[K, V]) LessEqual(key K, visit VisitFn[K, V]) {
+	sp.walk(0, 0, func(k K, v V) bool { return k <= key && visit(k, v) })
+}
+
+// ReverseLessEqual calls visit for all keys <= the given key, in descending order of the keys
+func (sp *sortedPages[K, V]) ReverseLessEqual(key K, visit VisitFn[K, V]) {
+	i, j := sp.first(key, false)
+	sp.walkReverse(i, j, visit)
+}
+
+// Greater calls visit for all keys > the given key
+func (sp *sortedPages[K, V]) Greater(key K, visit VisitFn[K, V]) {
+	i, j := sp.first(key, false)
+	sp.walk(i, j, visit)
+}
+
+// GreaterEqual calls visit for all keys >= the given key
+func (sp *sortedPages[K, V]) GreaterEqual(key K, visit VisitFn[K, V]) {
+	i, j := sp.first(key, true)
+	sp.walk(i, j, visit)
+}
+
+// StringStartsWith finds all keys with the given prefix.
+// If prefix (K) is not a string, this method panics!
+func (sp *sortedPages[K, V]) StringStartsWith(prefix K, visit VisitFn[K, V]) {
+	prefixStr, ok := any(prefix).(string)
+	if !ok {
+		panic(fmt.Sprintf("StringStartsWith supports only strings, not: %T", prefix))
+	}
+
+	i, j := sp.first(prefix, true)
+	sp.walk(i, j, func(k K, v V) bool { return strings.HasPrefix(any(k).(string), prefixStr) && visit(k, v) })
+}
+
+// countKeys returns the number of keys in a range: before returns true for the keys before the range
+// and behind for the keys behind the range.
+func (sp *sortedPages[K, V]) countKeys(before, behind func(K) bool) int {
+	fromPage, fromEntry := sp.search(func(k K) bool { return !before(k) })
+	toPage, toEntry := sp.search(behind)
+	if fromPage > toPage || (fromPage == toPage && fromEntry >= toEntry) {
+		return 0
+	}
+
+	count := toEntry - fromEntry
+	for _, p := range sp.pages[fromPage:toPage] {
+		count += len(p.keys)
+	}
+	return count
+}
@@ -0,0 +1,154 @@
+package fali
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// collect returns the keys, which are visited by walk
+func collect(walk func(VisitFn[int, int])) []int {
+	keys := make([]int, 0)
+	walk(func(k, _ int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+func TestSortedPages_Base(t *testing.T) {
+	var sp sortedPages[int, string]
+	assert.True(t, sp.Put(1, "a"))
+	assert.True(t, sp.Put(3, "c"))
+	assert.True(t, sp.Put(2, "b"))
+	assert.False(t, sp.Put(2, "b"))
+
+	val, found := sp.Get(2)
+	assert.True(t, found)
+	assert.Equal(t, "b", val)
+
+	assert.True(t, sp.Delete(2))
+	val, found = sp.Get(2)
+	assert.False(t, found)
+	assert.Equal(t, "", val)
+	assert.False(t, sp.Delete(2))
+
+	assert.True(t, sp.Delete(1))
+	assert.True(t, sp.Delete(3))
+	assert.Empty(t, sp.pages)
+	_, found = sp.Get(1)
+	assert.False(t, found)
+}
+
+func TestSortedPages_Pages(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(5, 1))
+	var sp sortedPages[int, int]
+	expected := make([]int, 0)
+
+	// the keys are split in many pages
+	for range 5_000 {
+		k := rnd.IntN(10_000)
+		if sp.Put(k, k*10) {
+			expected = append(expected, k)
+		}
+	}
+	slices.Sort(expected)
+	assert.Greater(t, len(sp.pages), len(expected)/maxPageEntries)
+
+	for _, p := range sp.pages {
+		assert.LessOrEqual(t, len(p.keys), maxPageEntries)
+		assert.True(t, slices.IsSorted(p.keys))
+	}
+	for _, k := range expected {
+		v, found := sp.Get(k)
+		assert.True(t, found)
+		assert.Equal(t, k*10, v)
+	}
+
+	desc := slices.Clone(expected)
+	slices.Reverse(desc)
+	mid := expected[len(expected)/2]
+	lower := expected[:len(expected)/2]
+	upper := expected[len(expected)/2+1:]
+
+	assert.Equal(t, expected, collect(func(v VisitFn[int, int]) { sp.Traverse(v) }))
+	assert.Equal(t, desc, collect(func(v VisitFn[int, int]) { sp.ReverseTraverse(v) }))
+	assert.Equal(t, lower, collect(func(v VisitFn[int, int]) { sp.Less(mid, v) }))
+	assert.Equal(t, append(slices.Clone(lower), mid), collect(func(v VisitFn[int, int]) { sp.LessEqual(mid, v) }))
+	assert.Equal(t, upper, collect(func(v VisitFn[int, int]) { sp.Greater(mid, v) }))
+	assert.Equal(t, append([]int{mid}, upper...), collect(func(v VisitFn[int, int]) { sp.GreaterEqual(mid, v) }))
+	assert.Equal(t, desc[len(desc)-1-len(expected)/2:], collect(func(v VisitFn[int, int]) { sp.ReverseLessEqual(mid, v) }))
+	assert.Equal(t, expected[10:21], collect(func(v VisitFn[int, int]) { sp.Range(expected[10], expected[20], v) }))
+	assert.Equal(t, expected[11:20], collect(func(v VisitFn[int, int]) {
+		sp.RangeBounds(expected[10], expected[20], false, false, v)
+	}))
+
+	// stop the walk
+	assert.False(t, sp.Traverse(func(k, _ int) bool { return k < mid }))
+
+	assert.Equal(t, len(expected), sp.countKeys(func(int) bool { return false }, func(int) bool { return false }))
+	assert.Equal(t, len(lower), sp.countKeys(func(int) bool { return false }, func(k int) bool { return k >= mid }))
+	assert.Equal(t, 11, sp.countKeys(func(k int) bool { return k < expected[10] }, func(k int) bool { return k > expected[20] }))
+	assert.Equal(t, 0, sp.countKeys(func(k int) bool { return k < 5 }, func(k int) bool { return k > 3 }))
+
+	// delete all keys
+	rnd.Shuffle(len(expected), func(i, j int) { expected[i], expected[j] = expected[j], expected[i] })
+	for _, k := range expected {
+		assert.True(t, sp.Delete(k))
+	}
+	assert.Empty(t, sp.pages)
+}
+
+func TestSortedPages_StringStartsWith(t *testing.T) {
+	var sp sortedPages[string, int]
+	for i, k := range []string{"Audi", "BMW", "Opel", "Ope", "Op", "Peugeot"} {
+		sp.Put(k, i)
+	}
+
+	keys := make([]string, 0)
+	sp.StringStartsWith("Op", func(k string, _ int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	assert.Equal(t, []string{"Op", "Ope", "Opel"}, keys)
+}
+
+func TestSortedPages_Snapshot(t *testing.T) {
+	var sp sortedPages[int, int]
+	for i := range 3 * maxPageEntries {
+		sp.Put(i, i)
+	}
+	pages := slices.Clone(sp.pages)
+
+	snap := sp.snapshot()
+	sp.Put(1, 100)
+	sp.Put(3*maxPageEntries, 0)
+	sp.Delete(2)
+
+	// only the changed pages are copied
+	assert.NotSame(t, pages[0], sp.pages[0])
+	for i := 1; i < len(pages)-1; i++ {
+		assert.Same(t, pages[i], sp.pages[i])
+	}
+
+	// the snapshot is not changed
+	assert.Equal(t, pages, snap.pages)
+	v, _ := snap.Get(1)
+	assert.Equal(t, 1, v)
+	_, found := snap.Get(2)
+	assert.True(t, found)
+	_, found = snap.Get(3 * maxPageEntries)
+	assert.False(t, found)
+
+	v, _ = sp.Get(1)
+	assert.Equal(t, 100, v)
+	_, found = sp.Get(2)
+	assert.False(t, found)
+
+	// the copied page is changed without a copy
+	copied := sp.pages[0]
+	sp.Put(4, 400)
+	assert.Same(t, copied, sp.pages[0])
+}
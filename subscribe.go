@@ -0,0 +1,364 @@
+package fali
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// EventKind describes, how the result of a subscribed Query has changed
+type EventKind uint8
+
+const (
+	// Inserted the Item enters the result (it is inserted or updated, so that it matches the Query)
+	Inserted EventKind = iota + 1
+	// Updated the Item is changed and matches the Query before and after the change
+	Updated
+	// Removed the Item leaves the result (it is removed or updated, so that it no longer matches the Query)
+	Removed
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Inserted:
+		return "Inserted"
+	case Updated:
+		return "Updated"
+	case Removed:
+		return "Removed"
+	default:
+		return "Unknown"
+	}
+}
+
+// Event is a change of the result of a subscribed Query
+type Event[T any] struct {
+	Kind EventKind
+	// Item is the new Item or by removing the removed Item
+	Item T
+	// Old is the Item before the change, if the event is caused by an Update
+	Old T
+}
+
+// Subscribe calls the given function for every change of the result of the Query:
+// Items, which enter (Inserted), change within (Updated) or leave (Removed) the result.
+//
+// The Query is not re-executed for every change, only the changed Item is checked against the Query.
+// The events are delivered in the order of the changes, after the write lock is released,
+// so the function can read (and change) the list. Changes of a Tx are delivered after the commit.
+// The returned function cancels the subscription.
+func (l *IndexList[T, ID]) Subscribe(query Query32, fn func(Event[T])) (cancel func(), err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	bs, err := ensureMutable(query(l.indexMap.FilterByName, l.indexMap.allIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	subs := l.indexMap.subs
+	subs.nextID++
+	id := subs.nextID
+	subs.active[id] = &subscription[T]{query: query, fn: fn, members: bs}
+
+	return func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		delete(subs.active, id)
+	}, nil
+}
+
+type subscription[T any] struct {
+	query Query32
+	fn    func(Event[T])
+	// the List-Indices of the Items, which matches the Query
+	members *BitSet[uint32]
+}
+
+type delivery[T any] struct {
+	fn    func(Event[T])
+	event Event[T]
+}
+
+// subscriptions are the subscribed Queries of an indexMap.
+// The events are created under the write lock of the IndexList and delivered after releasing the lock.
+type subscriptions[T any] struct {
+	// active and tx are protected by the lock of the IndexList
+	active map[uint64]*subscription[T]
+	nextID uint64
+	// the events of the current transaction, nil means there is no transaction
+	tx []delivery[T]
+
+	mu         sync.Mutex
+	pending    []delivery[T]
+	delivering bool
+}
+
+func newSubscriptions[T any]() *subscriptions[T] {
+	return &subscriptions[T]{active: make(map[uint64]*subscription[T])}
+}
+
+func (s *subscriptions[T]) emit(sub *subscription[T], event Event[T]) {
+	if s.tx != nil {
+		s.tx = append(s.tx, delivery[T]{sub.fn, event})
+		return
+	}
+
+	s.mu.Lock()
+	s.pending = append(s.pending, delivery[T]{sub.fn, event})
+	s.mu.Unlock()
+}
+
+// begin collects all following events, until commit or rollback
+func (s *subscriptions[T]) begin() { s.tx = make([]delivery[T], 0) }
+
+func (s *subscriptions[T]) commit() {
+	events := s.tx
+	s.tx = nil
+
+	if len(events) > 0 {
+		s.mu.Lock()
+		s.pending = append(s.pending, events...)
+		s.mu.Unlock()
+	}
+}
+
+// rollback discards the events of the transaction.
+// The members are already reverted by the undo operations.
+func (s *subscriptions[T]) rollback() { s.tx = nil }
+
+// deliver calls the functions for all pending events.
+// Only one goroutine delivers at the same time, so the order of the events is preserved.
+// If the function is called while delivering (e.g. a change in the callback), the events
+// are delivered by the already delivering goroutine.
+func (s *subscriptions[T]) deliver() {
+	s.mu.Lock()
+	if s.delivering {
+		s.mu.Unlock()
+		return
+	}
+	s.delivering = true
+
+	for len(s.pending) > 0 {
+		events := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+
+		for _, d := range events {
+			d.fn(d.event)
+		}
+
+		s.mu.Lock()
+	}
+
+	s.delivering = false
+	s.mu.Unlock()
+}
+
+func (i indexMap[OBJ, ID]) notifyInsert(obj *OBJ, lidx uint32) {
+	for _, sub := range i.subs.active {
+		if i.matches(sub.query, obj, lidx) {
+			sub.members.Set(lidx)
+			i.subs.emit(sub, Event[OBJ]{Kind: Inserted, Item: *obj})
+		}
+	}
+}
+
+func (i indexMap[OBJ, ID]) notifyUpdate(oldObj, obj *OBJ, lidx uint32) {
+	for _, sub := range i.subs.active {
+		was, is := sub.members.Contains(lidx), i.matches(sub.query, obj, lidx)
+
+		switch {
+		case was && is:
+			i.subs.emit(sub, Event[OBJ]{Kind: Updated, Item: *obj, Old: *oldObj})
+		case is:
+			sub.members.Set(lidx)
+			i.subs.emit(sub, Event[OBJ]{Kind: Inserted, Item: *obj, Old: *oldObj})
+		case was:
+			sub.members.UnSet(lidx)
+			i.subs.emit(sub, Event[OBJ]{Kind: Removed, Item: *obj, Old: *oldObj})
+		}
+	}
+}
+
+func (i indexMap[OBJ, ID]) notifyRemove(obj *OBJ, lidx uint32) {
+	for _, sub := range i.subs.active {
+		if sub.members.Contains(lidx) {
+			sub.members.UnSet(lidx)
+			i.subs.emit(sub, Event[OBJ]{Kind: Removed, Item: *obj})
+		}
+	}
+}
+
+// refreshSubscriptionsNoLock re-executes all subscribed Queries, after the list is replaced (e.g. Load).
+// The Items of the new result are delivered as Inserted, expected is, that all old Items are removed.
+func (l *IndexList[T, ID]) refreshSubscriptionsNoLock() {
+	for _, sub := range l.indexMap.subs.active {
+		bs, err := ensureMutable(sub.query(l.indexMap.FilterByName, l.indexMap.allIDs))
+		if err != nil {
+			sub.members = NewBitSet[uint32]()
+			continue
+		}
+		sub.members = bs
+
+		bs.Values(func(lidx uint32) bool {
+			item, _ := l.list.Get(int(lidx))
+			l.indexMap.subs.emit(sub, Event[T]{Kind: Inserted, Item: item})
+			return true
+		})
+	}
+}
+
+// matches checks, if the given Item matches the Query.
+// The Query is executed only for this one Item: every Filter returns the position 0, if the Item matches.
+func (i indexMap[OBJ, ID]) matches(query Query32, obj *OBJ, lidx uint32) bool {
+	byName := func(fieldName string) (Filter32, error) {
+		filter, err := i.FilterByName(fieldName)
+		if err != nil {
+			return nil, err
+		}
+		return itemFilter[OBJ]{filter: filter, obj: obj, lidx: lidx}, nil
+	}
+
+	matched := NewBitSetFrom[uint32](0)
+	result, _, err := query(byName, matched)
+	return err == nil && countAnd(result, matched) > 0
+}
+
+// itemMatcher is implemented by Indices, which can check one Item against the relation, without a lookup
+type itemMatcher[OBJ any] interface {
+	matchItem(obj *OBJ, op Op, values ...any) (bool, error)
+}
+
+// itemFilter is a Filter for one Item, the result is: {0} (matched) or {} (not matched)
+type itemFilter[OBJ any] struct {
+	filter Filter32
+	obj    *OBJ
+	lidx   uint32
+}
+
+// ValueType returns the value type of the Filter or nil
+func (f itemFilter[OBJ]) ValueType() reflect.Type {
+	if vt, ok := f.filter.(ValueTyper); ok {
+		return vt.ValueType()
+	}
+	return nil
+}
+
+func (f itemFilter[OBJ]) Match(op Op, value any) (*BitSet[uint32], error) {
+	if m, ok := f.filter.(itemMatcher[OBJ]); ok {
+		return itemResult(m.matchItem(f.obj, op, value))
+	}
+
+	bs, err := f.filter.Match(op, value)
+	if err != nil {
+		return nil, err
+	}
+	return itemResult(bs.Contains(f.lidx), nil)
+}
+
+func (f itemFilter[OBJ]) MatchMany(op Op, values ...any) (*BitSet[uint32], error) {
+	if m, ok := f.filter.(itemMatcher[OBJ]); ok {
+		return itemResult(m.matchItem(f.obj, op, values...))
+	}
+
+	bs, err := f.filter.MatchMany(op, values...)
+	if err != nil {
+		return nil, err
+	}
+	return itemResult(bs.Contains(f.lidx), nil)
+}
+
+//go:inline
+func itemResult(matched bool, err error) (*BitSet[uint32], error) {
+	if err != nil {
+		return nil, err
+	}
+	if matched {
+		return NewBitSetFrom[uint32](0), nil
+	}
+	return NewBitSet[uint32](), nil
+}
+
+func (mi *idMapIndex[OBJ, ID]) matchItem(obj *OBJ, op Op, values ...any) (bool, error) {
+	if op != OpEq || len(values) != 1 {
+		return false, ErrInvalidOperation{IDMapIndexName, op}
+	}
+	id, ok := values[0].(ID)
+	if !ok {
+		return false, ErrInvalidIndexValue[ID]{values[0]}
+	}
+
+	return mi.fieldGetFn(obj) == id, nil
+}
+
+func (mi *MapIndex[OBJ, V, LI]) matchItem(obj *OBJ, op Op, values ...any) (bool, error) {
+	if op != OpEq || len(values) != 1 {
+		return false, ErrInvalidOperation{MapIndexName, op}
+	}
+	if _, ok := values[0].(V); !ok {
+		return false, ErrInvalidIndexValue[V]{values[0]}
+	}
+
+	return any(mi.fieldGetFn(obj)) == values[0], nil
+}
+
+func (si *SortedIndex[OBJ, V, LI]) matchItem(obj *OBJ, op Op, values ...any) (bool, error) {
+	keys := make([]V, len(values))
+	var ok bool
+	for i, val := range values {
+		if keys[i], ok = val.(V); !ok {
+			return false, ErrInvalidIndexValue[V]{val}
+		}
+	}
+
+	switch op {
+	case OpEq, OpLt, OpLe, OpGt, OpGe, OpStartsWith:
+		if len(keys) != 1 {
+			return false, ErrInvalidArgsLen{defined: "1", got: len(keys)}
+		}
+	case OpBetween:
+		if len(keys) != 2 {
+			return false, ErrInvalidArgsLen{defined: "2", got: len(keys)}
+		}
+	}
+
+	value := si.fieldGetFn(obj)
+	switch op {
+	case OpEq:
+		return value == keys[0], nil
+	case OpLt:
+		return value < keys[0], nil
+	case OpLe:
+		return value <= keys[0], nil
+	case OpGt:
+		return value > keys[0], nil
+	case OpGe:
+		return value >= keys[0], nil
+	case OpStartsWith:
+		prefix, ok := values[0].(string)
+		if !ok {
+			return false, ErrInvalidIndexValue[string]{values[0]}
+		}
+		return strings.HasPrefix(any(value).(string), prefix), nil
+	case OpBetween:
+		return keys[0] <= value && value <= keys[1], nil
+	case OpIn:
+		return slices.Contains(keys, value), nil
+	default:
+		return false, ErrInvalidOperation{SortedIndexName, op}
+	}
+}
+
+func (ti *TrigramIndex[OBJ, LI]) matchItem(obj *OBJ, op Op, values ...any) (bool, error) {
+	if op != OpContains || len(values) != 1 {
+		return false, ErrInvalidOperation{TrigramIndexName, op}
+	}
+	query, ok := values[0].(string)
+	if !ok {
+		return false, ErrInvalidIndexValue[string]{values[0]}
+	}
+
+	return strings.Contains(ti.fieldGetFn(obj), query), nil
+}
@@ -0,0 +1,207 @@
+package fali
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSubscribeList(t *testing.T, query Query32) (*IndexList[car, string], *[]Event[car]) {
+	il := NewIndexListWithID((*car).Name)
+	assert.NoError(t, il.CreateIndex("age", NewSortedIndex((*car).Age)))
+	assert.NoError(t, il.CreateIndex("isnew", NewMapIndex((*car).IsNew)))
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+
+	events := make([]Event[car], 0)
+	_, err := il.Subscribe(query, func(e Event[car]) { events = append(events, e) })
+	assert.NoError(t, err)
+
+	return il, &events
+}
+
+func TestSubscribe_InsertUpdateRemove(t *testing.T) {
+	il, events := newSubscribeList(t, Lt("age", uint8(10)))
+
+	_, _ = il.Insert(car{name: "Audi", age: 3})
+	_, _ = il.Insert(car{name: "Dacia", age: 12})
+	assert.Equal(t, []Event[car]{{Kind: Inserted, Item: car{name: "Audi", age: 3}}}, *events)
+
+	// change within, enter and leave the result
+	*events = (*events)[:0]
+	assert.NoError(t, il.Update(car{name: "Audi", age: 4}))
+	assert.NoError(t, il.Update(car{name: "Dacia", age: 2}))
+	assert.NoError(t, il.Update(car{name: "Audi", age: 40}))
+	assert.Equal(t, []Event[car]{
+		{Kind: Updated, Item: car{name: "Audi", age: 4}, Old: car{name: "Audi", age: 3}},
+		{Kind: Inserted, Item: car{name: "Dacia", age: 2}, Old: car{name: "Dacia", age: 12}},
+		{Kind: Removed, Item: car{name: "Audi", age: 40}, Old: car{name: "Audi", age: 4}},
+	}, *events)
+
+	*events = (*events)[:0]
+	_, err := il.Remove("Audi")
+	assert.NoError(t, err)
+	_, err = il.Remove("Dacia")
+	assert.NoError(t, err)
+	assert.Equal(t, []Event[car]{{Kind: Removed, Item: car{name: "Dacia", age: 2}}}, *events)
+}
+
+func TestSubscribe_NotAndID(t *testing.T) {
+	il, events := newSubscribeList(t, Or(Eq("id", "Opel"), Not(Eq("isnew", false))))
+
+	_, _ = il.Insert(car{name: "Audi", age: 3})
+	_, _ = il.Insert(car{name: "BMW", age: 1, isNew: true})
+	assert.NoError(t, il.Update(car{name: "Opel", age: 23}))
+
+	assert.Equal(t, []Event[car]{
+		{Kind: Inserted, Item: car{name: "BMW", age: 1, isNew: true}},
+		{Kind: Updated, Item: car{name: "Opel", age: 23}, Old: car{name: "Opel", age: 22}},
+	}, *events)
+}
+
+func TestSubscribe_RemoveAll(t *testing.T) {
+	il, events := newSubscribeList(t, Gt("age", uint8(10)))
+	_, _ = il.Insert(car{name: "Audi", age: 3})
+
+	qr, err := il.Query(All())
+	assert.NoError(t, err)
+	assert.NoError(t, qr.RemoveAll())
+
+	assert.Equal(t, []Event[car]{{Kind: Removed, Item: car{name: "Opel", age: 22}}}, *events)
+}
+
+func TestSubscribe_Tx(t *testing.T) {
+	il, events := newSubscribeList(t, All())
+
+	err := il.Tx(func(tx *Tx[car, string]) error {
+		_, _ = tx.Insert(car{name: "Audi", age: 3})
+		_, err := tx.Remove("Opel")
+		assert.NoError(t, err)
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+	assert.Empty(t, *events)
+
+	err = il.Tx(func(tx *Tx[car, string]) error {
+		_, _ = tx.Insert(car{name: "Audi", age: 3})
+		_, err := tx.Remove("Opel")
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Event[car]{
+		{Kind: Inserted, Item: car{name: "Audi", age: 3}},
+		{Kind: Removed, Item: car{name: "Opel", age: 22}},
+	}, *events)
+
+	// the members are consistent after the rollback
+	*events = (*events)[:0]
+	_, err = il.Remove("Audi")
+	assert.NoError(t, err)
+	assert.Equal(t, []Event[car]{{Kind: Removed, Item: car{name: "Audi", age: 3}}}, *events)
+}
+
+func TestSubscribe_Load(t *testing.T) {
+	il, events := newSubscribeList(t, Eq("isnew", true))
+	_, _ = il.Insert(car{name: "Audi", age: 3, isNew: true})
+
+	var buf bytes.Buffer
+	src := NewIndexListWithID((*car).Name)
+	_, _ = src.Insert(car{name: "BMW", age: 1, isNew: true})
+	src.SetCodec(carCodec{})
+	il.SetCodec(carCodec{})
+	assert.NoError(t, src.Save(&buf))
+
+	*events = (*events)[:0]
+	assert.NoError(t, il.Load(&buf))
+	assert.Equal(t, []Event[car]{
+		{Kind: Removed, Item: car{name: "Audi", age: 3, isNew: true}},
+		{Kind: Inserted, Item: car{name: "BMW", age: 1, isNew: true}},
+	}, *events)
+}
+
+func TestSubscribe_CallbackReadsAndChanges(t *testing.T) {
+	il := NewIndexListWithID((*car).Name)
+	assert.NoError(t, il.CreateIndex("age", NewSortedIndex((*car).Age)))
+
+	counts := make([]int, 0)
+	_, err := il.Subscribe(Lt("age", uint8(10)), func(e Event[car]) {
+		counts = append(counts, il.Count())
+		if e.Kind == Inserted && e.Item.age < 5 {
+			_, _ = il.Insert(car{name: e.Item.name + "+", age: e.Item.age + 5})
+		}
+	})
+	assert.NoError(t, err)
+
+	_, _ = il.Insert(car{name: "Audi", age: 3})
+	assert.Equal(t, []int{1, 2}, counts)
+}
+
+func TestSubscribe_Cancel(t *testing.T) {
+	il := NewIndexList[car]()
+	assert.NoError(t, il.CreateIndex("age", NewSortedIndex((*car).Age)))
+
+	count := 0
+	cancel, err := il.Subscribe(Eq("age", uint8(3)), func(Event[car]) { count++ })
+	assert.NoError(t, err)
+
+	_, _ = il.Insert(car{name: "Audi", age: 3})
+	cancel()
+	_, _ = il.Insert(car{name: "BMW", age: 3})
+	assert.Equal(t, 1, count)
+
+	_, err = il.Subscribe(Eq("color", "red"), func(Event[car]) {})
+	assert.ErrorIs(t, err, ErrInvalidIndexdName{"color"})
+}
+
+func TestSubscribe_MatchItem(t *testing.T) {
+	im := newIndexMap(newIDMapIndex((*car).Name))
+	im.index["age"] = NewSortedIndex((*car).Age)
+	im.index["name"] = NewTrigramIndex((*car).Name)
+	im.index["isnew"] = NewMapIndex((*car).IsNew)
+
+	audi := car{name: "Audi", age: 3, isNew: true}
+
+	tests := []struct {
+		query    string
+		expected bool
+	}{
+		{`id = "Audi"`, true},
+		{`id = "BMW"`, false},
+		{`age = uint8(3)`, true},
+		{`age != uint8(3)`, false},
+		{`age <= uint8(3) and age >= uint8(3)`, true},
+		{`age between(uint8(1), uint8(2))`, false},
+		{`age between [uint8(1), uint8(3)]`, true},
+		{`age between [uint8(1), uint8(3)[`, false},
+		{`age > uint8(1) and age < uint8(3)`, false},
+		{`age in (uint8(1), uint8(3))`, true},
+		{`name contains "ud"`, true},
+		{`name contains "BMW"`, false},
+		{`isnew = true`, true},
+		{`not isnew = true`, false},
+		{`age = 3`, true},
+		{`age = 300`, false}, // overflow for uint8
+		{`age = "3"`, false}, // wrong value type
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := Parse(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, im.matches(query, &audi, 7))
+		})
+	}
+}
+
+type carCodec struct{}
+
+func (carCodec) Marshal(c car) ([]byte, error) {
+	return fmt.Appendf(nil, "%s %d %t", c.name, c.age, c.isNew), nil
+}
+
+func (carCodec) Unmarshal(data []byte) (c car, err error) {
+	_, err = fmt.Sscanf(string(data), "%s %d %t", &c.name, &c.age, &c.isNew)
+	return c, err
+}
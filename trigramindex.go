@@ -1,41 +1,82 @@
-package main
+package fali
 
 import (
+	"maps"
+	"reflect"
+	"slices"
 	"strings"
 )
 
+const TrigramIndexName = "TrigramIndex"
+
 type sbucket struct {
 	s        string
 	occupied bool
 }
 
-type TrigramIndex struct {
-	index   map[uint32]BitSet[uint32]
-	buckets []sbucket
-	len     int
+// TrigramIndex is well suited for Queries with: Contains (substring search)
+type TrigramIndex[OBJ any, LI Value] struct {
+	index      map[uint32]*BitSet[LI]
+	buckets    []sbucket
+	len        int
+	fieldGetFn FromField[OBJ, string]
+	cow        cow[uint32]
+}
+
+func NewTrigramIndex[OBJ any](fieldGetFn FromField[OBJ, string]) Index32[OBJ] {
+	return newTrigramIndex[OBJ, uint32](fieldGetFn)
 }
 
-func NewTrigramIndex(s ...string) TrigramIndex {
-	ti := TrigramIndex{
-		index:   make(map[uint32]BitSet[uint32], len(s)),
-		buckets: make([]sbucket, 0, len(s)),
+func newTrigramIndex[OBJ any, LI Value](fieldGetFn FromField[OBJ, string]) *TrigramIndex[OBJ, LI] {
+	return &TrigramIndex[OBJ, LI]{
+		index:      make(map[uint32]*BitSet[LI]),
+		buckets:    make([]sbucket, 0),
+		fieldGetFn: fieldGetFn,
+	}
+}
+
+func (ti *TrigramIndex[OBJ, LI]) Set(obj *OBJ, lidx LI) { ti.put(ti.fieldGetFn(obj), lidx) }
+func (ti *TrigramIndex[OBJ, LI]) UnSet(_ *OBJ, lidx LI) { ti.delete(lidx) }
+
+func (ti *TrigramIndex[OBJ, LI]) Match(op Op, value any) (*BitSet[LI], error) {
+	query, ok := value.(string)
+	if !ok {
+		return nil, ErrInvalidIndexValue[string]{value}
 	}
 
-	for i, el := range s {
-		ti.Put(el, i)
+	if op != OpContains {
+		return nil, ErrInvalidOperation{TrigramIndexName, op}
 	}
 
-	return ti
+	return ti.get(query), nil
 }
 
-func (ti *TrigramIndex) Get(query string) *BitSet[uint32] {
-	resultBS := NewBitSet[uint32]()
+// MatchMany is not supported by TrigramIndex, so that always returns an error
+func (ti *TrigramIndex[OBJ, LI]) MatchMany(op Op, values ...any) (*BitSet[LI], error) {
+	return nil, ErrInvalidOperation{TrigramIndexName, op}
+}
+
+// Snapshot returns an immutable TrigramIndex, which shares the data until this TrigramIndex is changed.
+func (ti *TrigramIndex[OBJ, LI]) Snapshot() Filter[LI] {
+	ti.cow.snapshot()
+	return &TrigramIndex[OBJ, LI]{index: ti.index, buckets: ti.buckets, len: ti.len, fieldGetFn: ti.fieldGetFn}
+}
+
+// ValueType returns the type of the indexed values: string
+func (ti *TrigramIndex[OBJ, LI]) ValueType() reflect.Type { return reflect.TypeFor[string]() }
+func (ti *TrigramIndex[OBJ, LI]) itemValue(obj *OBJ) any  { return ti.fieldGetFn(obj) }
+
+// Len returns the number of indexed strings
+func (ti *TrigramIndex[OBJ, LI]) Len() int { return ti.len }
+
+func (ti *TrigramIndex[OBJ, LI]) get(query string) *BitSet[LI] {
+	resultBS := NewBitSet[LI]()
 
 	if len(query) < 3 {
 		// full table scan
 		for i, b := range ti.buckets {
 			if b.occupied && strings.Contains(b.s, query) {
-				resultBS.Set(uint32(i))
+				resultBS.Set(LI(i))
 			}
 		}
 		return resultBS
@@ -48,19 +89,19 @@ func (ti *TrigramIndex) Get(query string) *BitSet[uint32] {
 		bs, ok := ti.index[tri]
 		if !ok {
 			// If any trigram doesn't exist, the whole substring can't exist
-			return NewBitSet[uint32]()
+			return NewBitSet[LI]()
 		}
 		if first {
-			resultBS.Or(&bs) // seed with first trigram's candidates
+			resultBS.Or(bs) // seed with first trigram's candidates
 			first = false
 		} else {
-			resultBS.And(&bs) // intersect to narrow down
+			resultBS.And(bs) // intersect to narrow down
 		}
 	}
 
 	// verification (False Positive Check)
 	// Trigrams only prove the characters exist; we must verify the order/presence
-	resultBS.Values(func(i uint32) bool {
+	resultBS.Values(func(i LI) bool {
 		b := ti.buckets[i]
 		if b.occupied && !strings.Contains(b.s, query) {
 			resultBS.UnSet(i)
@@ -71,43 +112,49 @@ func (ti *TrigramIndex) Get(query string) *BitSet[uint32] {
 	return resultBS
 }
 
-func (ti *TrigramIndex) Put(s string, li int) {
-	if li == ti.len {
-		ti.buckets = append(ti.buckets, sbucket{s: s, occupied: true})
-	} else if li < ti.len {
-		ti.buckets[li] = sbucket{s: s, occupied: true}
-	} else {
+func (ti *TrigramIndex[OBJ, LI]) put(s string, lidx LI) {
+	ti.own()
+	li := int(lidx)
+	if li >= len(ti.buckets) {
 		newBuckets := make([]sbucket, li+1)
 		copy(newBuckets, ti.buckets)
 		ti.buckets = newBuckets
-		ti.buckets[li] = sbucket{s: s, occupied: true}
 	}
+
+	if ti.buckets[li].occupied {
+		// the list index is reused, remove the old trigrams first
+		ti.delete(lidx)
+	}
+	ti.buckets[li] = sbucket{s: s, occupied: true}
 	ti.len++
 
 	for j := 0; j < len(s)-2; j++ {
 		tri := pack(s[j], s[j+1], s[j+2])
-		bs, found := ti.index[tri]
+		bs, found := ti.bitSet(tri)
 		if !found {
-			bs = *NewBitSet[uint32]()
+			bs = NewBitSet[LI]()
+			ti.index[tri] = bs
 		}
-		bs.Set(uint32(li))
-		ti.index[tri] = bs
+		bs.Set(lidx)
 	}
 }
 
-func (ti *TrigramIndex) Delete(li int) bool {
-	if li >= len(ti.buckets) {
+func (ti *TrigramIndex[OBJ, LI]) delete(lidx LI) bool {
+	li := int(lidx)
+	if li >= len(ti.buckets) || !ti.buckets[li].occupied {
 		return false
 	}
+	ti.own()
 
 	s := ti.buckets[li].s
 
 	for j := 0; j < len(s)-2; j++ {
 		tri := pack(s[j], s[j+1], s[j+2])
-		if bs, found := ti.index[tri]; found {
-			bs.UnSet(uint32(li))
-			ti.index[tri] = bs
-			bs.Shrink()
+		if bs, found := ti.bitSet(tri); found {
+			bs.UnSet(lidx)
+			if bs.Count() == 0 {
+				delete(ti.index, tri)
+			}
 		}
 	}
 	ti.buckets[li] = sbucket{s: ""}
@@ -116,7 +163,23 @@ func (ti *TrigramIndex) Delete(li int) bool {
 	return true
 }
 
-func (ti *TrigramIndex) Len() int { return ti.len }
+// own copies the buckets and the trigram map, if they are shared with a snapshot
+func (ti *TrigramIndex[OBJ, LI]) own() {
+	if ti.cow.ownRoot() {
+		ti.index = maps.Clone(ti.index)
+		ti.buckets = slices.Clone(ti.buckets)
+	}
+}
+
+// bitSet returns the BitSet for changing, copy the BitSet if it is shared with a snapshot
+func (ti *TrigramIndex[OBJ, LI]) bitSet(tri uint32) (*BitSet[LI], bool) {
+	bs, found := ti.index[tri]
+	if found && ti.cow.own(tri) {
+		bs = bs.Copy()
+		ti.index[tri] = bs
+	}
+	return bs, found
+}
 
 // pack converts 3 bytes into a single uint32 to save memory and speed up lookups
 //
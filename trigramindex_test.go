@@ -1,4 +1,4 @@
-package main
+package fali
 
 import (
 	"testing"
@@ -7,38 +7,64 @@ import (
 )
 
 func TestTrigram_Base(t *testing.T) {
-	ti := NewTrigramIndex("apple", "apply", "ban", "banana", "xapp")
-	assert.Equal(t, 5, ti.Len())
-	assert.Equal(t, []uint32{0, 1, 4}, ti.Get("app").ToSlice())
-	assert.Equal(t, []uint32{2, 3}, ti.Get("an").ToSlice())
+	contains := func(ti Index32[string], query string) []uint32 {
+		bs, err := ti.Match(OpContains, query)
+		assert.NoError(t, err)
+		return bs.ToSlice()
+	}
+
+	ti := NewTrigramIndex(FromValue[string]())
+	for i, s := range []string{"apple", "apply", "ban", "banana", "xapp"} {
+		set(ti, s, uint32(i))
+	}
+	assert.Equal(t, 5, ti.(*TrigramIndex[string, uint32]).Len())
+	assert.Equal(t, []uint32{0, 1, 4}, contains(ti, "app"))
+	assert.Equal(t, []uint32{2, 3}, contains(ti, "an"))
 	// not found
-	assert.Equal(t, []uint32{}, ti.Get("nix").ToSlice())
+	assert.Equal(t, []uint32{}, contains(ti, "nix"))
 
-	assert.True(t, ti.Delete(2))
-	assert.Equal(t, 4, ti.Len())
+	unSet(ti, "ban", 2)
+	assert.Equal(t, 4, ti.(*TrigramIndex[string, uint32]).Len())
 	// search with 2 letters
-	assert.Equal(t, []uint32{3}, ti.Get("an").ToSlice())
+	assert.Equal(t, []uint32{3}, contains(ti, "an"))
 
 	// grow the bucket list
-	ti.Put("xban", 20)
-	assert.Equal(t, 5, ti.Len())
-	assert.Equal(t, []uint32{3, 20}, ti.Get("ban").ToSlice())
+	set(ti, "xban", 20)
+	assert.Equal(t, 5, ti.(*TrigramIndex[string, uint32]).Len())
+	assert.Equal(t, []uint32{3, 20}, contains(ti, "ban"))
 
 	// reuse index 2
-	ti.Put("xappx", 2)
-	assert.Equal(t, 6, ti.Len())
-	assert.Equal(t, []uint32{0, 1, 2, 4}, ti.Get("app").ToSlice())
+	set(ti, "xappx", 2)
+	assert.Equal(t, 6, ti.(*TrigramIndex[string, uint32]).Len())
+	assert.Equal(t, []uint32{0, 1, 2, 4}, contains(ti, "app"))
 
 	// checks the false positive: ABCD and BCDE matching {0, 2}
-	ti = NewTrigramIndex("ABCD", "ZZZ", "BCDE")
-	assert.Equal(t, []uint32{}, ti.Get("ABCDE").ToSlice())
+	ti = NewTrigramIndex(FromValue[string]())
+	set(ti, "ABCD", 0)
+	set(ti, "ZZZ", 1)
+	set(ti, "BCDE", 2)
+	assert.Equal(t, []uint32{}, contains(ti, "ABCDE"))
 
 	// empty init
-	ti = NewTrigramIndex()
-	assert.Equal(t, 0, ti.Len())
-	assert.Equal(t, []uint32{}, ti.Get("nix").ToSlice())
+	ti = NewTrigramIndex(FromValue[string]())
+	assert.Equal(t, 0, ti.(*TrigramIndex[string, uint32]).Len())
+	assert.Equal(t, []uint32{}, contains(ti, "nix"))
+
+	set(ti, "üöß€ä@", 2)
+	assert.Equal(t, 1, ti.(*TrigramIndex[string, uint32]).Len())
+	assert.Equal(t, []uint32{2}, contains(ti, "öß€ä"))
+}
+
+func TestTrigram_Errors(t *testing.T) {
+	ti := NewTrigramIndex(FromValue[string]())
+	set(ti, "apple", 0)
+
+	_, err := ti.Match(OpEq, "apple")
+	assert.ErrorIs(t, ErrInvalidOperation{TrigramIndexName, OpEq}, err)
+
+	_, err = ti.Match(OpContains, 5)
+	assert.ErrorIs(t, ErrInvalidIndexValue[string]{5}, err)
 
-	ti.Put("üöß€ä@", 2)
-	assert.Equal(t, 1, ti.Len())
-	assert.Equal(t, []uint32{2}, ti.Get("öß€ä").ToSlice())
+	_, err = ti.MatchMany(OpIn, "apple")
+	assert.ErrorIs(t, ErrInvalidOperation{TrigramIndexName, OpIn}, err)
 }
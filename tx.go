@@ -0,0 +1,130 @@
+package fali
+
+// Tx is a transaction, which batches Inserts, Updates and Removes under one write lock.
+// A Tx is only valid in the callback function of IndexList.Tx.
+type Tx[T any, ID comparable] struct {
+	list *IndexList[T, ID]
+	// undo reverts the operations, in reverse order
+	undo []func()
+	ops  []walOp[T]
+}
+
+// Tx executes the given function in a transaction. All operations are visible for readers together.
+// If the function returns an error (or panics), all operations are rolled back:
+// the list and all Indices are in the same state as before.
+// If the write-ahead log is opened, all operations are written as one record.
+func (l *IndexList[T, ID]) Tx(fn func(tx *Tx[T, ID]) error) (err error) {
+	defer l.indexMap.subs.deliver()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	tx := &Tx[T, ID]{list: l}
+	l.indexMap.subs.begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	if err = l.logOps(tx.ops...); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	l.indexMap.subs.commit()
+	return nil
+}
+
+// Insert add the given Item to the list, see IndexList.Insert
+func (tx *Tx[T, ID]) Insert(item T) (int, error) {
+	l := tx.list
+	if err := l.indexMap.check(&item, l.list.nextIndex()); err != nil {
+		return 0, err
+	}
+
+	// an existing ID is mapped to the new Item, the rollback restores the mapping
+	_, prevIdx, prevErr := l.indexMap.getIDByItem(&item)
+
+	appended := l.list.freeHead == -1
+	idx := l.insertNoLock(item)
+
+	tx.ops = append(tx.ops, walOp[T]{kind: walInsert, item: item})
+	tx.undo = append(tx.undo, func() {
+		l.indexMap.UnSet(&item, idx)
+		l.list.undoInsert(idx, appended)
+		if prevErr == nil {
+			l.indexMap.idIndex.Set(&item, prevIdx)
+		}
+	})
+
+	return idx, nil
+}
+
+// Update replaces an Item, see IndexList.Update
+func (tx *Tx[T, ID]) Update(item T) error {
+	l := tx.list
+	idx, err := l.indexOfItemNoLock(&item)
+	if err != nil {
+		return err
+	}
+
+	if err := l.indexMap.check(&item, idx); err != nil {
+		return err
+	}
+
+	oldItem, _ := l.list.Get(idx)
+	l.updateNoLock(idx, item)
+
+	tx.ops = append(tx.ops, walOp[T]{kind: walUpdate, item: item})
+	tx.undo = append(tx.undo, func() { l.updateNoLock(idx, oldItem) })
+
+	return nil
+}
+
+// Remove an Item by the given ID, see IndexList.Remove
+func (tx *Tx[T, ID]) Remove(id ID) (bool, error) {
+	l := tx.list
+	idx, err := l.indexMap.getIndexByID(id)
+	if err != nil {
+		return false, err
+	}
+
+	item, removed := l.removeNoLock(idx)
+	if !removed {
+		return false, nil
+	}
+
+	tx.ops = append(tx.ops, walOp[T]{kind: walRemove, lidx: idx})
+	tx.undo = append(tx.undo, func() {
+		l.list.undoRemove(idx, item)
+		l.indexMap.Set(&item, idx)
+	})
+
+	return true, nil
+}
+
+// Get returns an Item by the given ID, with the changes of this transaction.
+func (tx *Tx[T, ID]) Get(id ID) (T, error) {
+	idx, err := tx.list.indexMap.getIndexByID(id)
+	if err != nil {
+		var null T
+		return null, err
+	}
+
+	item, _ := tx.list.list.Get(idx)
+	return item, nil
+}
+
+func (tx *Tx[T, ID]) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo, tx.ops = nil, nil
+	tx.list.indexMap.subs.rollback()
+}
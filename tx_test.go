@@ -0,0 +1,164 @@
+package fali
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTxList() *IndexList[car, string] {
+	il := NewIndexListWithID((*car).Name)
+	_ = il.CreateIndex("age", NewSortedIndex((*car).Age))
+	_ = il.CreateIndex("isnew", NewMapIndex((*car).IsNew))
+
+	_, _ = il.Insert(car{name: "Opel", age: 22})
+	_, _ = il.Insert(car{name: "Mercedes", age: 5, isNew: true})
+	_, _ = il.Insert(car{name: "Dacia", age: 22})
+	_, _ = il.Remove("Mercedes")
+
+	return il
+}
+
+func TestTx_Commit(t *testing.T) {
+	il := newTxList()
+
+	err := il.Tx(func(tx *Tx[car, string]) error {
+		removed, err := tx.Remove("Opel")
+		assert.NoError(t, err)
+		assert.True(t, removed)
+
+		// reuse the free slot of Opel
+		idx, err := tx.Insert(car{name: "Audi", age: 3, isNew: true})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, idx)
+
+		if err := tx.Update(car{name: "Dacia", age: 23}); err != nil {
+			return err
+		}
+
+		dacia, err := tx.Get("Dacia")
+		assert.NoError(t, err)
+		assert.Equal(t, car{name: "Dacia", age: 23}, dacia)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, il.Count())
+	assert.False(t, il.Contains("Opel"))
+
+	qr, err := il.QueryStr(`age > uint8(20)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Dacia", age: 23}}, qr.Values())
+
+	qr, err = il.Query(Eq("isnew", true))
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Audi", age: 3, isNew: true}}, qr.Values())
+}
+
+func TestTx_Rollback(t *testing.T) {
+	il := newTxList()
+	before := il.list.snapshot()
+
+	txErr := errors.New("tx failed")
+	err := il.Tx(func(tx *Tx[car, string]) error {
+		_, _ = tx.Insert(car{name: "Audi", age: 3, isNew: true})
+		_, _ = tx.Insert(car{name: "BMW", age: 22})
+		_, _ = tx.Remove("Opel")
+		_, _ = tx.Insert(car{name: "VW", age: 22})
+		assert.NoError(t, tx.Update(car{name: "Dacia", age: 1, isNew: true}))
+
+		// not found
+		assert.Error(t, tx.Update(car{name: "Fiat"}))
+		_, err := tx.Remove("Fiat")
+		assert.Error(t, err)
+
+		return txErr
+	})
+	assert.ErrorIs(t, err, txErr)
+
+	// the FreeList is restored, with the free slots
+	assertSameSlots(t, before, il.list)
+	assert.Equal(t, 2, il.Count())
+	assert.False(t, il.Contains("Audi"))
+	assert.False(t, il.Contains("BMW"))
+	assert.False(t, il.Contains("VW"))
+
+	qr, err := il.Query(All())
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Opel", age: 22}, {name: "Dacia", age: 22}}, qr.Values())
+
+	qr, err = il.QueryStr(`age = uint8(22)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []car{{name: "Opel", age: 22}, {name: "Dacia", age: 22}}, qr.Values())
+
+	qr, err = il.Query(Eq("isnew", true))
+	assert.NoError(t, err)
+	assert.True(t, qr.IsEmpty())
+
+	// the next Insert use the same slot as before
+	idx, err := il.Insert(car{name: "Fiat"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, idx)
+}
+
+func TestTx_RollbackDuplicateID(t *testing.T) {
+	il := newTxList()
+
+	txErr := errors.New("tx failed")
+	err := il.Tx(func(tx *Tx[car, string]) error {
+		_, _ = tx.Insert(car{name: "Opel", age: 1})
+		return txErr
+	})
+	assert.ErrorIs(t, err, txErr)
+
+	assert.Equal(t, 2, il.Count())
+	opel, err := il.Get("Opel")
+	assert.NoError(t, err)
+	assert.Equal(t, car{name: "Opel", age: 22}, opel)
+}
+
+func TestTx_RollbackPanic(t *testing.T) {
+	il := newTxList()
+
+	assert.Panics(t, func() {
+		_ = il.Tx(func(tx *Tx[car, string]) error {
+			_, _ = tx.Insert(car{name: "Audi", age: 3})
+			panic("tx panic")
+		})
+	})
+
+	assert.Equal(t, 2, il.Count())
+	assert.False(t, il.Contains("Audi"))
+}
+
+func TestTx_WAL(t *testing.T) {
+	dir := t.TempDir()
+
+	il := newPersistList()
+	assert.NoError(t, il.Open(dir))
+	_, _ = il.Insert(pcar{Name: "Opel", Color: "red", Age: 22})
+
+	err := il.Tx(func(tx *Tx[pcar, string]) error {
+		_, _ = tx.Remove("Opel")
+		_, _ = tx.Insert(pcar{Name: "Dacia", Color: "red", Age: 12})
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// rollback is not logged
+	err = il.Tx(func(tx *Tx[pcar, string]) error {
+		_, _ = tx.Insert(pcar{Name: "Audi"})
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+	assert.NoError(t, il.Close())
+
+	loaded := newPersistList()
+	assert.NoError(t, loaded.Open(dir))
+	defer loaded.Close()
+
+	qr, err := loaded.Query(All())
+	assert.NoError(t, err)
+	assert.Equal(t, []pcar{{Name: "Dacia", Color: "red", Age: 12}}, qr.Values())
+}
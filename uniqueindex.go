@@ -0,0 +1,130 @@
+package fali
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+)
+
+const UniqueIndexName = "UniqueIndex"
+
+// UniqueIndex is a mapping of an unique value to the Index in the List.
+// An Item with an already existing value is rejected, Insert and Update return the error: ErrUniqueViolation.
+// This index only supported Queries with the Equal Ralation!
+type UniqueIndex[OBJ any, V comparable, LI Value] struct {
+	data       map[V]LI
+	fieldGetFn FromField[OBJ, V]
+	cow        cow[V]
+}
+
+func NewUniqueIndex[OBJ any, V comparable](fieldGetFn FromField[OBJ, V]) Index32[OBJ] {
+	return newUniqueIndex[OBJ, V, uint32](fieldGetFn)
+}
+
+func newUniqueIndex[OBJ any, V comparable, LI Value](fieldGetFn FromField[OBJ, V]) *UniqueIndex[OBJ, V, LI] {
+	return &UniqueIndex[OBJ, V, LI]{
+		data:       make(map[V]LI),
+		fieldGetFn: fieldGetFn,
+	}
+}
+
+// Check returns the error ErrUniqueViolation, if the value exists for an other List-Index
+func (ui *UniqueIndex[OBJ, V, LI]) Check(obj *OBJ, lidx LI) error {
+	value := ui.fieldGetFn(obj)
+	if existing, found := ui.data[value]; found && existing != lidx {
+		return ErrUniqueViolation{value: value}
+	}
+	return nil
+}
+
+// Set panics by a duplicate value, because every Item is checked before (see: Check).
+// A duplicate is a broken invariant, which must not be silently dropped.
+func (ui *UniqueIndex[OBJ, V, LI]) Set(obj *OBJ, lidx LI) {
+	value := ui.fieldGetFn(obj)
+	if existing, found := ui.data[value]; found {
+		if existing != lidx {
+			panic(fmt.Sprintf("UniqueIndex: value %v already exists for List-Index %v, not checked before Set", value, existing))
+		}
+		return
+	}
+
+	ui.own()
+	ui.data[value] = lidx
+}
+
+func (ui *UniqueIndex[OBJ, V, LI]) UnSet(obj *OBJ, lidx LI) {
+	value := ui.fieldGetFn(obj)
+	if existing, found := ui.data[value]; found && existing == lidx {
+		ui.own()
+		delete(ui.data, value)
+	}
+}
+
+func (ui *UniqueIndex[OBJ, V, LI]) Match(op Op, value any) (*BitSet[LI], error) {
+	v, ok := value.(V)
+	if !ok {
+		return nil, ErrInvalidIndexValue[V]{value}
+	}
+
+	if op != OpEq {
+		return nil, ErrInvalidOperation{UniqueIndexName, op}
+	}
+
+	if lidx, found := ui.data[v]; found {
+		return NewBitSetFrom(lidx), nil
+	}
+	return NewBitSet[LI](), nil
+}
+
+// ValueType returns the type of the indexed values
+func (ui *UniqueIndex[OBJ, V, LI]) ValueType() reflect.Type { return reflect.TypeFor[V]() }
+func (ui *UniqueIndex[OBJ, V, LI]) itemValue(obj *OBJ) any  { return ui.fieldGetFn(obj) }
+
+// Estimate returns 1, if the value exists, otherwise 0
+func (ui *UniqueIndex[OBJ, V, LI]) Estimate(op Op, values ...any) (int, error) {
+	if op != OpEq || len(values) != 1 {
+		return 0, ErrInvalidOperation{UniqueIndexName, op}
+	}
+	v, ok := values[0].(V)
+	if !ok {
+		return 0, ErrInvalidIndexValue[V]{values[0]}
+	}
+
+	if _, found := ui.data[v]; found {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// MatchMany is not supported by UniqueIndex, so that always returns an error
+func (ui *UniqueIndex[OBJ, V, LI]) MatchMany(op Op, values ...any) (*BitSet[LI], error) {
+	return nil, ErrInvalidOperation{UniqueIndexName, op}
+}
+
+// Snapshot returns an immutable UniqueIndex, which shares the data until this UniqueIndex is changed.
+func (ui *UniqueIndex[OBJ, V, LI]) Snapshot() Filter[LI] {
+	ui.cow.snapshot()
+	return &UniqueIndex[OBJ, V, LI]{data: ui.data, fieldGetFn: ui.fieldGetFn}
+}
+
+// Len returns the number of unique values
+func (ui *UniqueIndex[OBJ, V, LI]) Len() int { return len(ui.data) }
+
+func (ui *UniqueIndex[OBJ, V, LI]) matchItem(obj *OBJ, op Op, values ...any) (bool, error) {
+	if op != OpEq || len(values) != 1 {
+		return false, ErrInvalidOperation{UniqueIndexName, op}
+	}
+	v, ok := values[0].(V)
+	if !ok {
+		return false, ErrInvalidIndexValue[V]{values[0]}
+	}
+
+	return ui.fieldGetFn(obj) == v, nil
+}
+
+//go:inline
+func (ui *UniqueIndex[OBJ, V, LI]) own() {
+	if ui.cow.ownRoot() {
+		ui.data = maps.Clone(ui.data)
+	}
+}
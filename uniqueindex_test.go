@@ -0,0 +1,138 @@
+package fali
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	id    int
+	email string
+	age   uint8
+}
+
+func (u *user) ID() int       { return u.id }
+func (u *user) Email() string { return u.email }
+func (u *user) Age() uint8    { return u.age }
+
+func newUserList(t *testing.T) *IndexList[user, int] {
+	il := NewIndexListWithID((*user).ID)
+	assert.NoError(t, il.CreateIndex("email", NewUniqueIndex((*user).Email)))
+	assert.NoError(t, il.CreateIndex("age", NewSortedIndex((*user).Age)))
+
+	_, err := il.Insert(user{id: 1, email: "a@x.de", age: 20})
+	assert.NoError(t, err)
+	_, err = il.Insert(user{id: 2, email: "b@x.de", age: 30})
+	assert.NoError(t, err)
+
+	return il
+}
+
+func TestUniqueIndex_Base(t *testing.T) {
+	ui := newUniqueIndex[user, string, uint32]((*user).Email)
+	ui.Set(&user{email: "a@x.de"}, 1)
+	ui.Set(&user{email: "b@x.de"}, 3)
+
+	assert.NoError(t, ui.Check(&user{email: "c@x.de"}, 5))
+	assert.NoError(t, ui.Check(&user{email: "a@x.de"}, 1))
+	assert.ErrorIs(t, ui.Check(&user{email: "a@x.de"}, 5), ErrUniqueViolation{value: "a@x.de"})
+
+	// a duplicate is a broken invariant, the first List-Index is kept
+	assert.Panics(t, func() { ui.Set(&user{email: "a@x.de"}, 5) })
+	ui.Set(&user{email: "a@x.de"}, 1)
+	bs, err := ui.Match(OpEq, "a@x.de")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1}, bs.ToSlice())
+
+	// UnSet with an other List-Index doesn't remove the value
+	ui.UnSet(&user{email: "a@x.de"}, 5)
+	assert.Equal(t, 2, ui.Len())
+	ui.UnSet(&user{email: "a@x.de"}, 1)
+	assert.Equal(t, 1, ui.Len())
+
+	bs, err = ui.Match(OpEq, "a@x.de")
+	assert.NoError(t, err)
+	assert.True(t, bs.IsEmpty())
+
+	_, err = ui.Match(OpEq, 5)
+	assert.ErrorIs(t, err, ErrInvalidIndexValue[string]{5})
+	_, err = ui.Match(OpLt, "a")
+	assert.ErrorIs(t, err, ErrInvalidOperation{UniqueIndexName, OpLt})
+	_, err = ui.MatchMany(OpIn, "a")
+	assert.ErrorIs(t, err, ErrInvalidOperation{UniqueIndexName, OpIn})
+}
+
+func TestUniqueIndex_Insert(t *testing.T) {
+	il := newUserList(t)
+
+	_, err := il.Insert(user{id: 3, email: "a@x.de", age: 40})
+	assert.ErrorIs(t, err, ErrUniqueViolation{field: "email", value: "a@x.de"})
+	assert.Equal(t, `unique violation, field: "email", value: a@x.de already exists`, err.Error())
+
+	// the list and the other Indices are not changed
+	assert.Equal(t, 2, il.Count())
+	assert.False(t, il.Contains(3))
+	qr, err := il.Query(Eq("age", uint8(40)))
+	assert.NoError(t, err)
+	assert.True(t, qr.IsEmpty())
+
+	idx, err := il.Insert(user{id: 3, email: "c@x.de", age: 40})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, idx)
+}
+
+func TestUniqueIndex_Update(t *testing.T) {
+	il := newUserList(t)
+
+	// the same email for the same Item is ok
+	assert.NoError(t, il.Update(user{id: 1, email: "a@x.de", age: 21}))
+
+	err := il.Update(user{id: 1, email: "b@x.de", age: 22})
+	assert.ErrorIs(t, err, ErrUniqueViolation{field: "email", value: "b@x.de"})
+
+	u, err := il.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, user{id: 1, email: "a@x.de", age: 21}, u)
+
+	// the email is free after changing
+	assert.NoError(t, il.Update(user{id: 2, email: "c@x.de", age: 30}))
+	assert.NoError(t, il.Update(user{id: 1, email: "b@x.de", age: 21}))
+
+	qr, err := il.Query(Eq("email", "b@x.de"))
+	assert.NoError(t, err)
+	assert.Equal(t, []user{{id: 1, email: "b@x.de", age: 21}}, qr.Values())
+}
+
+func TestUniqueIndex_Tx(t *testing.T) {
+	il := newUserList(t)
+
+	err := il.Tx(func(tx *Tx[user, int]) error {
+		if _, err := tx.Insert(user{id: 3, email: "c@x.de"}); err != nil {
+			return err
+		}
+		_, err := tx.Insert(user{id: 4, email: "c@x.de"})
+		return err
+	})
+	assert.ErrorIs(t, err, ErrUniqueViolation{field: "email", value: "c@x.de"})
+	assert.Equal(t, 2, il.Count())
+
+	err = il.Tx(func(tx *Tx[user, int]) error {
+		return tx.Update(user{id: 2, email: "a@x.de"})
+	})
+	assert.True(t, errors.As(err, &ErrUniqueViolation{}))
+}
+
+func TestUniqueIndex_CreateIndex(t *testing.T) {
+	il := NewIndexListWithID((*user).ID)
+	_, _ = il.Insert(user{id: 1, email: "a@x.de", age: 20})
+	_, _ = il.Insert(user{id: 2, email: "b@x.de", age: 20})
+
+	err := il.CreateIndex("age", NewUniqueIndex((*user).Age))
+	assert.ErrorIs(t, err, ErrUniqueViolation{field: "age", value: uint8(20)})
+	_, err = il.Query(Eq("age", uint8(20)))
+	assert.ErrorIs(t, err, ErrInvalidIndexdName{"age"})
+
+	assert.NoError(t, il.CreateIndex("email", NewUniqueIndex((*user).Email)))
+}
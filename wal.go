@@ -0,0 +1,398 @@
+package fali
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The write-ahead log (WAL) saves every mutation of an IndexList between two snapshots.
+// A directory contains the files:
+//
+//	snapshot-<gen>.fali  the saved IndexList (see: Save)
+//	wal-<gen>.log        the mutations after the snapshot with the same generation
+//
+// A record in the log (all numbers are little-endian or varints):
+//
+//	length   uint32 (of the payload)
+//	checksum uint32 (CRC32 IEEE of the payload)
+//	payload  count of operations, per operation: kind byte, the encoded Item or the List-Index
+//
+// All operations of one record are replayed together.
+const (
+	walInsert byte = 1
+	walUpdate byte = 2
+	walRemove byte = 3
+
+	walRecordHeaderSize = 8
+
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".fali"
+	walPrefix      = "wal-"
+	walSuffix      = ".log"
+)
+
+// walOp is one mutation of the IndexList
+type walOp[T any] struct {
+	kind byte
+	item T   // for Insert and Update
+	lidx int // for Remove
+}
+
+type wal struct {
+	dir  string
+	gen  uint64
+	file *os.File
+	// the first write error, after this error nothing is written
+	err error
+}
+
+func (w *wal) append(payload []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	rec := make([]byte, walRecordHeaderSize, walRecordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(rec, uint32(len(payload)))
+	binary.LittleEndian.PutUint32(rec[4:], crc32.ChecksumIEEE(payload))
+	rec = append(rec, payload...)
+
+	if _, err := w.file.Write(rec); err != nil {
+		w.err = err
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		w.err = err
+		return err
+	}
+
+	return nil
+}
+
+// Open restores the IndexList from the newest snapshot and the write-ahead logs in the given directory.
+// After this, all mutations (Insert, Update, Remove, RemoveAll) are appended to the log.
+//
+// The Indices must be created before calling Open, the existing Items are replaced.
+// A torn (incomplete or corrupted) last record returns the error ErrTornRecord, use Recover to truncate it.
+func (l *IndexList[T, ID]) Open(dir string) error { return l.open(dir, false) }
+
+// Recover works like Open, but truncates the last log from the first torn (incomplete or corrupted) record.
+func (l *IndexList[T, ID]) Recover(dir string) error { return l.open(dir, true) }
+
+func (l *IndexList[T, ID]) open(dir string, truncate bool) error {
+	defer l.indexMap.subs.deliver()
+	// don't read the files of a running checkpoint
+	l.checkpointLock.Lock()
+	defer l.checkpointLock.Unlock()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.wal != nil {
+		return ErrWALAlreadyOpened{}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	snapshots, logs, err := listGenerations(dir)
+	if err != nil {
+		return err
+	}
+
+	gen := uint64(0)
+	if len(snapshots) > 0 {
+		gen = snapshots[len(snapshots)-1]
+		data, err := os.ReadFile(filePath(dir, snapshotPrefix, gen, snapshotSuffix))
+		if err != nil {
+			return err
+		}
+		if err = l.loadNoLock(data); err != nil {
+			return err
+		}
+	}
+
+	if len(snapshots) == 0 {
+		l.clearNoLock()
+	}
+
+	// the logs, which are created after the snapshot
+	logs = slices.DeleteFunc(logs, func(g uint64) bool { return g < gen })
+	for i, g := range logs {
+		if err := l.replayLog(filePath(dir, walPrefix, g, walSuffix), truncate && i == len(logs)-1); err != nil {
+			return err
+		}
+		gen = g
+	}
+
+	file, err := os.OpenFile(filePath(dir, walPrefix, gen, walSuffix), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	l.wal = &wal{dir: dir, gen: gen, file: file}
+	return nil
+}
+
+// Checkpoint writes a new snapshot and starts a new write-ahead log (log rotation).
+// The older snapshots and logs are removed.
+// Checkpoints are serialized: a second Checkpoint waits, until the first has removed the older generations.
+func (l *IndexList[T, ID]) Checkpoint() error {
+	l.checkpointLock.Lock()
+	defer l.checkpointLock.Unlock()
+
+	l.lock.Lock()
+
+	w := l.wal
+	if w == nil {
+		l.lock.Unlock()
+		return ErrWALNotOpened{}
+	}
+	if w.err != nil {
+		l.lock.Unlock()
+		return w.err
+	}
+
+	var buf bytes.Buffer
+	if err := l.saveNoLock(&buf); err != nil {
+		l.lock.Unlock()
+		return err
+	}
+
+	// all following mutations are written in the new log
+	gen := w.gen + 1
+	file, err := os.OpenFile(filePath(w.dir, walPrefix, gen, walSuffix), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		l.lock.Unlock()
+		return err
+	}
+	old := w.file
+	w.file, w.gen = file, gen
+	l.lock.Unlock()
+
+	if err := old.Close(); err != nil {
+		return err
+	}
+
+	if err := writeFileSync(filePath(w.dir, snapshotPrefix, gen, snapshotSuffix), buf.Bytes()); err != nil {
+		return err
+	}
+
+	snapshots, logs, err := listGenerations(w.dir)
+	if err != nil {
+		return err
+	}
+	for _, g := range snapshots {
+		if g < gen {
+			err = errors.Join(err, os.Remove(filePath(w.dir, snapshotPrefix, g, snapshotSuffix)))
+		}
+	}
+	for _, g := range logs {
+		if g < gen {
+			err = errors.Join(err, os.Remove(filePath(w.dir, walPrefix, g, walSuffix)))
+		}
+	}
+
+	return err
+}
+
+// Close closes the write-ahead log, the following mutations are not longer logged.
+// Returns the first error, by writing in the log.
+func (l *IndexList[T, ID]) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.wal == nil {
+		return nil
+	}
+
+	w := l.wal
+	l.wal = nil
+	return errors.Join(w.err, w.file.Close())
+}
+
+// logOps writes the operations as one record in the log, if the log is opened.
+func (l *IndexList[T, ID]) logOps(ops ...walOp[T]) error {
+	if l.wal == nil || len(ops) == 0 {
+		return nil
+	}
+
+	codec := l.itemCodec()
+	e := &encoder{}
+	e.uvarint(uint64(len(ops)))
+	for _, op := range ops {
+		e.buf.WriteByte(op.kind)
+
+		switch op.kind {
+		case walInsert, walUpdate:
+			b, err := codec.Marshal(op.item)
+			if err != nil {
+				return err
+			}
+			e.bytes(b)
+		case walRemove:
+			e.uvarint(uint64(op.lidx))
+		}
+	}
+
+	return l.wal.append(e.buf.Bytes())
+}
+
+// replayLog replays all records of the log file.
+// A torn record returns an error or, if truncate is true, the file is truncated on this record.
+func (l *IndexList[T, ID]) replayLog(path string, truncate bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for offset < len(data) {
+		rec := data[offset:]
+		if len(rec) < walRecordHeaderSize {
+			break
+		}
+
+		size := int(binary.LittleEndian.Uint32(rec))
+		if len(rec)-walRecordHeaderSize < size {
+			break
+		}
+
+		payload := rec[walRecordHeaderSize : walRecordHeaderSize+size]
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(rec[4:]) {
+			break
+		}
+
+		if err := l.replayNoLock(payload); err != nil {
+			return fmt.Errorf("replay %s [%d]: %w", path, offset, err)
+		}
+		offset += walRecordHeaderSize + size
+	}
+
+	if offset == len(data) {
+		return nil
+	}
+
+	if !truncate {
+		return ErrTornRecord{file: path, offset: offset}
+	}
+	return os.Truncate(path, int64(offset))
+}
+
+func (l *IndexList[T, ID]) replayNoLock(payload []byte) error {
+	codec := l.itemCodec()
+	d := &decoder{data: payload}
+
+	for range d.uvarint() {
+		switch kind := d.byte(); kind {
+		case walInsert, walUpdate:
+			b := d.bytes()
+			if d.err != nil {
+				return d.err
+			}
+			item, err := codec.Unmarshal(b)
+			if err != nil {
+				return err
+			}
+
+			if kind == walInsert {
+				l.insertNoLock(item)
+				continue
+			}
+
+			idx, err := l.indexOfItemNoLock(&item)
+			if err != nil {
+				return err
+			}
+			l.updateNoLock(idx, item)
+		case walRemove:
+			idx := int(d.uvarint())
+			if d.err != nil {
+				return d.err
+			}
+			if _, removed := l.removeNoLock(idx); !removed {
+				return ErrInvalidFormat{fmt.Sprintf("no item to remove on index: %d", idx)}
+			}
+		default:
+			if d.err != nil {
+				return d.err
+			}
+			return ErrInvalidFormat{fmt.Sprintf("unknown operation: %d", kind)}
+		}
+	}
+
+	return d.err
+}
+
+// listGenerations returns the sorted generations of the snapshots and logs in the directory
+func listGenerations(dir string) (snapshots []uint64, logs []uint64, _ error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parse := func(name, prefix, suffix string) (uint64, bool) {
+		s, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			return 0, false
+		}
+		if s, ok = strings.CutSuffix(s, suffix); !ok {
+			return 0, false
+		}
+		gen, err := strconv.ParseUint(s, 16, 64)
+		return gen, err == nil
+	}
+
+	for _, e := range entries {
+		if gen, ok := parse(e.Name(), snapshotPrefix, snapshotSuffix); ok {
+			snapshots = append(snapshots, gen)
+		} else if gen, ok := parse(e.Name(), walPrefix, walSuffix); ok {
+			logs = append(logs, gen)
+		}
+	}
+
+	slices.Sort(snapshots)
+	slices.Sort(logs)
+	return snapshots, logs, nil
+}
+
+//go:inline
+func filePath(dir, prefix string, gen uint64, suffix string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%016x%s", prefix, gen, suffix))
+}
+
+// writeFileSync writes the data in a temporary file and renames it, after the data is synced
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if err = errors.Join(err, f.Close()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	// sync the directory, to persist the rename
+	d, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
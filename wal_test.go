@@ -0,0 +1,167 @@
+package fali
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWAL_OpenReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	il := newPersistList()
+	assert.NoError(t, il.Open(dir))
+	_, _ = il.Insert(pcar{Name: "Opel", Color: "red", Age: 22})
+	_, _ = il.Insert(pcar{Name: "Mercedes", Color: "black", Age: 5})
+	_, _ = il.Insert(pcar{Name: "Dacia", Color: "red", Age: 12})
+	_, _ = il.Insert(pcar{Name: "Audi", Color: "blue", Age: 2})
+	assert.NoError(t, il.Update(pcar{Name: "Dacia", Color: "green", Age: 13}))
+	_, err := il.Remove("Mercedes")
+	assert.NoError(t, err)
+	qr, err := il.QueryStr(`color = "blue"`)
+	assert.NoError(t, err)
+	assert.NoError(t, qr.RemoveAll())
+	assert.NoError(t, il.Close())
+
+	// mutations after close are not logged
+	_, _ = il.Insert(pcar{Name: "VW"})
+
+	loaded := newPersistList()
+	assert.NoError(t, loaded.Open(dir))
+	defer loaded.Close()
+
+	assert.Equal(t, 2, loaded.Count())
+	assert.False(t, loaded.Contains("VW"))
+	dacia, err := loaded.Get("Dacia")
+	assert.NoError(t, err)
+	assert.Equal(t, pcar{Name: "Dacia", Color: "green", Age: 13}, dacia)
+
+	qr, err = loaded.QueryStr(`age < uint8(20)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []pcar{{Name: "Dacia", Color: "green", Age: 13}}, qr.Values())
+
+	// the same free slots are reused
+	idx, err := loaded.Insert(pcar{Name: "BMW"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, idx)
+
+	assert.ErrorIs(t, loaded.Open(dir), ErrWALAlreadyOpened{})
+}
+
+func TestWAL_OpenConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	il := newPersistList()
+	defer il.Close()
+
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() { errs <- il.Open(dir) }()
+	}
+
+	// only one Open can open the log
+	err1, err2 := <-errs, <-errs
+	assert.True(t, (err1 == nil) != (err2 == nil))
+	assert.ErrorIs(t, errors.Join(err1, err2), ErrWALAlreadyOpened{})
+}
+
+func TestWAL_Checkpoint(t *testing.T) {
+	dir := t.TempDir()
+
+	il := newPersistList()
+	assert.ErrorIs(t, il.Checkpoint(), ErrWALNotOpened{})
+
+	assert.NoError(t, il.Open(dir))
+	_, _ = il.Insert(pcar{Name: "Opel", Color: "red", Age: 22})
+	_, _ = il.Insert(pcar{Name: "Mercedes", Color: "black", Age: 5})
+	assert.NoError(t, il.Checkpoint())
+
+	snapshots, logs, err := listGenerations(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1}, snapshots)
+	assert.Equal(t, []uint64{1}, logs)
+
+	// log after the checkpoint
+	_, err = il.Remove("Opel")
+	assert.NoError(t, err)
+	_, _ = il.Insert(pcar{Name: "Dacia", Color: "red", Age: 12})
+	assert.NoError(t, il.Close())
+
+	loaded := newPersistList()
+	assert.NoError(t, loaded.Open(dir))
+	defer loaded.Close()
+
+	qr, err := loaded.Query(All())
+	assert.NoError(t, err)
+	assert.Equal(t, []pcar{
+		{Name: "Dacia", Color: "red", Age: 12},
+		{Name: "Mercedes", Color: "black", Age: 5},
+	}, qr.Values())
+}
+
+func TestWAL_CheckpointConcurrent(t *testing.T) {
+	dir := t.TempDir()
+
+	il := newPersistList()
+	assert.NoError(t, il.Open(dir))
+	_, _ = il.Insert(pcar{Name: "Opel", Color: "red", Age: 22})
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = il.Insert(pcar{Name: strconv.Itoa(i), Color: "blue", Age: uint8(i)})
+			assert.NoError(t, il.Checkpoint())
+		}()
+	}
+	wg.Wait()
+	assert.NoError(t, il.Close())
+
+	// only the newest generation is left
+	snapshots, logs, err := listGenerations(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{10}, snapshots)
+	assert.Equal(t, []uint64{10}, logs)
+
+	loaded := newPersistList()
+	assert.NoError(t, loaded.Open(dir))
+	defer loaded.Close()
+	assert.Equal(t, 11, loaded.Count())
+}
+
+func TestWAL_TornRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	il := newPersistList()
+	assert.NoError(t, il.Open(dir))
+	_, _ = il.Insert(pcar{Name: "Opel", Color: "red", Age: 22})
+	_, _ = il.Insert(pcar{Name: "Mercedes", Color: "black", Age: 5})
+	assert.NoError(t, il.Close())
+
+	// simulate a crash, by writing a half record
+	path := filepath.Join(dir, "wal-0000000000000000.log")
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, append(data, data[:10]...), 0o644))
+
+	loaded := newPersistList()
+	assert.ErrorIs(t, loaded.Open(dir), ErrTornRecord{file: path, offset: int(info.Size())})
+
+	loaded = newPersistList()
+	assert.NoError(t, loaded.Recover(dir))
+	assert.Equal(t, 2, loaded.Count())
+	_, _ = loaded.Insert(pcar{Name: "Dacia", Color: "red", Age: 12})
+	assert.NoError(t, loaded.Close())
+
+	loaded = newPersistList()
+	assert.NoError(t, loaded.Open(dir))
+	assert.Equal(t, 3, loaded.Count())
+	assert.NoError(t, loaded.Close())
+}